@@ -1,14 +1,11 @@
 package config
 
 import (
-	"crypto/rsa"
-	"crypto/x509"
-	"encoding/pem"
-	"fmt"
-	"io"
+	"crypto/rand"
 	"log"
-	"net/http"
+	"net"
 	"os"
+	"strconv"
 	"strings"
 	"time"
 )
@@ -20,10 +17,58 @@ type Config struct {
 	Environment     string
 	Debug           bool
 	AuthServiceURL  string
-	JWTPublicKey    *rsa.PublicKey
 	JWTPublicKeyURL string
+	AuthRefreshURL  string
+	AuthLogoutURL   string
 	AllowedOrigins  []string
 	CasbinModelPath string
+	PublicURL       string
+
+	// TrustedProxies are the reverse proxies whose X-Forwarded-For/X-Real-IP headers are
+	// believed when working out a request's client IP
+	TrustedProxies []*net.IPNet
+
+	// CasbinReloadInterval is how often policies are reloaded from the database, so
+	// changes made through another instance's policy API take effect here too
+	CasbinReloadInterval time.Duration
+
+	// BootstrapAdminEmail is promoted to admin while the install has no admins
+	BootstrapAdminEmail string
+
+	// JWT verification key settings. JWTKeysURL serves a JWKS document or a PEM key.
+	JWTKeysURL            string
+	JWTPublicKeyFile      string
+	JWTKeyCacheFile       string
+	JWTKeyRefreshInterval time.Duration
+
+	// Attachment storage settings
+	StorageBackend      string
+	AttachmentsDir      string
+	S3Endpoint          string
+	S3Region            string
+	S3Bucket            string
+	S3AccessKeyID       string
+	S3SecretAccessKey   string
+	S3ForcePathStyle    bool
+	AttachmentURLSecret []byte
+	AttachmentURLTTL    time.Duration
+
+	// Proof-of-work challenges for anonymous public submissions
+	ChallengeSecret []byte
+	ChallengeTTL    time.Duration
+
+	// Image attachment processing settings
+	AttachmentWorkers     int
+	AttachmentMaxAttempts int
+	ThumbnailSize         int
+
+	// Webhook delivery settings
+	WebhookWorkers      int
+	WebhookTimeout      time.Duration
+	WebhookMaxAttempts  int
+	WebhookRetryDelay   time.Duration
+	WebhookPollInterval time.Duration
+	WebhookRetention    time.Duration
 }
 
 // Load reads configuration from environment variables
@@ -37,20 +82,69 @@ func Load() *Config {
 		Debug:           debug,
 		AuthServiceURL:  getEnv("AUTH_SERVICE_URL", "http://localhost:8081"),
 		JWTPublicKeyURL: getEnv("JWT_PUBLIC_KEY_URL", ""),
+		AuthRefreshURL:  getEnv("AUTH_REFRESH_URL", ""),
+		AuthLogoutURL:   getEnv("AUTH_LOGOUT_URL", ""),
 		AllowedOrigins:  parseAllowedOrigins(getEnv("ALLOWED_ORIGINS", "http://localhost:3000,http://localhost:5173,http://localhost:5174,http://localhost:5175")),
 		CasbinModelPath: getEnv("CASBIN_MODEL_PATH", "./config/casbin_model.conf"),
+		PublicURL:       getEnv("PUBLIC_URL", "http://localhost:8082"),
+
+		CasbinReloadInterval: getEnvDuration("CASBIN_RELOAD_INTERVAL", time.Minute),
+
+		BootstrapAdminEmail: getEnv("BOOTSTRAP_ADMIN_EMAIL", ""),
+		TrustedProxies:      parseTrustedProxies(getEnv("TRUSTED_PROXIES", "")),
+
+		JWTKeysURL:            getEnv("JWT_JWKS_URL", ""),
+		JWTPublicKeyFile:      getEnv("JWT_PUBLIC_KEY_FILE", ""),
+		JWTKeyCacheFile:       getEnv("JWT_KEY_CACHE_FILE", ""),
+		JWTKeyRefreshInterval: getEnvDuration("JWT_KEY_REFRESH_INTERVAL", 10*time.Minute),
+
+		StorageBackend:    getEnv("STORAGE_BACKEND", "local"),
+		AttachmentsDir:    getEnv("ATTACHMENTS_DIR", "./uploads"),
+		S3Endpoint:        getEnv("S3_ENDPOINT", ""),
+		S3Region:          getEnv("S3_REGION", "us-east-1"),
+		S3Bucket:          getEnv("S3_BUCKET", ""),
+		S3AccessKeyID:     getEnv("S3_ACCESS_KEY_ID", ""),
+		S3SecretAccessKey: getEnv("S3_SECRET_ACCESS_KEY", ""),
+		S3ForcePathStyle:  getEnv("S3_FORCE_PATH_STYLE", "false") == "true",
+		AttachmentURLTTL:  getEnvDuration("ATTACHMENT_URL_TTL", 5*time.Minute),
+
+		ChallengeTTL: getEnvDuration("CHALLENGE_TTL", 5*time.Minute),
+
+		AttachmentWorkers:     getEnvInt("ATTACHMENT_WORKERS", 2),
+		AttachmentMaxAttempts: getEnvInt("ATTACHMENT_MAX_ATTEMPTS", 3),
+		ThumbnailSize:         getEnvInt("THUMBNAIL_SIZE", 320),
+
+		WebhookWorkers:      getEnvInt("WEBHOOK_WORKERS", 4),
+		WebhookTimeout:      getEnvDuration("WEBHOOK_TIMEOUT", 10*time.Second),
+		WebhookMaxAttempts:  getEnvInt("WEBHOOK_MAX_ATTEMPTS", 8),
+		WebhookRetryDelay:   getEnvDuration("WEBHOOK_RETRY_DELAY", 5*time.Second),
+		WebhookPollInterval: getEnvDuration("WEBHOOK_POLL_INTERVAL", 2*time.Second),
+		WebhookRetention:    getEnvDuration("WEBHOOK_LOG_RETENTION", 30*24*time.Hour),
 	}
 
-	// Fetch JWT public key from auth-service on startup
+	// Signed attachment URLs need a stable secret to survive restarts and work across replicas
+	config.AttachmentURLSecret = getEnvSecret("ATTACHMENT_URL_SECRET", config.Environment,
+		"signed download links will not survive a restart or work on other instances")
+
+	// Challenges are verified by their signature alone, so every replica needs the same secret
+	config.ChallengeSecret = getEnvSecret("CHALLENGE_SECRET", config.Environment,
+		"proof-of-work challenges will only be accepted by this instance")
+
+	// Token refresh and logout are proxied to the auth-service
+	if config.AuthRefreshURL == "" {
+		config.AuthRefreshURL = config.AuthServiceURL + "/api/auth/refresh"
+	}
+	if config.AuthLogoutURL == "" {
+		config.AuthLogoutURL = config.AuthServiceURL + "/api/auth/logout"
+	}
+
+	// JWT keys come from the JWKS endpoint if configured, otherwise the auth-service's PEM public key
 	if config.JWTPublicKeyURL == "" {
 		config.JWTPublicKeyURL = config.AuthServiceURL + "/api/public-key"
 	}
-
-	publicKey, err := fetchPublicKey(config.JWTPublicKeyURL)
-	if err != nil {
-		log.Fatalf("Failed to fetch JWT public key: %v", err)
+	if config.JWTKeysURL == "" {
+		config.JWTKeysURL = config.JWTPublicKeyURL
 	}
-	config.JWTPublicKey = publicKey
 
 	if config.Debug {
 		log.Printf("Configuration loaded: Environment=%s, Port=%s, AuthServiceURL=%s",
@@ -69,35 +163,51 @@ func getEnv(key, defaultValue string) string {
 	return value
 }
 
-// fetchPublicKey fetches the JWT public key from the auth-service
-func fetchPublicKey(url string) (*rsa.PublicKey, error) {
-	client := &http.Client{Timeout: 10 * time.Second}
-	resp, err := client.Get(url)
-	if err != nil {
-		return nil, fmt.Errorf("failed to fetch public key: %w", err)
+// getEnvSecret retrieves a secret that must be the same on every instance. Outside
+// development a missing secret is fatal; in development a random one is generated, and
+// consequence describes what breaks because of it.
+func getEnvSecret(key, environment, consequence string) []byte {
+	if secret := os.Getenv(key); secret != "" {
+		return []byte(secret)
+	}
+	if environment != "development" {
+		log.Fatalf("%s must be set when ENVIRONMENT is %q", key, environment)
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("failed to fetch public key: status %d", resp.StatusCode)
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		log.Fatalf("Failed to generate %s: %v", key, err)
 	}
+	log.Printf("Warning: %s not set, using a random secret; %s", key, consequence)
+	return secret
+}
 
-	pemBytes, err := io.ReadAll(resp.Body)
+// getEnvInt retrieves an integer environment variable or returns a default value
+func getEnvInt(key string, defaultValue int) int {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	parsed, err := strconv.Atoi(value)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read public key: %w", err)
+		log.Printf("Invalid value for %s (%q), using default %d", key, value, defaultValue)
+		return defaultValue
 	}
+	return parsed
+}
 
-	block, _ := pem.Decode(pemBytes)
-	if block == nil {
-		return nil, fmt.Errorf("failed to decode PEM block")
+// getEnvDuration retrieves a duration environment variable (e.g. "30s") or returns a default value
+func getEnvDuration(key string, defaultValue time.Duration) time.Duration {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
 	}
-
-	publicKey, err := x509.ParsePKCS1PublicKey(block.Bytes)
+	parsed, err := time.ParseDuration(value)
 	if err != nil {
-		return nil, fmt.Errorf("failed to parse public key: %w", err)
+		log.Printf("Invalid value for %s (%q), using default %s", key, value, defaultValue)
+		return defaultValue
 	}
-
-	return publicKey, nil
+	return parsed
 }
 
 // parseAllowedOrigins parses comma-separated allowed origins
@@ -117,3 +227,28 @@ func parseAllowedOrigins(origins string) []string {
 
 	return result
 }
+
+// parseTrustedProxies parses comma-separated proxy IPs and CIDR ranges, skipping invalid entries
+func parseTrustedProxies(proxies string) []*net.IPNet {
+	result := []*net.IPNet{}
+	for _, part := range strings.Split(proxies, ",") {
+		trimmed := strings.TrimSpace(part)
+		if trimmed == "" {
+			continue
+		}
+		if !strings.Contains(trimmed, "/") {
+			if ip := net.ParseIP(trimmed); ip != nil && ip.To4() != nil {
+				trimmed += "/32"
+			} else {
+				trimmed += "/128"
+			}
+		}
+		_, network, err := net.ParseCIDR(trimmed)
+		if err != nil {
+			log.Printf("Invalid entry in TRUSTED_PROXIES (%q), ignoring it", part)
+			continue
+		}
+		result = append(result, network)
+	}
+	return result
+}
@@ -0,0 +1,459 @@
+package controllers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/frallan97/feedback-service/backend/database"
+	"github.com/frallan97/feedback-service/backend/middleware"
+	"github.com/frallan97/feedback-service/backend/models"
+	"github.com/frallan97/feedback-service/backend/services"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/lib/pq"
+)
+
+// apiKeyColumns are the api_keys columns scanned by scanAPIKey
+const apiKeyColumns = `id, application_id, name, key_prefix, scopes, is_default,
+	expires_at, last_used_at, revoked_at, rotated_at, replaced_by, created_by, created_at, updated_at`
+
+// rowQuerier is satisfied by both *sql.DB and *sql.Tx
+type rowQuerier interface {
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// rowScanner is satisfied by both *sql.Row and *sql.Rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanAPIKey(row rowScanner) (*models.APIKey, error) {
+	var k models.APIKey
+	err := row.Scan(
+		&k.ID, &k.ApplicationID, &k.Name, &k.Prefix, pq.Array(&k.Scopes), &k.IsDefault,
+		&k.ExpiresAt, &k.LastUsedAt, &k.RevokedAt, &k.RotatedAt, &k.ReplacedBy, &k.CreatedBy, &k.CreatedAt, &k.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	k.SetStatus(time.Now())
+	return &k, nil
+}
+
+// insertAPIKey generates and stores a new API key; the returned key includes the full
+// key, which is never available again
+func insertAPIKey(ctx context.Context, q rowQuerier, appID uuid.UUID, name string, scopes []string, isDefault bool, expiresAt *time.Time, createdBy interface{}) (*models.APIKey, error) {
+	generated, err := services.GenerateAPIKey()
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := scanAPIKey(q.QueryRowContext(ctx, `
+		INSERT INTO api_keys (application_id, name, key_prefix, key_hash, scopes, is_default, expires_at, created_by)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING `+apiKeyColumns,
+		appID, name, generated.Prefix, generated.Hash, pq.Array(scopes), isDefault, expiresAt, createdBy,
+	))
+	if err != nil {
+		return nil, err
+	}
+	key.Key = generated.Key
+	return key, nil
+}
+
+// validateAPIKeyScopes checks that scopes is non-empty and only has known scopes
+func validateAPIKeyScopes(scopes []string) bool {
+	if len(scopes) == 0 {
+		return false
+	}
+	for _, s := range scopes {
+		if !models.IsValidAPIKeyScope(s) {
+			return false
+		}
+	}
+	return true
+}
+
+// currentUserID returns the authenticated user's ID for created_by columns, or nil
+func currentUserID(r *http.Request) interface{} {
+	if id, ok := middleware.GetUserID(r.Context()); ok {
+		return id
+	}
+	return nil
+}
+
+// GetAPIKeys returns an application's API keys, including revoked and expired ones (application owners and admins)
+func GetAPIKeys(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	vars := mux.Vars(r)
+	appID := vars["id"]
+
+	rows, err := database.DB.QueryContext(r.Context(), `
+		SELECT `+apiKeyColumns+`
+		FROM api_keys
+		WHERE application_id = $1
+		ORDER BY created_at
+	`, appID)
+	if err != nil {
+		http.Error(w, `{"error":"Failed to fetch API keys"}`, http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	keys := []*models.APIKey{}
+	for rows.Next() {
+		k, err := scanAPIKey(rows)
+		if err != nil {
+			continue
+		}
+		keys = append(keys, k)
+	}
+
+	json.NewEncoder(w).Encode(keys)
+}
+
+// GetAPIKey returns a single API key (application owners and admins)
+func GetAPIKey(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	vars := mux.Vars(r)
+	appID := vars["id"]
+	keyID := vars["key_id"]
+
+	k, err := scanAPIKey(database.DB.QueryRowContext(r.Context(), `
+		SELECT `+apiKeyColumns+`
+		FROM api_keys
+		WHERE id = $1 AND application_id = $2
+	`, keyID, appID))
+	if err == sql.ErrNoRows {
+		http.Error(w, `{"error":"API key not found"}`, http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, `{"error":"Failed to fetch API key"}`, http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(k)
+}
+
+// CreateAPIKey issues a new API key for an application (application owners and admins).
+// The response is the only time the full key is shown.
+func CreateAPIKey(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	vars := mux.Vars(r)
+	appID, err := uuid.Parse(vars["id"])
+	if err != nil {
+		http.Error(w, `{"error":"Invalid application ID"}`, http.StatusBadRequest)
+		return
+	}
+
+	var req struct {
+		Name      string     `json:"name"`
+		Scopes    []string   `json:"scopes"`
+		ExpiresAt *time.Time `json:"expires_at"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, `{"error":"Invalid request body"}`, http.StatusBadRequest)
+		return
+	}
+
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" || len(req.Name) > 100 {
+		http.Error(w, `{"error":"Name is required (at most 100 characters)"}`, http.StatusBadRequest)
+		return
+	}
+	if !validateAPIKeyScopes(req.Scopes) {
+		http.Error(w, `{"error":"Scopes must be one or more of feedback:write, feedback:read_status, categories:read"}`, http.StatusBadRequest)
+		return
+	}
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(time.Now()) {
+		http.Error(w, `{"error":"expires_at must be in the future"}`, http.StatusBadRequest)
+		return
+	}
+
+	k, err := insertAPIKey(r.Context(), database.DB, appID, req.Name, req.Scopes, false, req.ExpiresAt, currentUserID(r))
+	if err != nil {
+		http.Error(w, `{"error":"Failed to create API key"}`, http.StatusInternalServerError)
+		return
+	}
+
+	recordAudit(r, "api_key.create", "api_key", k.ID, nil, auditSnapshot(r, "api_keys", k.ID))
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(k)
+}
+
+// UpdateAPIKey renames an API key or changes its scopes (application owners and admins)
+func UpdateAPIKey(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	vars := mux.Vars(r)
+	appID := vars["id"]
+	keyID := vars["key_id"]
+
+	var req struct {
+		Name   *string  `json:"name"`
+		Scopes []string `json:"scopes"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, `{"error":"Invalid request body"}`, http.StatusBadRequest)
+		return
+	}
+
+	updates := []string{}
+	args := []interface{}{}
+	argPos := 1
+
+	if req.Name != nil {
+		name := strings.TrimSpace(*req.Name)
+		if name == "" || len(name) > 100 {
+			http.Error(w, `{"error":"Name is required (at most 100 characters)"}`, http.StatusBadRequest)
+			return
+		}
+		updates = append(updates, "name = $"+strconv.Itoa(argPos))
+		args = append(args, name)
+		argPos++
+	}
+
+	if req.Scopes != nil {
+		if !validateAPIKeyScopes(req.Scopes) {
+			http.Error(w, `{"error":"Scopes must be one or more of feedback:write, feedback:read_status, categories:read"}`, http.StatusBadRequest)
+			return
+		}
+		updates = append(updates, "scopes = $"+strconv.Itoa(argPos))
+		args = append(args, pq.Array(req.Scopes))
+		argPos++
+	}
+
+	if len(updates) == 0 {
+		http.Error(w, `{"error":"No fields to update"}`, http.StatusBadRequest)
+		return
+	}
+
+	snapshot := auditSnapshot(r, "api_keys", keyID)
+
+	args = append(args, keyID, appID)
+	query := "UPDATE api_keys SET " + strings.Join(updates, ", ") +
+		" WHERE id = $" + strconv.Itoa(argPos) + " AND application_id = $" + strconv.Itoa(argPos+1) +
+		" AND revoked_at IS NULL RETURNING " + apiKeyColumns
+
+	k, err := scanAPIKey(database.DB.QueryRowContext(r.Context(), query, args...))
+	if err == sql.ErrNoRows {
+		http.Error(w, `{"error":"API key not found or revoked"}`, http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, `{"error":"Failed to update API key"}`, http.StatusInternalServerError)
+		return
+	}
+
+	recordAudit(r, "api_key.update", "api_key", keyID, snapshot, auditSnapshot(r, "api_keys", keyID))
+
+	json.NewEncoder(w).Encode(k)
+}
+
+// RevokeAPIKey permanently disables an API key (application owners and admins).
+// The key is kept so it still shows up, as revoked, in the key list.
+func RevokeAPIKey(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	vars := mux.Vars(r)
+	appID := vars["id"]
+	keyID := vars["key_id"]
+
+	snapshot := auditSnapshot(r, "api_keys", keyID)
+
+	result, err := database.DB.ExecContext(r.Context(),
+		"UPDATE api_keys SET revoked_at = NOW() WHERE id = $1 AND application_id = $2 AND revoked_at IS NULL",
+		keyID, appID,
+	)
+	if err != nil {
+		http.Error(w, `{"error":"Failed to revoke API key"}`, http.StatusInternalServerError)
+		return
+	}
+
+	rows, _ := result.RowsAffected()
+	if rows == 0 {
+		http.Error(w, `{"error":"API key not found or already revoked"}`, http.StatusNotFound)
+		return
+	}
+
+	recordAudit(r, "api_key.revoke", "api_key", keyID, snapshot, auditSnapshot(r, "api_keys", keyID))
+
+	json.NewEncoder(w).Encode(map[string]string{"message": "API key revoked successfully"})
+}
+
+// errAPIKeyNotRotatable is returned when a key is revoked, expired or already rotated
+var errAPIKeyNotRotatable = errors.New("api key cannot be rotated")
+
+// rotateAPIKey replaces a key with a new one with the same name, scopes, expiry and
+// default flag.
+// The old key keeps working for gracePeriod, or is revoked at once if gracePeriod is zero.
+// It returns sql.ErrNoRows if the key doesn't exist.
+func rotateAPIKey(ctx context.Context, tx *sql.Tx, appID uuid.UUID, keyID uuid.UUID, gracePeriod time.Duration, createdBy interface{}) (*models.APIKey, error) {
+	old, err := scanAPIKey(tx.QueryRowContext(ctx, `
+		SELECT `+apiKeyColumns+`
+		FROM api_keys
+		WHERE id = $1 AND application_id = $2
+		FOR UPDATE
+	`, keyID, appID))
+	if err != nil {
+		return nil, err
+	}
+	if old.Status != models.APIKeyStatusActive {
+		return nil, errAPIKeyNotRotatable
+	}
+
+	// Retire the old key first; only one default key may be live at a time
+	if gracePeriod > 0 {
+		_, err = tx.ExecContext(ctx, `
+			UPDATE api_keys
+			SET is_default = FALSE, rotated_at = NOW(),
+				expires_at = LEAST(COALESCE(expires_at, 'infinity'), NOW() + make_interval(secs => $2))
+			WHERE id = $1
+		`, old.ID, gracePeriod.Seconds())
+	} else {
+		_, err = tx.ExecContext(ctx,
+			"UPDATE api_keys SET is_default = FALSE, rotated_at = NOW(), revoked_at = NOW() WHERE id = $1",
+			old.ID,
+		)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := insertAPIKey(ctx, tx, appID, old.Name, old.Scopes, old.IsDefault, old.ExpiresAt, createdBy)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := tx.ExecContext(ctx, "UPDATE api_keys SET replaced_by = $1 WHERE id = $2", key.ID, old.ID); err != nil {
+		return nil, err
+	}
+
+	return key, nil
+}
+
+// decodeGracePeriod reads an optional {"grace_period": "24h"} request body
+func decodeGracePeriod(r *http.Request) (time.Duration, string) {
+	var req struct {
+		GracePeriod string `json:"grace_period"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && err != io.EOF {
+		return 0, `{"error":"Invalid request body"}`
+	}
+	if req.GracePeriod == "" {
+		return 0, ""
+	}
+
+	grace, err := time.ParseDuration(req.GracePeriod)
+	if err != nil || grace < 0 || grace > models.MaxAPIKeyGracePeriod {
+		return 0, `{"error":"grace_period must be a duration between 0s and 720h, e.g. \"24h\""}`
+	}
+	return grace, ""
+}
+
+// RotateAPIKey replaces an API key with a new one that has the same name, scopes and
+// expiry (application owners and admins). With a grace_period the old key keeps working,
+// marked as deprecated, until the period ends. The response is the only time the new key
+// is shown.
+func RotateAPIKey(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	vars := mux.Vars(r)
+	appID, err := uuid.Parse(vars["id"])
+	if err != nil {
+		http.Error(w, `{"error":"Invalid application ID"}`, http.StatusBadRequest)
+		return
+	}
+	keyID, err := uuid.Parse(vars["key_id"])
+	if err != nil {
+		http.Error(w, `{"error":"API key not found"}`, http.StatusNotFound)
+		return
+	}
+
+	grace, msg := decodeGracePeriod(r)
+	if msg != "" {
+		http.Error(w, msg, http.StatusBadRequest)
+		return
+	}
+
+	snapshot := auditSnapshot(r, "api_keys", keyID)
+
+	tx, err := database.DB.BeginTx(r.Context(), nil)
+	if err != nil {
+		http.Error(w, `{"error":"Failed to rotate API key"}`, http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	key, err := rotateAPIKey(r.Context(), tx, appID, keyID, grace, currentUserID(r))
+	if err == sql.ErrNoRows {
+		http.Error(w, `{"error":"API key not found"}`, http.StatusNotFound)
+		return
+	}
+	if err == errAPIKeyNotRotatable {
+		http.Error(w, `{"error":"Only active API keys can be rotated"}`, http.StatusConflict)
+		return
+	}
+	if err != nil {
+		http.Error(w, `{"error":"Failed to rotate API key"}`, http.StatusInternalServerError)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		http.Error(w, `{"error":"Failed to rotate API key"}`, http.StatusInternalServerError)
+		return
+	}
+
+	recordAudit(r, "api_key.rotate", "api_key", keyID, snapshot, auditSnapshot(r, "api_keys", keyID))
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"key":             key,
+		"previous_key_id": keyID,
+		"grace_period":    grace.String(),
+	})
+}
+
+// EndAPIKeyGracePeriod revokes a rotated API key before its grace period ends
+// (application owners and admins)
+func EndAPIKeyGracePeriod(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	vars := mux.Vars(r)
+	appID := vars["id"]
+	keyID := vars["key_id"]
+
+	snapshot := auditSnapshot(r, "api_keys", keyID)
+
+	result, err := database.DB.ExecContext(r.Context(), `
+		UPDATE api_keys SET revoked_at = NOW()
+		WHERE id = $1 AND application_id = $2 AND rotated_at IS NOT NULL
+		  AND revoked_at IS NULL AND (expires_at IS NULL OR expires_at > NOW())
+	`, keyID, appID)
+	if err != nil {
+		http.Error(w, `{"error":"Failed to end grace period"}`, http.StatusInternalServerError)
+		return
+	}
+
+	rows, _ := result.RowsAffected()
+	if rows == 0 {
+		http.Error(w, `{"error":"No rotated API key in its grace period found"}`, http.StatusNotFound)
+		return
+	}
+
+	recordAudit(r, "api_key.end_grace", "api_key", keyID, snapshot, auditSnapshot(r, "api_keys", keyID))
+
+	json.NewEncoder(w).Encode(map[string]string{"message": "Grace period ended; the old API key is revoked"})
+}
@@ -1,30 +1,30 @@
 package controllers
 
 import (
-	"crypto/rand"
 	"database/sql"
-	"encoding/base64"
 	"encoding/json"
+	"log"
 	"net/http"
+	"strconv"
 
 	"github.com/frallan97/feedback-service/backend/database"
+	"github.com/frallan97/feedback-service/backend/middleware"
 	"github.com/frallan97/feedback-service/backend/models"
+	"github.com/frallan97/feedback-service/backend/services"
 	"github.com/google/uuid"
 	"github.com/gorilla/mux"
 	"github.com/lib/pq"
 )
 
-// generateAPIKey creates a random API key
-func generateAPIKey() (string, error) {
-	b := make([]byte, 32)
-	_, err := rand.Read(b)
-	if err != nil {
-		return "", err
-	}
-	return base64.URLEncoding.EncodeToString(b), nil
-}
+// defaultKeyPrefix selects the prefix of an application's default API key
+const defaultKeyPrefix = `COALESCE((
+	SELECT key_prefix FROM api_keys
+	WHERE api_keys.application_id = applications.id AND is_default AND revoked_at IS NULL
+), '')`
 
-// CreateApplication creates a new application and generates an API key (admin only)
+// CreateApplication creates a new application and its default API key (admin only).
+// The creator becomes the application's first owner. The response is the only time
+// the full key is shown; only its hash is stored.
 func CreateApplication(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 
@@ -32,7 +32,6 @@ func CreateApplication(w http.ResponseWriter, r *http.Request) {
 		Name           string   `json:"name"`
 		Slug           string   `json:"slug"`
 		Description    string   `json:"description"`
-		WebhookURL     *string  `json:"webhook_url"`
 		AllowedOrigins []string `json:"allowed_origins"`
 	}
 
@@ -47,22 +46,30 @@ func CreateApplication(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Generate API key
-	apiKey, err := generateAPIKey()
+	origins, ok := normalizeAllowedOrigins(req.AllowedOrigins)
+	if !ok {
+		http.Error(w, `{"error":"allowed_origins entries must be \"*\" or an origin like https://example.com or https://*.example.com"}`, http.StatusBadRequest)
+		return
+	}
+	req.AllowedOrigins = origins
+
+	tx, err := database.DB.BeginTx(r.Context(), nil)
 	if err != nil {
-		http.Error(w, `{"error":"Failed to generate API key"}`, http.StatusInternalServerError)
+		http.Error(w, `{"error":"Failed to create application"}`, http.StatusInternalServerError)
 		return
 	}
+	defer tx.Rollback()
 
 	// Insert application
 	var app models.Application
-	err = database.DB.QueryRowContext(r.Context(), `
-		INSERT INTO applications (name, slug, description, api_key, webhook_url, allowed_origins)
-		VALUES ($1, $2, $3, $4, $5, $6)
-		RETURNING id, name, slug, description, api_key, is_active, webhook_url, allowed_origins, created_at, updated_at
-	`, req.Name, req.Slug, req.Description, apiKey, req.WebhookURL, pq.Array(req.AllowedOrigins)).Scan(
-		&app.ID, &app.Name, &app.Slug, &app.Description, &app.APIKey, &app.IsActive,
-		&app.WebhookURL, pq.Array(&app.AllowedOrigins), &app.CreatedAt, &app.UpdatedAt,
+	err = tx.QueryRowContext(r.Context(), `
+		INSERT INTO applications (name, slug, description, allowed_origins)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, name, slug, description, is_active, allowed_origins,
+			max_attachment_size, max_attachments_per_feedback, allowed_attachment_types, rate_limit_per_key, rate_limit_per_ip, spam_filter_enabled, spam_threshold, pow_enabled, pow_difficulty, created_at, updated_at
+	`, req.Name, req.Slug, req.Description, pq.Array(req.AllowedOrigins)).Scan(
+		&app.ID, &app.Name, &app.Slug, &app.Description, &app.IsActive, pq.Array(&app.AllowedOrigins),
+		&app.MaxAttachmentSize, &app.MaxAttachmentsPerFeedback, pq.Array(&app.AllowedAttachmentTypes), &app.RateLimitPerKey, &app.RateLimitPerIP, &app.SpamFilterEnabled, &app.SpamThreshold, &app.PowEnabled, &app.PowDifficulty, &app.CreatedAt, &app.UpdatedAt,
 	)
 
 	if err != nil {
@@ -74,19 +81,55 @@ func CreateApplication(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Issue the default API key with every scope
+	apiKey, err := insertAPIKey(r.Context(), tx, app.ID, "Default", models.APIKeyScopes, true, nil, currentUserID(r))
+	if err != nil {
+		http.Error(w, `{"error":"Failed to generate API key"}`, http.StatusInternalServerError)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		http.Error(w, `{"error":"Failed to create application"}`, http.StatusInternalServerError)
+		return
+	}
+	app.APIKey = apiKey.Key
+	app.APIKeyPrefix = apiKey.Prefix
+
+	recordAudit(r, "application.create", "application", app.ID, nil, auditSnapshot(r, "applications", app.ID))
+
+	if userID, ok := middleware.GetUserID(r.Context()); ok {
+		if _, err := database.DB.ExecContext(r.Context(), `
+			INSERT INTO application_members (application_id, user_id, role, added_by)
+			VALUES ($1, $2, 'owner', $2)
+			ON CONFLICT DO NOTHING
+		`, app.ID, userID); err != nil {
+			log.Printf("[MEMBERS] Failed to add creator as owner of %s: %v", app.ID, err)
+		}
+	}
+
 	w.WriteHeader(http.StatusCreated)
 	json.NewEncoder(w).Encode(app)
 }
 
-// GetApplications returns all applications (admin only)
+// GetApplications returns the applications the caller can access
 func GetApplications(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 
-	rows, err := database.DB.QueryContext(r.Context(), `
-		SELECT id, name, slug, description, is_active, webhook_url, allowed_origins, created_at, updated_at
+	query := `
+		SELECT id, name, slug, description, ` + defaultKeyPrefix + `, is_active, allowed_origins,
+			   max_attachment_size, max_attachments_per_feedback, allowed_attachment_types, rate_limit_per_key, rate_limit_per_ip, spam_filter_enabled, spam_threshold, pow_enabled, pow_difficulty, created_at, updated_at
 		FROM applications
-		ORDER BY name
-	`)
+	`
+	args := []interface{}{}
+
+	// Non-admins only see applications they're a member of
+	if memberID, scoped := memberScope(r); scoped {
+		query += " WHERE id IN (SELECT application_id FROM application_members WHERE user_id = $1)"
+		args = append(args, memberID)
+	}
+	query += " ORDER BY name"
+
+	rows, err := database.DB.QueryContext(r.Context(), query, args...)
 	if err != nil {
 		http.Error(w, `{"error":"Failed to fetch applications"}`, http.StatusInternalServerError)
 		return
@@ -97,21 +140,19 @@ func GetApplications(w http.ResponseWriter, r *http.Request) {
 	for rows.Next() {
 		var app models.Application
 		err := rows.Scan(
-			&app.ID, &app.Name, &app.Slug, &app.Description, &app.IsActive,
-			&app.WebhookURL, pq.Array(&app.AllowedOrigins), &app.CreatedAt, &app.UpdatedAt,
+			&app.ID, &app.Name, &app.Slug, &app.Description, &app.APIKeyPrefix, &app.IsActive, pq.Array(&app.AllowedOrigins),
+			&app.MaxAttachmentSize, &app.MaxAttachmentsPerFeedback, pq.Array(&app.AllowedAttachmentTypes), &app.RateLimitPerKey, &app.RateLimitPerIP, &app.SpamFilterEnabled, &app.SpamThreshold, &app.PowEnabled, &app.PowDifficulty, &app.CreatedAt, &app.UpdatedAt,
 		)
 		if err != nil {
 			continue
 		}
-		// Don't expose API key in list view
-		app.APIKey = ""
 		applications = append(applications, app)
 	}
 
 	json.NewEncoder(w).Encode(applications)
 }
 
-// GetApplicationByID returns a single application with API key (admin only)
+// GetApplicationByID returns a single application. Only the default API key's prefix is available.
 func GetApplicationByID(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 
@@ -120,12 +161,13 @@ func GetApplicationByID(w http.ResponseWriter, r *http.Request) {
 
 	var app models.Application
 	err := database.DB.QueryRowContext(r.Context(), `
-		SELECT id, name, slug, description, api_key, is_active, webhook_url, allowed_origins, created_at, updated_at
+		SELECT id, name, slug, description, `+defaultKeyPrefix+`, is_active, allowed_origins,
+			   max_attachment_size, max_attachments_per_feedback, allowed_attachment_types, rate_limit_per_key, rate_limit_per_ip, spam_filter_enabled, spam_threshold, pow_enabled, pow_difficulty, created_at, updated_at
 		FROM applications
 		WHERE id = $1
 	`, appID).Scan(
-		&app.ID, &app.Name, &app.Slug, &app.Description, &app.APIKey, &app.IsActive,
-		&app.WebhookURL, pq.Array(&app.AllowedOrigins), &app.CreatedAt, &app.UpdatedAt,
+		&app.ID, &app.Name, &app.Slug, &app.Description, &app.APIKeyPrefix, &app.IsActive, pq.Array(&app.AllowedOrigins),
+		&app.MaxAttachmentSize, &app.MaxAttachmentsPerFeedback, pq.Array(&app.AllowedAttachmentTypes), &app.RateLimitPerKey, &app.RateLimitPerIP, &app.SpamFilterEnabled, &app.SpamThreshold, &app.PowEnabled, &app.PowDifficulty, &app.CreatedAt, &app.UpdatedAt,
 	)
 
 	if err == sql.ErrNoRows {
@@ -140,7 +182,7 @@ func GetApplicationByID(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(app)
 }
 
-// UpdateApplication updates an application (admin only)
+// UpdateApplication updates an application (application owners and admins)
 func UpdateApplication(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 
@@ -151,8 +193,20 @@ func UpdateApplication(w http.ResponseWriter, r *http.Request) {
 		Name           *string  `json:"name"`
 		Description    *string  `json:"description"`
 		IsActive       *bool    `json:"is_active"`
-		WebhookURL     *string  `json:"webhook_url"`
 		AllowedOrigins []string `json:"allowed_origins"`
+
+		MaxAttachmentSize         *int64   `json:"max_attachment_size"`
+		MaxAttachmentsPerFeedback *int     `json:"max_attachments_per_feedback"`
+		AllowedAttachmentTypes    []string `json:"allowed_attachment_types"`
+
+		RateLimitPerKey *int `json:"rate_limit_per_key"`
+		RateLimitPerIP  *int `json:"rate_limit_per_ip"`
+
+		SpamFilterEnabled *bool    `json:"spam_filter_enabled"`
+		SpamThreshold     *float64 `json:"spam_threshold"`
+
+		PowEnabled    *bool `json:"pow_enabled"`
+		PowDifficulty *int  `json:"pow_difficulty"`
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -166,32 +220,114 @@ func UpdateApplication(w http.ResponseWriter, r *http.Request) {
 	argPos := 1
 
 	if req.Name != nil {
-		updates = append(updates, "name = $"+string(rune(argPos+'0')))
+		updates = append(updates, "name = $"+strconv.Itoa(argPos))
 		args = append(args, *req.Name)
 		argPos++
 	}
 
 	if req.Description != nil {
-		updates = append(updates, "description = $"+string(rune(argPos+'0')))
+		updates = append(updates, "description = $"+strconv.Itoa(argPos))
 		args = append(args, *req.Description)
 		argPos++
 	}
 
 	if req.IsActive != nil {
-		updates = append(updates, "is_active = $"+string(rune(argPos+'0')))
+		updates = append(updates, "is_active = $"+strconv.Itoa(argPos))
 		args = append(args, *req.IsActive)
 		argPos++
 	}
 
-	if req.WebhookURL != nil {
-		updates = append(updates, "webhook_url = $"+string(rune(argPos+'0')))
-		args = append(args, *req.WebhookURL)
+	if req.AllowedOrigins != nil {
+		origins, ok := normalizeAllowedOrigins(req.AllowedOrigins)
+		if !ok {
+			http.Error(w, `{"error":"allowed_origins entries must be \"*\" or an origin like https://example.com or https://*.example.com"}`, http.StatusBadRequest)
+			return
+		}
+		updates = append(updates, "allowed_origins = $"+strconv.Itoa(argPos))
+		args = append(args, pq.Array(origins))
 		argPos++
 	}
 
-	if req.AllowedOrigins != nil {
-		updates = append(updates, "allowed_origins = $"+string(rune(argPos+'0')))
-		args = append(args, pq.Array(req.AllowedOrigins))
+	if req.MaxAttachmentSize != nil {
+		if *req.MaxAttachmentSize < 1 || *req.MaxAttachmentSize > maxAttachmentSizeLimit {
+			http.Error(w, `{"error":"max_attachment_size must be between 1 byte and 25 MB"}`, http.StatusBadRequest)
+			return
+		}
+		updates = append(updates, "max_attachment_size = $"+strconv.Itoa(argPos))
+		args = append(args, *req.MaxAttachmentSize)
+		argPos++
+	}
+
+	if req.MaxAttachmentsPerFeedback != nil {
+		if *req.MaxAttachmentsPerFeedback < 0 {
+			http.Error(w, `{"error":"max_attachments_per_feedback cannot be negative"}`, http.StatusBadRequest)
+			return
+		}
+		updates = append(updates, "max_attachments_per_feedback = $"+strconv.Itoa(argPos))
+		args = append(args, *req.MaxAttachmentsPerFeedback)
+		argPos++
+	}
+
+	if req.AllowedAttachmentTypes != nil {
+		types, ok := normalizeAttachmentTypes(req.AllowedAttachmentTypes)
+		if !ok {
+			http.Error(w, `{"error":"allowed_attachment_types entries must be MIME types like image/png or patterns like image/*"}`, http.StatusBadRequest)
+			return
+		}
+		updates = append(updates, "allowed_attachment_types = $"+strconv.Itoa(argPos))
+		args = append(args, pq.Array(types))
+		argPos++
+	}
+
+	if req.RateLimitPerKey != nil {
+		if *req.RateLimitPerKey < 0 {
+			http.Error(w, `{"error":"rate_limit_per_key cannot be negative (0 is unlimited)"}`, http.StatusBadRequest)
+			return
+		}
+		updates = append(updates, "rate_limit_per_key = $"+strconv.Itoa(argPos))
+		args = append(args, *req.RateLimitPerKey)
+		argPos++
+	}
+
+	if req.RateLimitPerIP != nil {
+		if *req.RateLimitPerIP < 0 {
+			http.Error(w, `{"error":"rate_limit_per_ip cannot be negative (0 is unlimited)"}`, http.StatusBadRequest)
+			return
+		}
+		updates = append(updates, "rate_limit_per_ip = $"+strconv.Itoa(argPos))
+		args = append(args, *req.RateLimitPerIP)
+		argPos++
+	}
+
+	if req.SpamFilterEnabled != nil {
+		updates = append(updates, "spam_filter_enabled = $"+strconv.Itoa(argPos))
+		args = append(args, *req.SpamFilterEnabled)
+		argPos++
+	}
+
+	if req.SpamThreshold != nil {
+		if *req.SpamThreshold <= 0 || *req.SpamThreshold > 1 {
+			http.Error(w, `{"error":"spam_threshold must be greater than 0 and at most 1"}`, http.StatusBadRequest)
+			return
+		}
+		updates = append(updates, "spam_threshold = $"+strconv.Itoa(argPos))
+		args = append(args, *req.SpamThreshold)
+		argPos++
+	}
+
+	if req.PowEnabled != nil {
+		updates = append(updates, "pow_enabled = $"+strconv.Itoa(argPos))
+		args = append(args, *req.PowEnabled)
+		argPos++
+	}
+
+	if req.PowDifficulty != nil {
+		if *req.PowDifficulty < 1 || *req.PowDifficulty > 24 {
+			http.Error(w, `{"error":"pow_difficulty must be between 1 and 24 bits"}`, http.StatusBadRequest)
+			return
+		}
+		updates = append(updates, "pow_difficulty = $"+strconv.Itoa(argPos))
+		args = append(args, *req.PowDifficulty)
 		argPos++
 	}
 
@@ -200,6 +336,8 @@ func UpdateApplication(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	snapshot := auditSnapshot(r, "applications", appID)
+
 	// Add app ID to args
 	args = append(args, appID)
 
@@ -208,7 +346,7 @@ func UpdateApplication(w http.ResponseWriter, r *http.Request) {
 	for i := 1; i < len(updates); i++ {
 		query += ", " + updates[i]
 	}
-	query += " WHERE id = $" + string(rune(argPos+'0'))
+	query += " WHERE id = $" + strconv.Itoa(argPos)
 
 	result, err := database.DB.ExecContext(r.Context(), query, args...)
 	if err != nil {
@@ -222,52 +360,121 @@ func UpdateApplication(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	recordAudit(r, "application.update", "application", appID, snapshot, auditSnapshot(r, "applications", appID))
+
 	json.NewEncoder(w).Encode(map[string]string{"message": "Application updated successfully"})
 }
 
-// RegenerateAPIKey generates a new API key for an application (admin only)
+// RegenerateAPIKey replaces an application's default API key (application owners and admins).
+// The new key keeps the old key's name and scopes. With a grace_period (e.g. "24h") the old
+// key keeps working, marked as deprecated, until the period ends; otherwise it is revoked
+// at once. The response is the only time the new key is shown.
 func RegenerateAPIKey(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 
 	vars := mux.Vars(r)
-	appID := vars["id"]
-
-	// Generate new API key
-	apiKey, err := generateAPIKey()
+	appID, err := uuid.Parse(vars["id"])
 	if err != nil {
-		http.Error(w, `{"error":"Failed to generate API key"}`, http.StatusInternalServerError)
+		http.Error(w, `{"error":"Invalid application ID"}`, http.StatusBadRequest)
 		return
 	}
 
-	// Update API key
-	result, err := database.DB.ExecContext(r.Context(),
-		"UPDATE applications SET api_key = $1 WHERE id = $2",
-		apiKey, appID,
-	)
+	grace, msg := decodeGracePeriod(r)
+	if msg != "" {
+		http.Error(w, msg, http.StatusBadRequest)
+		return
+	}
+
+	tx, err := database.DB.BeginTx(r.Context(), nil)
 	if err != nil {
 		http.Error(w, `{"error":"Failed to update API key"}`, http.StatusInternalServerError)
 		return
 	}
+	defer tx.Rollback()
 
-	rows, _ := result.RowsAffected()
-	if rows == 0 {
+	var exists bool
+	if err := tx.QueryRowContext(r.Context(),
+		"SELECT EXISTS(SELECT 1 FROM applications WHERE id = $1)", appID,
+	).Scan(&exists); err != nil {
+		http.Error(w, `{"error":"Failed to update API key"}`, http.StatusInternalServerError)
+		return
+	}
+	if !exists {
 		http.Error(w, `{"error":"Application not found"}`, http.StatusNotFound)
 		return
 	}
 
-	json.NewEncoder(w).Encode(map[string]string{
-		"api_key": apiKey,
-		"message": "API key regenerated successfully",
-	})
+	var previousKeyID *uuid.UUID
+	err = tx.QueryRowContext(r.Context(), `
+		SELECT id FROM api_keys
+		WHERE application_id = $1 AND is_default AND revoked_at IS NULL
+		  AND (expires_at IS NULL OR expires_at > NOW())
+	`, appID).Scan(&previousKeyID)
+	if err != nil && err != sql.ErrNoRows {
+		http.Error(w, `{"error":"Failed to update API key"}`, http.StatusInternalServerError)
+		return
+	}
+
+	var snapshot json.RawMessage
+	if previousKeyID != nil {
+		snapshot = auditSnapshot(r, "api_keys", *previousKeyID)
+	}
+
+	var apiKey *models.APIKey
+	if previousKeyID != nil {
+		apiKey, err = rotateAPIKey(r.Context(), tx, appID, *previousKeyID, grace, currentUserID(r))
+	} else {
+		// The default key was revoked or has expired; start a new one with every scope
+		if _, err = tx.ExecContext(r.Context(),
+			"UPDATE api_keys SET is_default = FALSE WHERE application_id = $1 AND is_default", appID,
+		); err == nil {
+			apiKey, err = insertAPIKey(r.Context(), tx, appID, "Default", models.APIKeyScopes, true, nil, currentUserID(r))
+		}
+	}
+	if err != nil {
+		http.Error(w, `{"error":"Failed to generate API key"}`, http.StatusInternalServerError)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		http.Error(w, `{"error":"Failed to update API key"}`, http.StatusInternalServerError)
+		return
+	}
+
+	if previousKeyID != nil {
+		recordAudit(r, "api_key.rotate", "api_key", *previousKeyID, snapshot, auditSnapshot(r, "api_keys", *previousKeyID))
+	} else {
+		recordAudit(r, "api_key.create", "api_key", apiKey.ID, nil, auditSnapshot(r, "api_keys", apiKey.ID))
+	}
+
+	response := map[string]interface{}{
+		"api_key":        apiKey.Key,
+		"api_key_prefix": apiKey.Prefix,
+		"api_key_id":     apiKey.ID,
+		"message":        "API key regenerated successfully",
+	}
+	if previousKeyID != nil && grace > 0 {
+		response["previous_key_id"] = previousKeyID
+		response["grace_period"] = grace.String()
+	}
+	json.NewEncoder(w).Encode(response)
 }
 
-// DeleteApplication deletes an application and all its feedback (admin only)
+// DeleteApplication deletes an application and all its feedback (application owners and admins)
 func DeleteApplication(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 
 	vars := mux.Vars(r)
 	appID := vars["id"]
 
+	// Collect attachment files before the rows cascade away
+	attachmentKeys, err := services.AttachmentKeysForApplication(r.Context(), appID)
+	if err != nil {
+		http.Error(w, `{"error":"Failed to fetch attachments"}`, http.StatusInternalServerError)
+		return
+	}
+	snapshot := auditSnapshot(r, "applications", appID)
+
 	result, err := database.DB.ExecContext(r.Context(), "DELETE FROM applications WHERE id = $1", appID)
 	if err != nil {
 		http.Error(w, `{"error":"Failed to delete application"}`, http.StatusInternalServerError)
@@ -280,10 +487,13 @@ func DeleteApplication(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	services.DeleteAttachmentFiles(r.Context(), attachmentKeys)
+	recordAudit(r, "application.delete", "application", appID, snapshot, nil)
+
 	json.NewEncoder(w).Encode(map[string]string{"message": "Application deleted successfully"})
 }
 
-// GetCategories returns all categories for an application (admin only)
+// GetCategories returns all categories for an application (application members)
 func GetCategories(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 
@@ -314,7 +524,7 @@ func GetCategories(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(categories)
 }
 
-// CreateCategory creates a new category for an application (admin only)
+// CreateCategory creates a new category for an application (application owners and admins)
 func CreateCategory(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 
@@ -365,6 +575,26 @@ func CreateCategory(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	recordAudit(r, "category.create", "category", category.ID, nil, auditSnapshot(r, "categories", category.ID))
+
 	w.WriteHeader(http.StatusCreated)
 	json.NewEncoder(w).Encode(category)
 }
+
+// normalizeAllowedOrigins validates an application's allowed origins and returns them in
+// canonical form, without duplicates
+func normalizeAllowedOrigins(origins []string) ([]string, bool) {
+	normalized := []string{}
+	seen := map[string]bool{}
+	for _, origin := range origins {
+		origin, ok := middleware.NormalizeOrigin(origin)
+		if !ok {
+			return nil, false
+		}
+		if !seen[origin] {
+			seen[origin] = true
+			normalized = append(normalized, origin)
+		}
+	}
+	return normalized, true
+}
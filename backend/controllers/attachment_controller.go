@@ -0,0 +1,539 @@
+package controllers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/frallan97/feedback-service/backend/database"
+	"github.com/frallan97/feedback-service/backend/middleware"
+	"github.com/frallan97/feedback-service/backend/models"
+	"github.com/frallan97/feedback-service/backend/pkg/storage"
+	"github.com/frallan97/feedback-service/backend/services"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/lib/pq"
+)
+
+// maxAttachmentSizeLimit is the largest per-application attachment size an admin can configure
+const maxAttachmentSizeLimit = 25 << 20
+
+// attachmentExtensions maps sniffed content types to the file extension used in storage keys
+var attachmentExtensions = map[string]string{
+	"image/png":       ".png",
+	"image/jpeg":      ".jpg",
+	"image/gif":       ".gif",
+	"image/webp":      ".webp",
+	"application/pdf": ".pdf",
+	"text/plain":      ".txt",
+}
+
+// sniffContentType detects a file's real MIME type from its contents, ignoring parameters
+func sniffContentType(data []byte) string {
+	contentType := http.DetectContentType(data)
+	if i := strings.Index(contentType, ";"); i != -1 {
+		contentType = contentType[:i]
+	}
+	return strings.TrimSpace(contentType)
+}
+
+// sanitizeFileName keeps only the base name of a client-supplied file name
+func sanitizeFileName(name string) string {
+	name = filepath.Base(strings.ReplaceAll(name, "\\", "/"))
+	if name == "." || name == "/" {
+		return ""
+	}
+	if len(name) > 255 {
+		name = name[:255]
+	}
+	return name
+}
+
+// attachmentTypeAllowed reports whether fileType matches one of an application's allowed
+// attachment types, either exactly or by a "type/*" pattern
+func attachmentTypeAllowed(allowed []string, fileType string) bool {
+	for _, t := range allowed {
+		if t == fileType || (strings.HasSuffix(t, "/*") && strings.HasPrefix(fileType, strings.TrimSuffix(t, "*"))) {
+			return true
+		}
+	}
+	return false
+}
+
+// normalizeAttachmentTypes validates an application's allowed attachment types, which must
+// be MIME types like image/png or patterns like image/*, and returns them in lowercase
+// without duplicates
+func normalizeAttachmentTypes(types []string) ([]string, bool) {
+	normalized := []string{}
+	seen := map[string]bool{}
+	for _, t := range types {
+		t = strings.ToLower(strings.TrimSpace(t))
+		mediaType, params, err := mime.ParseMediaType(t)
+		if err != nil || len(params) > 0 || mediaType != t {
+			return nil, false
+		}
+		major, minor, found := strings.Cut(t, "/")
+		if !found || strings.Contains(major, "*") || (strings.Contains(minor, "*") && minor != "*") {
+			return nil, false
+		}
+		if !seen[t] {
+			seen[t] = true
+			normalized = append(normalized, t)
+		}
+	}
+	return normalized, true
+}
+
+// UploadAttachment accepts a multipart "file" upload for a feedback item (API key authenticated)
+func UploadAttachment(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	// Get application ID from context (set by AppAuth middleware)
+	appID, ok := middleware.GetAppID(r.Context())
+	if !ok {
+		http.Error(w, `{"error":"Application ID not found"}`, http.StatusUnauthorized)
+		return
+	}
+
+	vars := mux.Vars(r)
+	feedbackID, err := uuid.Parse(vars["id"])
+	if err != nil {
+		http.Error(w, `{"error":"Invalid feedback ID"}`, http.StatusBadRequest)
+		return
+	}
+
+	// Load the application's attachment limits
+	var maxSize int64
+	var maxCount int
+	var allowedTypes []string
+	err = database.DB.QueryRowContext(r.Context(), `
+		SELECT max_attachment_size, max_attachments_per_feedback, allowed_attachment_types
+		FROM applications
+		WHERE id = $1
+	`, appID).Scan(&maxSize, &maxCount, pq.Array(&allowedTypes))
+	if err != nil {
+		http.Error(w, `{"error":"Failed to load application settings"}`, http.StatusInternalServerError)
+		return
+	}
+
+	// Verify the feedback belongs to this application. A submission a blocklist rule
+	// discarded takes uploads too, so its sender can't tell it was dropped.
+	var count int
+	discarded := false
+	err = database.DB.QueryRowContext(r.Context(), `
+		SELECT (SELECT COUNT(*) FROM feedback_attachments WHERE feedback_id = f.id)
+		FROM feedback f
+		WHERE f.id = $1 AND f.application_id = $2
+	`, feedbackID, appID).Scan(&count)
+	if err == sql.ErrNoRows {
+		_, err = services.GetDiscardedSubmission(r.Context(), appID, feedbackID.String())
+		discarded = err == nil
+	}
+	if err == sql.ErrNoRows {
+		http.Error(w, `{"error":"Feedback not found"}`, http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, `{"error":"Failed to fetch feedback"}`, http.StatusInternalServerError)
+		return
+	}
+
+	if count >= maxCount {
+		http.Error(w, `{"error":"Attachment limit reached for this feedback"}`, http.StatusConflict)
+		return
+	}
+
+	// Allow some headroom for multipart framing on top of the file itself
+	r.Body = http.MaxBytesReader(w, r.Body, maxSize+64*1024)
+
+	reader, err := r.MultipartReader()
+	if err != nil {
+		http.Error(w, `{"error":"Expected multipart/form-data with a file field"}`, http.StatusBadRequest)
+		return
+	}
+
+	var data []byte
+	var fileName string
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			writeUploadReadError(w, err)
+			return
+		}
+		if part.FormName() != "file" {
+			part.Close()
+			continue
+		}
+
+		fileName = sanitizeFileName(part.FileName())
+		data, err = io.ReadAll(io.LimitReader(part, maxSize+1))
+		part.Close()
+		if err != nil {
+			writeUploadReadError(w, err)
+			return
+		}
+		break
+	}
+
+	if len(data) == 0 {
+		http.Error(w, `{"error":"File is required"}`, http.StatusBadRequest)
+		return
+	}
+	if int64(len(data)) > maxSize {
+		http.Error(w, `{"error":"File exceeds the maximum allowed size"}`, http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	// Trust the file contents, not the client's Content-Type header
+	fileType := sniffContentType(data)
+	if !attachmentTypeAllowed(allowedTypes, fileType) {
+		http.Error(w, `{"error":"File type not allowed"}`, http.StatusUnsupportedMediaType)
+		return
+	}
+
+	attachmentID := uuid.New()
+	if discarded {
+		writeUploadedAttachment(w, &models.FeedbackAttachment{
+			ID:               attachmentID,
+			FileName:         fileName,
+			FileType:         fileType,
+			FileSize:         int64(len(data)),
+			ProcessingStatus: services.AttachmentProcessingStatus(fileType),
+		})
+		return
+	}
+
+	key := appID.String() + "/" + feedbackID.String() + "/" + attachmentID.String() + attachmentExtensions[fileType]
+
+	if err := services.SaveAttachmentFile(r.Context(), key, data, fileType); err != nil {
+		log.Printf("[ATTACHMENTS] Failed to store %s: %v", key, err)
+		http.Error(w, `{"error":"Failed to store attachment"}`, http.StatusInternalServerError)
+		return
+	}
+
+	attachment, err := insertAttachment(r, feedbackID, maxCount, attachmentID, key, fileName, fileType, len(data))
+	if err != nil {
+		services.DeleteAttachmentFiles(r.Context(), []string{key})
+		if err == errAttachmentLimitReached {
+			http.Error(w, `{"error":"Attachment limit reached for this feedback"}`, http.StatusConflict)
+			return
+		}
+		http.Error(w, `{"error":"Failed to save attachment"}`, http.StatusInternalServerError)
+		return
+	}
+
+	if attachment.ProcessingStatus == services.ProcessingStatusPending {
+		services.WakeAttachmentProcessor()
+	}
+
+	writeUploadedAttachment(w, attachment)
+}
+
+// writeUploadedAttachment writes the response to a successful upload
+func writeUploadedAttachment(w http.ResponseWriter, attachment *models.FeedbackAttachment) {
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"id":                attachment.ID,
+		"file_name":         attachment.FileName,
+		"file_type":         attachment.FileType,
+		"file_size":         attachment.FileSize,
+		"processing_status": attachment.ProcessingStatus,
+		"message":           "Attachment uploaded successfully",
+	})
+}
+
+// errAttachmentLimitReached is returned by insertAttachment when the feedback already has
+// the application's maximum number of attachments
+var errAttachmentLimitReached = errors.New("attachment limit reached")
+
+// insertAttachment records an uploaded attachment. The feedback row is locked while its
+// attachments are counted, so concurrent uploads can't exceed maxCount.
+func insertAttachment(r *http.Request, feedbackID uuid.UUID, maxCount int, attachmentID uuid.UUID, key, fileName, fileType string, size int) (*models.FeedbackAttachment, error) {
+	tx, err := database.DB.BeginTx(r.Context(), nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var count int
+	err = tx.QueryRowContext(r.Context(), `
+		SELECT (SELECT COUNT(*) FROM feedback_attachments WHERE feedback_id = f.id)
+		FROM feedback f
+		WHERE f.id = $1
+		FOR UPDATE
+	`, feedbackID).Scan(&count)
+	if err != nil {
+		return nil, err
+	}
+	if count >= maxCount {
+		return nil, errAttachmentLimitReached
+	}
+
+	var attachment models.FeedbackAttachment
+	err = tx.QueryRowContext(r.Context(), `
+		INSERT INTO feedback_attachments (id, feedback_id, file_url, file_name, file_type, file_size, processing_status)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id, feedback_id, file_url, COALESCE(file_name, ''), file_type, file_size, processing_status, created_at
+	`, attachmentID, feedbackID, key, fileName, fileType, size, services.AttachmentProcessingStatus(fileType)).Scan(
+		&attachment.ID, &attachment.FeedbackID, &attachment.FileURL, &attachment.FileName,
+		&attachment.FileType, &attachment.FileSize, &attachment.ProcessingStatus, &attachment.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &attachment, tx.Commit()
+}
+
+// writeUploadReadError maps errors from reading an upload body to a response
+func writeUploadReadError(w http.ResponseWriter, err error) {
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		http.Error(w, `{"error":"File exceeds the maximum allowed size"}`, http.StatusRequestEntityTooLarge)
+		return
+	}
+	http.Error(w, `{"error":"Invalid multipart body"}`, http.StatusBadRequest)
+}
+
+// GetAttachments returns all attachments for a feedback item with signed download links (admin endpoint)
+func GetAttachments(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	vars := mux.Vars(r)
+	feedbackID := vars["id"]
+
+	rows, err := database.DB.QueryContext(r.Context(), `
+		SELECT id, feedback_id, file_url, COALESCE(file_name, ''), COALESCE(file_type, ''), COALESCE(file_size, 0),
+			   width, height, processing_status, COALESCE(thumbnail_key, ''), created_at
+		FROM feedback_attachments
+		WHERE feedback_id = $1
+		ORDER BY created_at ASC
+	`, feedbackID)
+	if err != nil {
+		http.Error(w, `{"error":"Failed to fetch attachments"}`, http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	attachments := []models.FeedbackAttachment{}
+	for rows.Next() {
+		var a models.FeedbackAttachment
+		err := rows.Scan(
+			&a.ID, &a.FeedbackID, &a.FileURL, &a.FileName, &a.FileType, &a.FileSize,
+			&a.Width, &a.Height, &a.ProcessingStatus, &a.ThumbnailKey, &a.CreatedAt,
+		)
+		if err != nil {
+			continue
+		}
+		setAttachmentURLs(&a)
+		attachments = append(attachments, a)
+	}
+
+	json.NewEncoder(w).Encode(attachments)
+}
+
+// GetAttachment returns a single attachment with a signed download link (admin endpoint)
+func GetAttachment(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	vars := mux.Vars(r)
+	feedbackID := vars["id"]
+	attachmentID := vars["attachment_id"]
+
+	var a models.FeedbackAttachment
+	err := database.DB.QueryRowContext(r.Context(), `
+		SELECT id, feedback_id, file_url, COALESCE(file_name, ''), COALESCE(file_type, ''), COALESCE(file_size, 0),
+			   width, height, processing_status, COALESCE(thumbnail_key, ''), created_at
+		FROM feedback_attachments
+		WHERE id = $1 AND feedback_id = $2
+	`, attachmentID, feedbackID).Scan(
+		&a.ID, &a.FeedbackID, &a.FileURL, &a.FileName, &a.FileType, &a.FileSize,
+		&a.Width, &a.Height, &a.ProcessingStatus, &a.ThumbnailKey, &a.CreatedAt,
+	)
+
+	if err == sql.ErrNoRows {
+		http.Error(w, `{"error":"Attachment not found"}`, http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, `{"error":"Failed to fetch attachment"}`, http.StatusInternalServerError)
+		return
+	}
+
+	setAttachmentURLs(&a)
+	json.NewEncoder(w).Encode(a)
+}
+
+// setAttachmentURLs fills in signed links to an attachment once it has been processed and,
+// once generated, its thumbnail
+func setAttachmentURLs(a *models.FeedbackAttachment) {
+	if !services.AttachmentDownloadable(a.ProcessingStatus) {
+		return
+	}
+	a.DownloadURL = services.SignedAttachmentURL(a.ID)
+	if a.ThumbnailKey != "" {
+		a.ThumbnailURL = services.SignedAttachmentThumbnailURL(a.ID)
+	}
+}
+
+// ServeAttachment streams an attachment's contents (or its thumbnail, for variant=thumbnail)
+// for a valid signed download link
+func ServeAttachment(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	attachmentID, err := uuid.Parse(vars["attachment_id"])
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		http.Error(w, `{"error":"Invalid attachment ID"}`, http.StatusBadRequest)
+		return
+	}
+
+	query := r.URL.Query()
+	variant := query.Get("variant")
+	if variant != "" && variant != services.AttachmentVariantThumbnail {
+		w.Header().Set("Content-Type", "application/json")
+		http.Error(w, `{"error":"Invalid variant"}`, http.StatusBadRequest)
+		return
+	}
+	if !services.VerifyAttachmentSignature(attachmentID, variant, query.Get("expires"), query.Get("signature")) {
+		w.Header().Set("Content-Type", "application/json")
+		http.Error(w, `{"error":"Invalid or expired download link"}`, http.StatusForbidden)
+		return
+	}
+
+	var a models.FeedbackAttachment
+	err = database.DB.QueryRowContext(r.Context(), `
+		SELECT id, file_url, COALESCE(file_name, ''), COALESCE(file_type, ''), COALESCE(file_size, 0),
+			   COALESCE(thumbnail_key, ''), processing_status
+		FROM feedback_attachments
+		WHERE id = $1
+	`, attachmentID).Scan(&a.ID, &a.FileURL, &a.FileName, &a.FileType, &a.FileSize, &a.ThumbnailKey, &a.ProcessingStatus)
+
+	if err == sql.ErrNoRows {
+		w.Header().Set("Content-Type", "application/json")
+		http.Error(w, `{"error":"Attachment not found"}`, http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		http.Error(w, `{"error":"Failed to fetch attachment"}`, http.StatusInternalServerError)
+		return
+	}
+
+	// Images are stored with their metadata until the processor has stripped it
+	if !services.AttachmentDownloadable(a.ProcessingStatus) {
+		w.Header().Set("Content-Type", "application/json")
+		if a.ProcessingStatus == services.ProcessingStatusFailed {
+			http.Error(w, `{"error":"Attachment could not be processed"}`, http.StatusConflict)
+			return
+		}
+		http.Error(w, `{"error":"Attachment is still being processed"}`, http.StatusConflict)
+		return
+	}
+
+	key := a.FileURL
+	if variant == services.AttachmentVariantThumbnail {
+		if a.ThumbnailKey == "" {
+			w.Header().Set("Content-Type", "application/json")
+			http.Error(w, `{"error":"Thumbnail not available"}`, http.StatusNotFound)
+			return
+		}
+		key = a.ThumbnailKey
+		baseName := strings.TrimSuffix(a.FileName, filepath.Ext(a.FileName))
+		if baseName == "" {
+			baseName = a.ID.String()
+		}
+		a.FileName = baseName + "_thumb.jpg"
+		a.FileType = "image/jpeg"
+		a.FileSize = 0
+	}
+
+	file, err := services.OpenAttachmentFile(r.Context(), key)
+	if err != nil {
+		log.Printf("[ATTACHMENTS] Failed to open %s: %v", key, err)
+		w.Header().Set("Content-Type", "application/json")
+		if err == storage.ErrNotFound {
+			http.Error(w, `{"error":"Attachment file unavailable"}`, http.StatusNotFound)
+			return
+		}
+		http.Error(w, `{"error":"Failed to read attachment"}`, http.StatusInternalServerError)
+		return
+	}
+	defer file.Close()
+
+	writeAttachmentHeaders(w, &a)
+	io.Copy(w, file)
+}
+
+// writeAttachmentHeaders sets headers for serving the stored, sniffed type. Raster images are
+// shown inline (for previews); everything else is forced to download.
+func writeAttachmentHeaders(w http.ResponseWriter, a *models.FeedbackAttachment) {
+	fileName := a.FileName
+	if fileName == "" {
+		fileName = a.ID.String() + attachmentExtensions[a.FileType]
+	}
+
+	contentType := a.FileType
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+
+	disposition := "attachment"
+	if strings.HasPrefix(contentType, "image/") {
+		disposition = "inline"
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", mime.FormatMediaType(disposition, map[string]string{"filename": fileName}))
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("Content-Security-Policy", "default-src 'none'; sandbox")
+	w.Header().Set("Cache-Control", "private, max-age="+strconv.Itoa(int(services.AttachmentURLTTL().Seconds())))
+	if a.FileSize > 0 {
+		w.Header().Set("Content-Length", strconv.FormatInt(a.FileSize, 10))
+	}
+}
+
+// DeleteAttachment deletes an attachment and its stored file (admin endpoint)
+func DeleteAttachment(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	vars := mux.Vars(r)
+	feedbackID := vars["id"]
+	attachmentID := vars["attachment_id"]
+
+	snapshot := auditSnapshot(r, attachmentAuditTable, attachmentID)
+
+	var key string
+	var thumbnailKey sql.NullString
+	err := database.DB.QueryRowContext(r.Context(),
+		"DELETE FROM feedback_attachments WHERE id = $1 AND feedback_id = $2 RETURNING file_url, thumbnail_key",
+		attachmentID, feedbackID,
+	).Scan(&key, &thumbnailKey)
+
+	if err == sql.ErrNoRows {
+		http.Error(w, `{"error":"Attachment not found"}`, http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, `{"error":"Failed to delete attachment"}`, http.StatusInternalServerError)
+		return
+	}
+
+	keys := []string{key}
+	if thumbnailKey.Valid {
+		keys = append(keys, thumbnailKey.String)
+	}
+	services.DeleteAttachmentFiles(r.Context(), keys)
+	recordAudit(r, "attachment.delete", "attachment", attachmentID, snapshot, nil)
+
+	json.NewEncoder(w).Encode(map[string]string{"message": "Attachment deleted successfully"})
+}
@@ -0,0 +1,105 @@
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/frallan97/feedback-service/backend/middleware"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+// pngHeader is enough of a PNG for content sniffing
+var pngHeader = []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+
+// uploadRequest builds a public attachment upload to feedbackID for appID
+func uploadRequest(t *testing.T, appID, feedbackID uuid.UUID, data []byte) *http.Request {
+	t.Helper()
+
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	part, err := form.CreateFormFile("file", "screenshot.png")
+	if err != nil {
+		t.Fatalf("CreateFormFile: %v", err)
+	}
+	part.Write(data)
+	form.Close()
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/public/feedback/"+feedbackID.String()+"/attachments", &body)
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	req = mux.SetURLVars(req, map[string]string{"id": feedbackID.String()})
+	return req.WithContext(context.WithValue(req.Context(), middleware.AppIDKey, appID))
+}
+
+// expectAttachmentLookup expects the upload's application settings and feedback lookups,
+// finding no stored feedback
+func expectAttachmentLookup(mock sqlmock.Sqlmock, appID, feedbackID uuid.UUID) {
+	mock.ExpectQuery(`SELECT max_attachment_size, max_attachments_per_feedback, allowed_attachment_types`).
+		WithArgs(appID).
+		WillReturnRows(sqlmock.NewRows([]string{"max_attachment_size", "max_attachments_per_feedback", "allowed_attachment_types"}).
+			AddRow(1<<20, 5, "{image/png}"))
+	mock.ExpectQuery(`FROM feedback f`).
+		WithArgs(feedbackID, appID).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}))
+}
+
+func TestUploadAttachmentToDiscardedSubmission(t *testing.T) {
+	mock := mockDatabase(t)
+	appID, feedbackID := uuid.New(), uuid.New()
+
+	expectAttachmentLookup(mock, appID, feedbackID)
+	mock.ExpectQuery(`SELECT created_at FROM discarded_submissions WHERE id = \$1 AND application_id = \$2`).
+		WithArgs(feedbackID.String(), appID).
+		WillReturnRows(sqlmock.NewRows([]string{"created_at"}).AddRow(time.Now()))
+
+	rec := httptest.NewRecorder()
+	UploadAttachment(rec, uploadRequest(t, appID, feedbackID, pngHeader))
+
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("status = %d, want %d: %s", rec.Code, http.StatusCreated, rec.Body.String())
+	}
+	var resp map[string]interface{}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decoding response: %v", err)
+	}
+	for _, field := range []string{"id", "file_name", "file_type", "file_size", "processing_status", "message"} {
+		if _, ok := resp[field]; !ok {
+			t.Errorf("response has no %q", field)
+		}
+	}
+	if resp["file_type"] != "image/png" || resp["file_name"] != "screenshot.png" {
+		t.Errorf("response = %v", resp)
+	}
+
+	// Nothing is stored: no further queries, and no file storage is configured
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestUploadAttachmentToUnknownFeedback(t *testing.T) {
+	mock := mockDatabase(t)
+	appID, feedbackID := uuid.New(), uuid.New()
+
+	expectAttachmentLookup(mock, appID, feedbackID)
+	mock.ExpectQuery(`FROM discarded_submissions`).
+		WithArgs(feedbackID.String(), appID).
+		WillReturnRows(sqlmock.NewRows([]string{"created_at"}))
+
+	rec := httptest.NewRecorder()
+	UploadAttachment(rec, uploadRequest(t, appID, feedbackID, pngHeader))
+
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusNotFound)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
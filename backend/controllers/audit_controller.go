@@ -0,0 +1,149 @@
+package controllers
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/frallan97/feedback-service/backend/middleware"
+	"github.com/frallan97/feedback-service/backend/models"
+	"github.com/frallan97/feedback-service/backend/services"
+	"github.com/google/uuid"
+)
+
+// Comments and attachments belong to an application through their feedback; these snapshot
+// them with the application's ID so their audit events can be filtered by application
+const (
+	commentAuditTable    = "(SELECT c.*, f.application_id FROM feedback_comments c JOIN feedback f ON f.id = c.feedback_id)"
+	attachmentAuditTable = "(SELECT a.*, f.application_id FROM feedback_attachments a JOIN feedback f ON f.id = a.feedback_id)"
+)
+
+// webhookDeliveryAuditTable snapshots webhook deliveries without their payload
+const webhookDeliveryAuditTable = "(SELECT id, application_id, endpoint_id, event_id, event_type, url, status, redelivery_of, created_at FROM webhook_deliveries)"
+
+// auditSnapshot returns the row of table with id for an audit event's before or after state
+func auditSnapshot(r *http.Request, table string, id interface{}) json.RawMessage {
+	return services.SnapshotRow(r.Context(), table, "id = $1", id)
+}
+
+// auditJSON returns v as an audit event's before or after state, for targets that aren't table rows
+func auditJSON(v interface{}) json.RawMessage {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil
+	}
+	return data
+}
+
+// recordAudit appends an audit event for a change made by r's user. before and after are
+// snapshots of the target; either is nil when the target didn't or no longer exists.
+func recordAudit(r *http.Request, action, targetType string, targetID interface{}, before, after json.RawMessage) {
+	ctx := r.Context()
+	event := models.AuditEvent{
+		Action:     action,
+		TargetType: targetType,
+		TargetID:   fmt.Sprint(targetID),
+		Before:     before,
+		After:      after,
+	}
+
+	if claims, ok := middleware.GetUserClaims(ctx); ok {
+		event.ActorID = &claims.UserID
+		event.ActorEmail = &claims.Email
+		if claims.TokenID != uuid.Nil {
+			event.ActorTokenID = &claims.TokenID
+		}
+	}
+	if ip := middleware.GetClientIP(ctx); ip != "" {
+		event.IP = &ip
+	}
+	if requestID := middleware.GetRequestID(ctx); requestID != "" {
+		event.RequestID = &requestID
+	}
+	event.ApplicationID = auditApplicationID(targetType, event.TargetID, after, before)
+
+	services.RecordAuditEvent(ctx, event)
+}
+
+// auditApplicationID finds the application an audited change belongs to
+func auditApplicationID(targetType, targetID string, snapshots ...json.RawMessage) *uuid.UUID {
+	if targetType == "application" {
+		if id, err := uuid.Parse(targetID); err == nil {
+			return &id
+		}
+	}
+	for _, snapshot := range snapshots {
+		var row struct {
+			ApplicationID *uuid.UUID `json:"application_id"`
+		}
+		if json.Unmarshal(snapshot, &row) == nil && row.ApplicationID != nil {
+			return row.ApplicationID
+		}
+	}
+	return nil
+}
+
+// GetAuditEvents returns the audit log, newest first (admins). Filter with ?actor_id=,
+// ?action=, ?target_type=, ?target_id=, ?application_id= and ?since=/?until= (RFC 3339).
+func GetAuditEvents(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	// Parse query parameters
+	query := r.URL.Query()
+	page, _ := strconv.Atoi(query.Get("page"))
+	limit, _ := strconv.Atoi(query.Get("limit"))
+
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+
+	filter := services.AuditFilter{
+		Action:     query.Get("action"),
+		TargetType: query.Get("target_type"),
+		TargetID:   query.Get("target_id"),
+		Limit:      limit,
+		Offset:     (page - 1) * limit,
+	}
+
+	for param, dest := range map[string]**uuid.UUID{"actor_id": &filter.ActorID, "application_id": &filter.ApplicationID} {
+		if value := query.Get(param); value != "" {
+			id, err := uuid.Parse(value)
+			if err != nil {
+				http.Error(w, `{"error":"Invalid `+param+`"}`, http.StatusBadRequest)
+				return
+			}
+			*dest = &id
+		}
+	}
+	for param, dest := range map[string]**time.Time{"since": &filter.Since, "until": &filter.Until} {
+		if value := query.Get(param); value != "" {
+			t, err := time.Parse(time.RFC3339, value)
+			if err != nil {
+				http.Error(w, `{"error":"`+param+` must be an RFC 3339 timestamp"}`, http.StatusBadRequest)
+				return
+			}
+			t = t.UTC()
+			*dest = &t
+		}
+	}
+
+	events, total, err := services.ListAuditEvents(r.Context(), filter)
+	if err != nil {
+		log.Printf("[AUDIT] %v", err)
+		http.Error(w, `{"error":"Failed to fetch audit events"}`, http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"events": events,
+		"total":  total,
+		"page":   page,
+		"limit":  limit,
+	})
+}
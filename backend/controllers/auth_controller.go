@@ -1,10 +1,17 @@
 package controllers
 
 import (
+	"bytes"
 	"encoding/json"
+	"fmt"
+	"io"
+	"log"
 	"net/http"
+	"time"
 
 	"github.com/frallan97/feedback-service/backend/middleware"
+	customJWT "github.com/frallan97/feedback-service/backend/pkg/jwt"
+	"github.com/frallan97/feedback-service/backend/services"
 )
 
 // GetCurrentUser returns the authenticated user from context
@@ -24,20 +31,110 @@ func GetCurrentUser(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
-// RefreshToken handles token refresh requests
-func RefreshToken(w http.ResponseWriter, r *http.Request) {
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusNotImplemented)
-	json.NewEncoder(w).Encode(map[string]string{
-		"message": "Refresh token endpoint not implemented",
-	})
+// authServiceClient calls the auth-service on behalf of the dashboard
+var authServiceClient = &http.Client{Timeout: 10 * time.Second}
+
+const (
+	maxAuthRequestSize  = 64 << 10
+	maxAuthResponseSize = 1 << 20
+)
+
+// RefreshToken exchanges the refresh token cookie for a new access token by proxying
+// the request to the auth-service's refresh endpoint
+func RefreshToken(refreshURL string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		resp, body, err := callAuthService(r, refreshURL)
+		if err != nil {
+			log.Printf("[AUTH] Token refresh failed: %v", err)
+			w.Header().Set("Content-Type", "application/json")
+			http.Error(w, `{"error":"Auth service unavailable"}`, http.StatusBadGateway)
+			return
+		}
+
+		copyAuthServiceHeaders(w, resp)
+		if contentType := resp.Header.Get("Content-Type"); contentType != "" {
+			w.Header().Set("Content-Type", contentType)
+		}
+		w.WriteHeader(resp.StatusCode)
+		w.Write(body)
+	}
 }
 
-// Logout handles logout requests
-func Logout(w http.ResponseWriter, r *http.Request) {
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(map[string]string{
-		"message": "Logged out successfully",
-	})
+// Logout revokes the request's access token until it expires and asks the auth-service
+// to revoke the refresh token. Requests without a valid access token only do the latter.
+func Logout(logoutURL string, keys *customJWT.KeySet) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		if tokenString, ok := middleware.BearerToken(r); ok {
+			if claims, err := customJWT.ValidateAccessToken(tokenString, keys); err == nil {
+				expiresAt := time.Now().Add(24 * time.Hour)
+				if claims.ExpiresAt != nil {
+					expiresAt = claims.ExpiresAt.Time
+				}
+				if err := services.RevokeToken(r.Context(), claims.TokenID(tokenString), claims.UserID, expiresAt); err != nil {
+					log.Printf("[AUTH] %v", err)
+					http.Error(w, `{"error":"Failed to revoke token"}`, http.StatusInternalServerError)
+					return
+				}
+			}
+		}
+
+		// The access token is already revoked, so a failure here only leaves the refresh token valid
+		resp, _, err := callAuthService(r, logoutURL)
+		if err != nil {
+			log.Printf("[AUTH] Failed to revoke refresh token: %v", err)
+		} else {
+			if resp.StatusCode >= 300 {
+				log.Printf("[AUTH] Auth service logout returned status %d", resp.StatusCode)
+			}
+			copyAuthServiceHeaders(w, resp)
+		}
+
+		json.NewEncoder(w).Encode(map[string]string{
+			"message": "Logged out successfully",
+		})
+	}
+}
+
+// callAuthService forwards a dashboard request's body, content type, credentials and
+// cookies to the auth-service and reads its response
+func callAuthService(r *http.Request, url string) (*http.Response, []byte, error) {
+	reqBody, err := io.ReadAll(io.LimitReader(r.Body, maxAuthRequestSize))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(r.Context(), http.MethodPost, url, bytes.NewReader(reqBody))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to build request: %w", err)
+	}
+	for _, header := range []string{"Content-Type", "Cookie", "Authorization"} {
+		if value := r.Header.Get(header); value != "" {
+			req.Header.Set(header, value)
+		}
+	}
+
+	resp, err := authServiceClient.Do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxAuthResponseSize))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read auth service response: %w", err)
+	}
+	return resp, body, nil
+}
+
+// copyAuthServiceHeaders passes on the cookies (e.g. a rotated or cleared refresh
+// token) and caching headers the auth-service set
+func copyAuthServiceHeaders(w http.ResponseWriter, resp *http.Response) {
+	for _, cookie := range resp.Header.Values("Set-Cookie") {
+		w.Header().Add("Set-Cookie", cookie)
+	}
+	if cacheControl := resp.Header.Get("Cache-Control"); cacheControl != "" {
+		w.Header().Set("Cache-Control", cacheControl)
+	}
 }
@@ -0,0 +1,260 @@
+package controllers
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/frallan97/feedback-service/backend/database"
+	"github.com/frallan97/feedback-service/backend/middleware"
+	customJWT "github.com/frallan97/feedback-service/backend/pkg/jwt"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+// authServiceStub stands in for the auth-service, answering every request with status,
+// body and a Set-Cookie header, and recording the last request it received
+type authServiceStub struct {
+	*httptest.Server
+	status int
+	body   string
+	cookie string
+
+	gotCookie string
+	gotBody   string
+}
+
+func newAuthServiceStub(t *testing.T, status int, body, cookie string) *authServiceStub {
+	t.Helper()
+
+	stub := &authServiceStub{status: status, body: body, cookie: cookie}
+	stub.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		reqBody, _ := io.ReadAll(r.Body)
+		stub.gotCookie = r.Header.Get("Cookie")
+		stub.gotBody = string(reqBody)
+
+		w.Header().Set("Content-Type", "application/json")
+		if stub.cookie != "" {
+			w.Header().Set("Set-Cookie", stub.cookie)
+		}
+		w.WriteHeader(stub.status)
+		io.WriteString(w, stub.body)
+	}))
+	t.Cleanup(stub.Close)
+	return stub
+}
+
+// mockDatabase replaces database.DB with a sqlmock for the test
+func mockDatabase(t *testing.T) sqlmock.Sqlmock {
+	t.Helper()
+
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	previous := database.DB
+	database.DB = db
+	t.Cleanup(func() {
+		database.DB = previous
+		db.Close()
+	})
+	return mock
+}
+
+// testTokenIssuer signs access tokens with a key served as PEM, like the auth-service's
+// public key endpoint
+type testTokenIssuer struct {
+	key  *rsa.PrivateKey
+	keys *customJWT.KeySet
+}
+
+func newTestTokenIssuer(t *testing.T) *testTokenIssuer {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("generating key: %v", err)
+	}
+	der, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		t.Fatalf("encoding public key: %v", err)
+	}
+	publicKey := pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})
+
+	keyServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(publicKey)
+	}))
+	t.Cleanup(keyServer.Close)
+
+	keys, err := customJWT.NewKeySet(customJWT.KeySetConfig{URL: keyServer.URL})
+	if err != nil {
+		t.Fatalf("NewKeySet: %v", err)
+	}
+	return &testTokenIssuer{key: key, keys: keys}
+}
+
+func (i *testTokenIssuer) token(t *testing.T, jti string) string {
+	t.Helper()
+
+	claims := customJWT.Claims{
+		UserID: uuid.New(),
+		Email:  "dev@example.com",
+		Name:   "Dev",
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        jti,
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(15 * time.Minute)),
+		},
+	}
+	signed, err := jwt.NewWithClaims(jwt.SigningMethodRS256, claims).SignedString(i.key)
+	if err != nil {
+		t.Fatalf("signing token: %v", err)
+	}
+	return signed
+}
+
+func TestRefreshTokenPassesThroughSuccess(t *testing.T) {
+	stub := newAuthServiceStub(t, http.StatusOK, `{"access_token":"new-token"}`, "refresh_token=rotated; HttpOnly")
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/auth/refresh", strings.NewReader(`{}`))
+	req.Header.Set("Cookie", "refresh_token=old")
+	rec := httptest.NewRecorder()
+	RefreshToken(stub.URL)(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusOK)
+	}
+	if got := rec.Body.String(); got != `{"access_token":"new-token"}` {
+		t.Errorf("body = %q", got)
+	}
+	if got := rec.Header().Get("Set-Cookie"); got != "refresh_token=rotated; HttpOnly" {
+		t.Errorf("Set-Cookie = %q", got)
+	}
+	if stub.gotCookie != "refresh_token=old" {
+		t.Errorf("auth-service got Cookie %q", stub.gotCookie)
+	}
+	if stub.gotBody != `{}` {
+		t.Errorf("auth-service got body %q", stub.gotBody)
+	}
+}
+
+func TestRefreshTokenPassesThroughFailure(t *testing.T) {
+	stub := newAuthServiceStub(t, http.StatusUnauthorized, `{"error":"refresh token expired"}`, "")
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/auth/refresh", nil)
+	rec := httptest.NewRecorder()
+	RefreshToken(stub.URL)(rec, req)
+
+	if rec.Code != http.StatusUnauthorized {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusUnauthorized)
+	}
+	if got := rec.Body.String(); got != `{"error":"refresh token expired"}` {
+		t.Errorf("body = %q", got)
+	}
+}
+
+func TestRefreshTokenAuthServiceUnreachable(t *testing.T) {
+	stub := newAuthServiceStub(t, http.StatusOK, "", "")
+	stub.Close()
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/auth/refresh", nil)
+	rec := httptest.NewRecorder()
+	RefreshToken(stub.URL)(rec, req)
+
+	if rec.Code != http.StatusBadGateway {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusBadGateway)
+	}
+	if !strings.Contains(rec.Body.String(), "Auth service unavailable") {
+		t.Errorf("body = %q", rec.Body.String())
+	}
+}
+
+func TestLogoutRevokesAccessToken(t *testing.T) {
+	issuer := newTestTokenIssuer(t)
+	mock := mockDatabase(t)
+	stub := newAuthServiceStub(t, http.StatusOK, `{}`, "refresh_token=; Max-Age=0")
+	token := issuer.token(t, "token-1")
+
+	mock.ExpectExec(`INSERT INTO revoked_tokens`).
+		WithArgs("token-1", sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`DELETE FROM revoked_tokens WHERE expires_at < NOW\(\)`).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/auth/logout", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Cookie", "refresh_token=old")
+	rec := httptest.NewRecorder()
+	Logout(stub.URL, issuer.keys)(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d: %s", rec.Code, http.StatusOK, rec.Body.String())
+	}
+	if got := rec.Header().Get("Set-Cookie"); got != "refresh_token=; Max-Age=0" {
+		t.Errorf("Set-Cookie = %q, want the auth-service's cleared cookie", got)
+	}
+	if stub.gotCookie != "refresh_token=old" {
+		t.Errorf("auth-service got Cookie %q", stub.gotCookie)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestLogoutSucceedsWhenAuthServiceUnreachable(t *testing.T) {
+	issuer := newTestTokenIssuer(t)
+	mock := mockDatabase(t)
+	stub := newAuthServiceStub(t, http.StatusOK, "", "")
+	stub.Close()
+
+	mock.ExpectExec(`INSERT INTO revoked_tokens`).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`DELETE FROM revoked_tokens`).WillReturnResult(sqlmock.NewResult(0, 0))
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/auth/logout", nil)
+	req.Header.Set("Authorization", "Bearer "+issuer.token(t, "token-2"))
+	rec := httptest.NewRecorder()
+	Logout(stub.URL, issuer.keys)(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusOK)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestAuthRejectsRevokedToken(t *testing.T) {
+	issuer := newTestTokenIssuer(t)
+	mock := mockDatabase(t)
+
+	mock.ExpectQuery(`SELECT EXISTS\(SELECT 1 FROM revoked_tokens WHERE token_id = \$1\)`).
+		WithArgs("token-3").
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+
+	called := false
+	handler := middleware.Auth(issuer.keys)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/feedback", nil)
+	req.Header.Set("Authorization", "Bearer "+issuer.token(t, "token-3"))
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusUnauthorized {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusUnauthorized)
+	}
+	if called {
+		t.Error("handler was called for a revoked token")
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,284 @@
+package controllers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/frallan97/feedback-service/backend/database"
+	"github.com/frallan97/feedback-service/backend/models"
+	"github.com/frallan97/feedback-service/backend/services"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/lib/pq"
+)
+
+// blocklistRuleColumns are the blocklist_rules columns scanned by scanBlocklistRule
+const blocklistRuleColumns = `id, application_id, type, value, action, COALESCE(description, ''),
+	hit_count, last_hit_at, created_by, created_at, updated_at`
+
+func scanBlocklistRule(row rowScanner) (*models.BlocklistRule, error) {
+	var rule models.BlocklistRule
+	err := row.Scan(
+		&rule.ID, &rule.ApplicationID, &rule.Type, &rule.Value, &rule.Action, &rule.Description,
+		&rule.HitCount, &rule.LastHitAt, &rule.CreatedBy, &rule.CreatedAt, &rule.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &rule, nil
+}
+
+// blocklistValueError returns the error response for a rule value that failed validation
+func blocklistValueError(err error) string {
+	msg, _ := json.Marshal(map[string]string{"error": err.Error()})
+	return string(msg)
+}
+
+// GetBlocklistRules returns an application's blocklist rules with their hit counts
+// (application owners and admins)
+func GetBlocklistRules(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	vars := mux.Vars(r)
+	appID := vars["id"]
+
+	rows, err := database.DB.QueryContext(r.Context(), `
+		SELECT `+blocklistRuleColumns+`
+		FROM blocklist_rules
+		WHERE application_id = $1
+		ORDER BY created_at, id
+	`, appID)
+	if err != nil {
+		http.Error(w, `{"error":"Failed to fetch blocklist rules"}`, http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	rules := []*models.BlocklistRule{}
+	for rows.Next() {
+		rule, err := scanBlocklistRule(rows)
+		if err != nil {
+			continue
+		}
+		rules = append(rules, rule)
+	}
+
+	json.NewEncoder(w).Encode(rules)
+}
+
+// GetBlocklistRule returns a single blocklist rule (application owners and admins)
+func GetBlocklistRule(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	vars := mux.Vars(r)
+	appID := vars["id"]
+	ruleID := vars["rule_id"]
+
+	rule, err := scanBlocklistRule(database.DB.QueryRowContext(r.Context(), `
+		SELECT `+blocklistRuleColumns+`
+		FROM blocklist_rules
+		WHERE id = $1 AND application_id = $2
+	`, ruleID, appID))
+	if err == sql.ErrNoRows {
+		http.Error(w, `{"error":"Blocklist rule not found"}`, http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, `{"error":"Failed to fetch blocklist rule"}`, http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(rule)
+}
+
+// CreateBlocklistRule adds a blocklist rule to an application (application owners and admins)
+func CreateBlocklistRule(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	vars := mux.Vars(r)
+	appID, err := uuid.Parse(vars["id"])
+	if err != nil {
+		http.Error(w, `{"error":"Invalid application ID"}`, http.StatusBadRequest)
+		return
+	}
+
+	var req struct {
+		Type        string `json:"type"`
+		Value       string `json:"value"`
+		Action      string `json:"action"`
+		Description string `json:"description"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, `{"error":"Invalid request body"}`, http.StatusBadRequest)
+		return
+	}
+
+	if !models.IsValidBlocklistType(req.Type) {
+		http.Error(w, `{"error":"type must be one of email, email_domain, ip, phrase"}`, http.StatusBadRequest)
+		return
+	}
+	if req.Action == "" {
+		req.Action = models.BlocklistActionReject
+	}
+	if !models.IsValidBlocklistAction(req.Action) {
+		http.Error(w, `{"error":"action must be reject or discard"}`, http.StatusBadRequest)
+		return
+	}
+	value, err := services.NormalizeBlocklistValue(req.Type, req.Value)
+	if err != nil {
+		http.Error(w, blocklistValueError(err), http.StatusBadRequest)
+		return
+	}
+
+	rule, err := scanBlocklistRule(database.DB.QueryRowContext(r.Context(), `
+		INSERT INTO blocklist_rules (application_id, type, value, action, description, created_by)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING `+blocklistRuleColumns,
+		appID, req.Type, value, req.Action, req.Description, currentUserID(r),
+	))
+	if err != nil {
+		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23503" {
+			http.Error(w, `{"error":"Application not found"}`, http.StatusNotFound)
+			return
+		}
+		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
+			http.Error(w, `{"error":"The application already has this rule"}`, http.StatusConflict)
+			return
+		}
+		http.Error(w, `{"error":"Failed to create blocklist rule"}`, http.StatusInternalServerError)
+		return
+	}
+
+	recordAudit(r, "blocklist_rule.create", "blocklist_rule", rule.ID, nil, auditSnapshot(r, "blocklist_rules", rule.ID))
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(rule)
+}
+
+// UpdateBlocklistRule changes a blocklist rule's value, action or description (application
+// owners and admins). A rule's type can't change.
+func UpdateBlocklistRule(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	vars := mux.Vars(r)
+	appID := vars["id"]
+	ruleID := vars["rule_id"]
+
+	var req struct {
+		Value       *string `json:"value"`
+		Action      *string `json:"action"`
+		Description *string `json:"description"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, `{"error":"Invalid request body"}`, http.StatusBadRequest)
+		return
+	}
+
+	updates := []string{}
+	args := []interface{}{}
+	argPos := 1
+
+	if req.Value != nil {
+		var ruleType string
+		err := database.DB.QueryRowContext(r.Context(),
+			"SELECT type FROM blocklist_rules WHERE id = $1 AND application_id = $2",
+			ruleID, appID,
+		).Scan(&ruleType)
+		if err == sql.ErrNoRows {
+			http.Error(w, `{"error":"Blocklist rule not found"}`, http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			http.Error(w, `{"error":"Failed to update blocklist rule"}`, http.StatusInternalServerError)
+			return
+		}
+
+		value, err := services.NormalizeBlocklistValue(ruleType, *req.Value)
+		if err != nil {
+			http.Error(w, blocklistValueError(err), http.StatusBadRequest)
+			return
+		}
+		updates = append(updates, "value = $"+strconv.Itoa(argPos))
+		args = append(args, value)
+		argPos++
+	}
+
+	if req.Action != nil {
+		if !models.IsValidBlocklistAction(*req.Action) {
+			http.Error(w, `{"error":"action must be reject or discard"}`, http.StatusBadRequest)
+			return
+		}
+		updates = append(updates, "action = $"+strconv.Itoa(argPos))
+		args = append(args, *req.Action)
+		argPos++
+	}
+
+	if req.Description != nil {
+		updates = append(updates, "description = $"+strconv.Itoa(argPos))
+		args = append(args, *req.Description)
+		argPos++
+	}
+
+	if len(updates) == 0 {
+		http.Error(w, `{"error":"No fields to update"}`, http.StatusBadRequest)
+		return
+	}
+
+	snapshot := auditSnapshot(r, "blocklist_rules", ruleID)
+
+	args = append(args, ruleID, appID)
+	query := "UPDATE blocklist_rules SET " + strings.Join(updates, ", ") + ", updated_at = NOW()" +
+		" WHERE id = $" + strconv.Itoa(argPos) + " AND application_id = $" + strconv.Itoa(argPos+1) +
+		" RETURNING " + blocklistRuleColumns
+
+	rule, err := scanBlocklistRule(database.DB.QueryRowContext(r.Context(), query, args...))
+	if err == sql.ErrNoRows {
+		http.Error(w, `{"error":"Blocklist rule not found"}`, http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
+			http.Error(w, `{"error":"The application already has this rule"}`, http.StatusConflict)
+			return
+		}
+		http.Error(w, `{"error":"Failed to update blocklist rule"}`, http.StatusInternalServerError)
+		return
+	}
+
+	recordAudit(r, "blocklist_rule.update", "blocklist_rule", ruleID, snapshot, auditSnapshot(r, "blocklist_rules", ruleID))
+
+	json.NewEncoder(w).Encode(rule)
+}
+
+// DeleteBlocklistRule removes a blocklist rule (application owners and admins)
+func DeleteBlocklistRule(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	vars := mux.Vars(r)
+	appID := vars["id"]
+	ruleID := vars["rule_id"]
+
+	snapshot := auditSnapshot(r, "blocklist_rules", ruleID)
+
+	result, err := database.DB.ExecContext(r.Context(),
+		"DELETE FROM blocklist_rules WHERE id = $1 AND application_id = $2",
+		ruleID, appID,
+	)
+	if err != nil {
+		http.Error(w, `{"error":"Failed to delete blocklist rule"}`, http.StatusInternalServerError)
+		return
+	}
+
+	rows, _ := result.RowsAffected()
+	if rows == 0 {
+		http.Error(w, `{"error":"Blocklist rule not found"}`, http.StatusNotFound)
+		return
+	}
+
+	recordAudit(r, "blocklist_rule.delete", "blocklist_rule", ruleID, snapshot, nil)
+
+	json.NewEncoder(w).Encode(map[string]string{"message": "Blocklist rule deleted successfully"})
+}
@@ -0,0 +1,110 @@
+package controllers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/frallan97/feedback-service/backend/database"
+	"github.com/frallan97/feedback-service/backend/middleware"
+	"github.com/frallan97/feedback-service/backend/services"
+	"github.com/google/uuid"
+)
+
+// GetPublicChallenge issues a proof-of-work challenge for submitting feedback (public
+// endpoint). Its difficulty rises while the client or application is being rate limited.
+func GetPublicChallenge(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	// Get application ID from context (set by AppAuth middleware)
+	appID, ok := middleware.GetAppID(r.Context())
+	if !ok {
+		http.Error(w, `{"error":"Application ID not found"}`, http.StatusUnauthorized)
+		return
+	}
+
+	settings, err := services.GetProofOfWorkSettings(r.Context(), appID)
+	if err != nil {
+		http.Error(w, `{"error":"Failed to issue challenge"}`, http.StatusInternalServerError)
+		return
+	}
+
+	difficulty := services.ChallengeDifficulty(r.Context(), appID, settings.Difficulty, middleware.GetClientIP(r.Context()))
+	challenge, err := services.IssueChallenge(appID, difficulty)
+	if err != nil {
+		http.Error(w, `{"error":"Failed to issue challenge"}`, http.StatusInternalServerError)
+		return
+	}
+	challenge.Required = settings.Enabled
+
+	// Every challenge must be fresh
+	w.Header().Set("Cache-Control", "no-store")
+	json.NewEncoder(w).Encode(challenge)
+}
+
+// checkProofOfWork verifies the proof of work an anonymous submission must carry if the
+// application requires it. It returns the solved challenge for storeWithChallenge (nil if
+// none is required), or an error message and status code if the submission should be
+// rejected.
+func checkProofOfWork(ctx context.Context, appID uuid.UUID, challenge, solution string) (*services.SolvedChallenge, string, int) {
+	settings, err := services.GetProofOfWorkSettings(ctx, appID)
+	if err != nil {
+		return nil, `{"error":"Failed to verify proof of work"}`, http.StatusInternalServerError
+	}
+	if !settings.Enabled {
+		return nil, "", 0
+	}
+	if challenge == "" || solution == "" {
+		return nil, `{"error":"Proof of work required: solve a challenge from /api/v1/public/challenge"}`, http.StatusForbidden
+	}
+
+	solved, err := services.VerifyProofOfWork(appID, challenge, solution)
+	if err != nil {
+		msg, code := proofOfWorkError(appID, err)
+		return nil, msg, code
+	}
+	return solved, "", 0
+}
+
+// proofOfWorkError returns the error message and status code for a failed proof of work
+func proofOfWorkError(appID uuid.UUID, err error) (string, int) {
+	switch {
+	case errors.Is(err, services.ErrInvalidChallenge):
+		return `{"error":"Invalid challenge"}`, http.StatusForbidden
+	case errors.Is(err, services.ErrChallengeExpired):
+		return `{"error":"Challenge has expired, fetch a new one"}`, http.StatusForbidden
+	case errors.Is(err, services.ErrChallengeSpent):
+		return `{"error":"Challenge has already been used, fetch a new one"}`, http.StatusForbidden
+	case errors.Is(err, services.ErrInvalidSolution):
+		return `{"error":"Invalid proof-of-work solution"}`, http.StatusForbidden
+	default:
+		log.Printf("[AUTH] Failed to verify proof of work for application %s: %v", appID, err)
+		return `{"error":"Failed to verify proof of work"}`, http.StatusInternalServerError
+	}
+}
+
+// storeWithChallenge runs store (if not nil) in a transaction that also spends the
+// submission's solved challenge (if any), so a challenge is only used up by a submission
+// that's accepted. It returns services.ErrChallengeSpent if the challenge was already used.
+func storeWithChallenge(ctx context.Context, appID uuid.UUID, solved *services.SolvedChallenge, store func(tx *sql.Tx) error) error {
+	tx, err := database.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if solved != nil {
+		if err := services.SpendChallenge(ctx, tx, appID, solved); err != nil {
+			return err
+		}
+	}
+	if store != nil {
+		if err := store(tx); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
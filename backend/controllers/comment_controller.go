@@ -8,10 +8,16 @@ import (
 	"github.com/frallan97/feedback-service/backend/database"
 	"github.com/frallan97/feedback-service/backend/middleware"
 	"github.com/frallan97/feedback-service/backend/models"
+	"github.com/frallan97/feedback-service/backend/services"
 	"github.com/google/uuid"
 	"github.com/gorilla/mux"
 )
 
+// canUseInternalComments reports whether a role can read and write internal (staff-only) comments
+func canUseInternalComments(role string) bool {
+	return models.RoleAtLeast(role, models.RoleTriager)
+}
+
 // GetComments returns all comments for a feedback item
 func GetComments(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
@@ -20,18 +26,15 @@ func GetComments(w http.ResponseWriter, r *http.Request) {
 	feedbackID := vars["id"]
 
 	// Get user from context
-	var isAdmin bool
-	if claims, ok := middleware.GetUserClaims(r.Context()); ok {
-		isAdmin = claims.Role == "admin"
-	}
+	canSeeInternal := canUseInternalComments(middleware.GetRole(r.Context()))
 
-	// Query comments - hide internal comments from non-admin users
+	// Query comments - hide internal comments from viewers
 	query := `
 		SELECT id, feedback_id, user_id, content, is_internal, created_at, updated_at
 		FROM feedback_comments
 		WHERE feedback_id = $1
 	`
-	if !isAdmin {
+	if !canSeeInternal {
 		query += " AND is_internal = false"
 	}
 	query += " ORDER BY created_at ASC"
@@ -84,20 +87,20 @@ func CreateComment(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Only admins can create internal comments
-	if req.IsInternal && claims.Role != "admin" {
-		http.Error(w, `{"error":"Only admins can create internal comments"}`, http.StatusForbidden)
+	// Only triagers and admins can create internal comments
+	if req.IsInternal && !canUseInternalComments(middleware.GetRole(r.Context())) {
+		http.Error(w, `{"error":"Only triagers and admins can create internal comments"}`, http.StatusForbidden)
 		return
 	}
 
 	// Verify feedback exists
-	var exists bool
+	var appID uuid.UUID
 	err := database.DB.QueryRowContext(r.Context(),
-		"SELECT EXISTS(SELECT 1 FROM feedback WHERE id = $1)",
+		"SELECT application_id FROM feedback WHERE id = $1",
 		feedbackID,
-	).Scan(&exists)
+	).Scan(&appID)
 
-	if err != nil || !exists {
+	if err != nil {
 		http.Error(w, `{"error":"Feedback not found"}`, http.StatusNotFound)
 		return
 	}
@@ -117,6 +120,11 @@ func CreateComment(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	recordAudit(r, "comment.create", "comment", comment.ID, nil, auditSnapshot(r, commentAuditTable, comment.ID))
+
+	// Notify the application's webhooks
+	services.EmitWebhookEvent(r.Context(), appID, services.EventCommentCreated, comment)
+
 	w.WriteHeader(http.StatusCreated)
 	json.NewEncoder(w).Encode(comment)
 }
@@ -165,12 +173,14 @@ func UpdateComment(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Only owner or admin can update
-	if ownerID != claims.UserID && claims.Role != "admin" {
+	// Only the author, application owners or admins can update
+	if ownerID != claims.UserID && !models.RoleAtLeast(middleware.GetRole(r.Context()), models.RoleOwner) {
 		http.Error(w, `{"error":"You can only update your own comments"}`, http.StatusForbidden)
 		return
 	}
 
+	snapshot := auditSnapshot(r, commentAuditTable, commentID)
+
 	// Update comment
 	result, err := database.DB.ExecContext(r.Context(),
 		"UPDATE feedback_comments SET content = $1 WHERE id = $2",
@@ -187,6 +197,8 @@ func UpdateComment(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	recordAudit(r, "comment.update", "comment", commentID, snapshot, auditSnapshot(r, commentAuditTable, commentID))
+
 	json.NewEncoder(w).Encode(map[string]string{"message": "Comment updated successfully"})
 }
 
@@ -220,12 +232,14 @@ func DeleteComment(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Only owner or admin can delete
-	if ownerID != claims.UserID && claims.Role != "admin" {
+	// Only the author, application owners or admins can delete
+	if ownerID != claims.UserID && !models.RoleAtLeast(middleware.GetRole(r.Context()), models.RoleOwner) {
 		http.Error(w, `{"error":"You can only delete your own comments"}`, http.StatusForbidden)
 		return
 	}
 
+	snapshot := auditSnapshot(r, commentAuditTable, commentID)
+
 	// Delete comment
 	result, err := database.DB.ExecContext(r.Context(), "DELETE FROM feedback_comments WHERE id = $1", commentID)
 	if err != nil {
@@ -239,5 +253,7 @@ func DeleteComment(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	recordAudit(r, "comment.delete", "comment", commentID, snapshot, nil)
+
 	json.NewEncoder(w).Encode(map[string]string{"message": "Comment deleted successfully"})
 }
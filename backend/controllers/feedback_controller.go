@@ -1,8 +1,11 @@
 package controllers
 
 import (
+	"context"
 	"database/sql"
 	"encoding/json"
+	"errors"
+	"log"
 	"net/http"
 	"strconv"
 	"time"
@@ -10,8 +13,10 @@ import (
 	"github.com/frallan97/feedback-service/backend/database"
 	"github.com/frallan97/feedback-service/backend/middleware"
 	"github.com/frallan97/feedback-service/backend/models"
+	"github.com/frallan97/feedback-service/backend/services"
 	"github.com/google/uuid"
 	"github.com/gorilla/mux"
+	"github.com/lib/pq"
 )
 
 // SubmitFeedback handles public feedback submission (API key authenticated)
@@ -36,6 +41,9 @@ func SubmitFeedback(w http.ResponseWriter, r *http.Request) {
 		AppVersion   string                 `json:"app_version"`
 		Metadata     map[string]interface{} `json:"metadata"`
 		ContactEmail string                 `json:"contact_email"`
+		Honeypot     string                 `json:"honeypot"` // A hidden form field only bots fill in
+		PowChallenge string                 `json:"pow_challenge"`
+		PowSolution  string                 `json:"pow_solution"`
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -54,33 +62,141 @@ func SubmitFeedback(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Get user ID from JWT token if available (optional)
-	var userID *uuid.UUID
-	if userClaims, ok := r.Context().Value(middleware.UserClaimsKey).(*middleware.Claims); ok {
-		userID = &userClaims.UserID
+	// Identify the reporter if the client application vouches for them with a signed token
+	var endUserID *uuid.UUID
+	if token := r.Header.Get(services.UserTokenHeader); token != "" {
+		id, err := identifyEndUser(r.Context(), appID, token)
+		if errors.Is(err, services.ErrNoIdentityKey) {
+			http.Error(w, `{"error":"Application has no identity key for this user token's algorithm"}`, http.StatusBadRequest)
+			return
+		}
+		if errors.Is(err, services.ErrInvalidIdentityToken) {
+			log.Printf("[AUTH] Rejected user token for application %s: %v", appID, err)
+			http.Error(w, `{"error":"Invalid user token"}`, http.StatusUnauthorized)
+			return
+		}
+		if err != nil {
+			http.Error(w, `{"error":"Failed to verify user token"}`, http.StatusInternalServerError)
+			return
+		}
+		endUserID = &id
+	}
+
+	// Anonymous submissions from browsers and apps may have to prove they did some work;
+	// signed requests come from the application's servers
+	_, hasKey := middleware.GetAPIKeyID(r.Context())
+	var solved *services.SolvedChallenge
+	if hasKey && endUserID == nil {
+		var msg string
+		var code int
+		if solved, msg, code = checkProofOfWork(r.Context(), appID, req.PowChallenge, req.PowSolution); msg != "" {
+			http.Error(w, msg, code)
+			return
+		}
+	}
+
+	// Apply the application's blocklist. A signed request's IP is the application's server,
+	// so IP rules only apply to API key requests.
+	blockedIP := ""
+	if hasKey {
+		blockedIP = middleware.GetClientIP(r.Context())
+	}
+	rule, err := services.MatchBlocklist(r.Context(), appID, services.BlocklistSubmission{
+		ContactEmail: req.ContactEmail,
+		IP:           blockedIP,
+		Text:         req.Title + "\n" + req.Content,
+	})
+	if err != nil {
+		log.Printf("[BLOCKLIST] Failed to check submission for application %s: %v", appID, err)
+	} else if rule != nil {
+		log.Printf("[BLOCKLIST] Rule %s (%s) matched a submission for application %s, action %s", rule.ID, rule.Type, appID, rule.Action)
+		if rule.Action == models.BlocklistActionDiscard {
+			// Answer like a stored submission, so the sender has no reason to change tactics.
+			// That includes spending the challenge, so reusing it fails the same way, and a
+			// tombstone the status endpoint answers from.
+			var discardedID uuid.UUID
+			err := storeWithChallenge(r.Context(), appID, solved, func(tx *sql.Tx) error {
+				var err error
+				discardedID, err = services.RecordDiscardedSubmission(r.Context(), tx, appID)
+				return err
+			})
+			if err != nil {
+				if errors.Is(err, services.ErrChallengeSpent) {
+					msg, code := proofOfWorkError(appID, err)
+					http.Error(w, msg, code)
+					return
+				}
+				http.Error(w, `{"error":"Failed to create feedback"}`, http.StatusInternalServerError)
+				return
+			}
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"id":      discardedID,
+				"message": "Feedback submitted successfully",
+			})
+			return
+		}
+		http.Error(w, `{"error":"Submission blocked"}`, http.StatusForbidden)
+		return
+	}
+
+	// Hold likely spam for moderation. The submitter isn't told, so bots learn nothing.
+	status := models.FeedbackStatusNew
+	spam, err := scoreSubmission(r.Context(), appID, services.SpamSubmission{
+		Title:        req.Title,
+		Content:      req.Content,
+		ContactEmail: req.ContactEmail,
+		Honeypot:     req.Honeypot,
+	})
+	if err != nil {
+		// Losing feedback is worse than letting spam through
+		log.Printf("[SPAM] Failed to score submission for application %s: %v", appID, err)
+	} else if spam != nil && spam.held {
+		status = models.FeedbackStatusHeld
+	}
+
+	var spamScore interface{}
+	var spamReasons interface{}
+	if spam != nil {
+		spamScore = spam.Score
+		spamReasons = pq.Array(spam.Reasons)
 	}
 
 	// Convert browser_info and metadata to JSON
 	browserInfoJSON, _ := json.Marshal(req.BrowserInfo)
 	metadataJSON, _ := json.Marshal(req.Metadata)
 
-	// Insert feedback
+	// Insert feedback, spending the proof-of-work challenge with it
 	var feedbackID uuid.UUID
-	err := database.DB.QueryRowContext(r.Context(), `
-		INSERT INTO feedback (
-			application_id, user_id, category_id, title, content, rating,
-			status, priority, page_url, browser_info, app_version, metadata, contact_email
-		) VALUES ($1, $2, $3, $4, $5, $6, 'new', 'medium', $7, $8, $9, $10, $11)
-		RETURNING id
-	`, appID, userID, req.CategoryID, req.Title, req.Content, req.Rating,
-		req.PageURL, browserInfoJSON, req.AppVersion, metadataJSON, req.ContactEmail,
-	).Scan(&feedbackID)
-
+	err = storeWithChallenge(r.Context(), appID, solved, func(tx *sql.Tx) error {
+		return tx.QueryRowContext(r.Context(), `
+			INSERT INTO feedback (
+				application_id, end_user_id, category_id, title, content, rating,
+				status, priority, page_url, browser_info, app_version, metadata, contact_email,
+				spam_score, spam_reasons
+			) VALUES ($1, $2, $3, $4, $5, $6, $7, 'medium', $8, $9, $10, $11, $12, $13, $14)
+			RETURNING id
+		`, appID, endUserID, req.CategoryID, req.Title, req.Content, req.Rating,
+			status, req.PageURL, browserInfoJSON, req.AppVersion, metadataJSON, req.ContactEmail,
+			spamScore, spamReasons,
+		).Scan(&feedbackID)
+	})
+	if errors.Is(err, services.ErrChallengeSpent) {
+		msg, code := proofOfWorkError(appID, err)
+		http.Error(w, msg, code)
+		return
+	}
 	if err != nil {
 		http.Error(w, `{"error":"Failed to create feedback"}`, http.StatusInternalServerError)
 		return
 	}
 
+	// Notify the application's webhooks; held feedback is announced once it's approved
+	if status == models.FeedbackStatusHeld {
+		log.Printf("[SPAM] Held feedback %s for application %s (score %.2f, reasons %v)", feedbackID, appID, spam.Score, spam.Reasons)
+	} else if f, err := loadFeedback(r.Context(), feedbackID); err == nil {
+		services.EmitWebhookEvent(r.Context(), appID, services.EventFeedbackCreated, f)
+	}
+
 	// Return feedback ID
 	json.NewEncoder(w).Encode(map[string]interface{}{
 		"id":      feedbackID,
@@ -98,6 +214,7 @@ func GetFeedback(w http.ResponseWriter, r *http.Request) {
 	status := query.Get("status")
 	priority := query.Get("priority")
 	categoryID := query.Get("category_id")
+	endUserID := query.Get("end_user_id")
 	page, _ := strconv.Atoi(query.Get("page"))
 	limit, _ := strconv.Atoi(query.Get("limit"))
 
@@ -109,19 +226,31 @@ func GetFeedback(w http.ResponseWriter, r *http.Request) {
 	}
 	offset := (page - 1) * limit
 
-	// Build query
+	// Build query. Held and rejected feedback is only listed when asked for by status.
 	queryStr := `
-		SELECT id, application_id, user_id, category_id, title, content, rating,
+		SELECT feedback.id, feedback.application_id, user_id, category_id, title, content, rating,
 			   status, priority, page_url, browser_info, app_version, metadata,
-			   contact_email, created_at, updated_at, reviewed_at, resolved_at
+			   contact_email, created_at, updated_at, reviewed_at, resolved_at,
+			   (SELECT a.id FROM feedback_attachments a
+				WHERE a.feedback_id = feedback.id AND a.thumbnail_key IS NOT NULL
+				ORDER BY a.created_at LIMIT 1) AS thumbnail_attachment_id,
+			   ` + reporterColumns + `
 		FROM feedback
+		LEFT JOIN end_users eu ON eu.id = feedback.end_user_id
 		WHERE 1=1
 	`
 	args := []interface{}{}
 	argPos := 1
 
+	// Non-admins only see feedback for applications they're a member of
+	memberID, scoped := memberScope(r)
+	if scoped {
+		queryStr += " AND feedback.application_id IN (SELECT application_id FROM application_members WHERE user_id = $" + strconv.Itoa(argPos) + ")"
+		args = append(args, memberID)
+		argPos++
+	}
 	if appID != "" {
-		queryStr += " AND application_id = $" + strconv.Itoa(argPos)
+		queryStr += " AND feedback.application_id = $" + strconv.Itoa(argPos)
 		args = append(args, appID)
 		argPos++
 	}
@@ -129,6 +258,8 @@ func GetFeedback(w http.ResponseWriter, r *http.Request) {
 		queryStr += " AND status = $" + strconv.Itoa(argPos)
 		args = append(args, status)
 		argPos++
+	} else {
+		queryStr += " AND status NOT IN ('held', 'rejected')"
 	}
 	if priority != "" {
 		queryStr += " AND priority = $" + strconv.Itoa(argPos)
@@ -140,8 +271,13 @@ func GetFeedback(w http.ResponseWriter, r *http.Request) {
 		args = append(args, categoryID)
 		argPos++
 	}
+	if endUserID != "" {
+		queryStr += " AND feedback.end_user_id = $" + strconv.Itoa(argPos)
+		args = append(args, endUserID)
+		argPos++
+	}
 
-	queryStr += " ORDER BY created_at DESC LIMIT $" + strconv.Itoa(argPos) + " OFFSET $" + strconv.Itoa(argPos+1)
+	queryStr += " ORDER BY feedback.created_at DESC LIMIT $" + strconv.Itoa(argPos) + " OFFSET $" + strconv.Itoa(argPos+1)
 	args = append(args, limit, offset)
 
 	// Execute query
@@ -157,15 +293,24 @@ func GetFeedback(w http.ResponseWriter, r *http.Request) {
 	for rows.Next() {
 		var f models.Feedback
 		var browserInfoJSON, metadataJSON []byte
+		var thumbnailAttachmentID uuid.NullUUID
+		var reporter reporterRow
 
-		err := rows.Scan(
+		dest := []interface{}{
 			&f.ID, &f.ApplicationID, &f.UserID, &f.CategoryID, &f.Title, &f.Content, &f.Rating,
 			&f.Status, &f.Priority, &f.PageURL, &browserInfoJSON, &f.AppVersion, &metadataJSON,
 			&f.ContactEmail, &f.CreatedAt, &f.UpdatedAt, &f.ReviewedAt, &f.ResolvedAt,
-		)
-		if err != nil {
+			&thumbnailAttachmentID,
+		}
+		if err := rows.Scan(append(dest, reporter.dest()...)...); err != nil {
 			continue
 		}
+		f.Reporter = reporter.endUser(f.ApplicationID)
+
+		// Preview of the first processed image attachment for the dashboard list
+		if thumbnailAttachmentID.Valid {
+			f.ThumbnailURL = services.SignedAttachmentThumbnailURL(thumbnailAttachmentID.UUID)
+		}
 
 		// Parse JSON fields
 		if browserInfoJSON != nil {
@@ -183,6 +328,11 @@ func GetFeedback(w http.ResponseWriter, r *http.Request) {
 	countQuery := "SELECT COUNT(*) FROM feedback WHERE 1=1"
 	countArgs := []interface{}{}
 	argPos = 1
+	if scoped {
+		countQuery += " AND application_id IN (SELECT application_id FROM application_members WHERE user_id = $" + strconv.Itoa(argPos) + ")"
+		countArgs = append(countArgs, memberID)
+		argPos++
+	}
 	if appID != "" {
 		countQuery += " AND application_id = $" + strconv.Itoa(argPos)
 		countArgs = append(countArgs, appID)
@@ -192,6 +342,8 @@ func GetFeedback(w http.ResponseWriter, r *http.Request) {
 		countQuery += " AND status = $" + strconv.Itoa(argPos)
 		countArgs = append(countArgs, status)
 		argPos++
+	} else {
+		countQuery += " AND status NOT IN ('held', 'rejected')"
 	}
 	if priority != "" {
 		countQuery += " AND priority = $" + strconv.Itoa(argPos)
@@ -203,6 +355,11 @@ func GetFeedback(w http.ResponseWriter, r *http.Request) {
 		countArgs = append(countArgs, categoryID)
 		argPos++
 	}
+	if endUserID != "" {
+		countQuery += " AND end_user_id = $" + strconv.Itoa(argPos)
+		countArgs = append(countArgs, endUserID)
+		argPos++
+	}
 	database.DB.QueryRowContext(r.Context(), countQuery, countArgs...).Scan(&total)
 
 	// Return response
@@ -221,29 +378,44 @@ func GetFeedbackByID(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	feedbackID := vars["id"]
 
+	f, err := loadFeedback(r.Context(), feedbackID)
+	if err == sql.ErrNoRows {
+		http.Error(w, `{"error":"Feedback not found"}`, http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, `{"error":"Failed to fetch feedback"}`, http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(f)
+}
+
+// loadFeedback fetches a single feedback item by ID, returning sql.ErrNoRows if it doesn't exist
+func loadFeedback(ctx context.Context, feedbackID interface{}) (*models.Feedback, error) {
 	var f models.Feedback
 	var browserInfoJSON, metadataJSON []byte
 
-	err := database.DB.QueryRowContext(r.Context(), `
-		SELECT id, application_id, user_id, category_id, title, content, rating,
-			   status, priority, page_url, browser_info, app_version, metadata,
-			   contact_email, created_at, updated_at, reviewed_at, resolved_at
-		FROM feedback
-		WHERE id = $1
-	`, feedbackID).Scan(
+	var reporter reporterRow
+
+	dest := []interface{}{
 		&f.ID, &f.ApplicationID, &f.UserID, &f.CategoryID, &f.Title, &f.Content, &f.Rating,
 		&f.Status, &f.Priority, &f.PageURL, &browserInfoJSON, &f.AppVersion, &metadataJSON,
 		&f.ContactEmail, &f.CreatedAt, &f.UpdatedAt, &f.ReviewedAt, &f.ResolvedAt,
-	)
-
-	if err == sql.ErrNoRows {
-		http.Error(w, `{"error":"Feedback not found"}`, http.StatusNotFound)
-		return
 	}
+	err := database.DB.QueryRowContext(ctx, `
+		SELECT feedback.id, feedback.application_id, user_id, category_id, title, content, rating,
+			   status, priority, page_url, browser_info, app_version, metadata,
+			   contact_email, created_at, updated_at, reviewed_at, resolved_at,
+			   `+reporterColumns+`
+		FROM feedback
+		LEFT JOIN end_users eu ON eu.id = feedback.end_user_id
+		WHERE feedback.id = $1
+	`, feedbackID).Scan(append(dest, reporter.dest()...)...)
 	if err != nil {
-		http.Error(w, `{"error":"Failed to fetch feedback"}`, http.StatusInternalServerError)
-		return
+		return nil, err
 	}
+	f.Reporter = reporter.endUser(f.ApplicationID)
 
 	// Parse JSON fields
 	if browserInfoJSON != nil {
@@ -253,7 +425,51 @@ func GetFeedbackByID(w http.ResponseWriter, r *http.Request) {
 		json.Unmarshal(metadataJSON, &f.Metadata)
 	}
 
-	json.NewEncoder(w).Encode(f)
+	return &f, nil
+}
+
+// reporterColumns selects a feedback item's verified reporter, joined as eu
+const reporterColumns = `eu.id, eu.external_id, COALESCE(eu.email, ''), COALESCE(eu.name, ''), eu.first_seen_at, eu.last_seen_at`
+
+// reporterRow scans reporterColumns, which are NULL for feedback without a verified reporter
+type reporterRow struct {
+	id                  uuid.NullUUID
+	externalID          sql.NullString
+	email, name         sql.NullString
+	firstSeen, lastSeen sql.NullTime
+}
+
+func (rr *reporterRow) dest() []interface{} {
+	return []interface{}{&rr.id, &rr.externalID, &rr.email, &rr.name, &rr.firstSeen, &rr.lastSeen}
+}
+
+func (rr *reporterRow) endUser(appID uuid.UUID) *models.EndUser {
+	if !rr.id.Valid {
+		return nil
+	}
+	return &models.EndUser{
+		ID:            rr.id.UUID,
+		ApplicationID: appID,
+		ExternalID:    rr.externalID.String,
+		Email:         rr.email.String,
+		Name:          rr.name.String,
+		FirstSeenAt:   rr.firstSeen.Time,
+		LastSeenAt:    rr.lastSeen.Time,
+	}
+}
+
+// identifyEndUser verifies an end-user identity token with the application's keys and
+// records the end user
+func identifyEndUser(ctx context.Context, appID uuid.UUID, token string) (uuid.UUID, error) {
+	identity, err := services.GetApplicationIdentity(ctx, appID)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	claims, err := services.VerifyIdentityToken(token, identity)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	return services.UpsertEndUser(ctx, appID, claims)
 }
 
 // UpdateFeedback updates feedback status, priority, or other fields (admin endpoint)
@@ -275,6 +491,30 @@ func UpdateFeedback(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Capture the current state so a status change can be announced
+	before, err := loadFeedback(r.Context(), feedbackID)
+	if err == sql.ErrNoRows {
+		http.Error(w, `{"error":"Feedback not found"}`, http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, `{"error":"Failed to fetch feedback"}`, http.StatusInternalServerError)
+		return
+	}
+	// Only the spam filter holds feedback, and moderation decisions go through approve and
+	// reject, which also train the filter
+	if req.Status != nil && *req.Status != before.Status {
+		if *req.Status == models.FeedbackStatusHeld || *req.Status == models.FeedbackStatusRejected {
+			http.Error(w, `{"error":"status can't be set to held or rejected; reject feedback with POST /api/v1/feedback/:id/reject"}`, http.StatusBadRequest)
+			return
+		}
+		if before.Status == models.FeedbackStatusHeld || before.Status == models.FeedbackStatusRejected {
+			http.Error(w, `{"error":"Held and rejected feedback is released with POST /api/v1/feedback/:id/approve"}`, http.StatusConflict)
+			return
+		}
+	}
+	snapshot := auditSnapshot(r, "feedback", feedbackID)
+
 	// Build update query dynamically
 	updates := []string{}
 	args := []interface{}{}
@@ -323,6 +563,10 @@ func UpdateFeedback(w http.ResponseWriter, r *http.Request) {
 		query += ", " + updates[i]
 	}
 	query += " WHERE id = $" + strconv.Itoa(argPos)
+	if req.Status != nil {
+		// A moderation decision may have landed since the check above
+		query += " AND (status = $1 OR status NOT IN ('held', 'rejected'))"
+	}
 
 	result, err := database.DB.ExecContext(r.Context(), query, args...)
 	if err != nil {
@@ -331,11 +575,28 @@ func UpdateFeedback(w http.ResponseWriter, r *http.Request) {
 	}
 
 	rows, _ := result.RowsAffected()
+	if rows == 0 && req.Status != nil {
+		http.Error(w, `{"error":"Feedback was moderated while it was being updated"}`, http.StatusConflict)
+		return
+	}
 	if rows == 0 {
 		http.Error(w, `{"error":"Feedback not found"}`, http.StatusNotFound)
 		return
 	}
 
+	recordAudit(r, "feedback.update", "feedback", feedbackID, snapshot, auditSnapshot(r, "feedback", feedbackID))
+
+	// Notify the application's webhooks
+	if f, err := loadFeedback(r.Context(), feedbackID); err == nil {
+		services.EmitWebhookEvent(r.Context(), f.ApplicationID, services.EventFeedbackUpdated, f)
+		if f.Status != before.Status {
+			services.EmitWebhookEvent(r.Context(), f.ApplicationID, services.EventFeedbackStatusChanged, map[string]interface{}{
+				"feedback":        f,
+				"previous_status": before.Status,
+			})
+		}
+	}
+
 	json.NewEncoder(w).Encode(map[string]string{"message": "Feedback updated successfully"})
 }
 
@@ -346,6 +607,25 @@ func DeleteFeedback(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	feedbackID := vars["id"]
 
+	// Snapshot the feedback so the webhook can carry what was deleted
+	f, err := loadFeedback(r.Context(), feedbackID)
+	if err == sql.ErrNoRows {
+		http.Error(w, `{"error":"Feedback not found"}`, http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, `{"error":"Failed to fetch feedback"}`, http.StatusInternalServerError)
+		return
+	}
+
+	// Collect attachment files before the rows cascade away
+	attachmentKeys, err := services.AttachmentKeysForFeedback(r.Context(), feedbackID)
+	if err != nil {
+		http.Error(w, `{"error":"Failed to fetch attachments"}`, http.StatusInternalServerError)
+		return
+	}
+	snapshot := auditSnapshot(r, "feedback", feedbackID)
+
 	result, err := database.DB.ExecContext(r.Context(), "DELETE FROM feedback WHERE id = $1", feedbackID)
 	if err != nil {
 		http.Error(w, `{"error":"Failed to delete feedback"}`, http.StatusInternalServerError)
@@ -358,6 +638,12 @@ func DeleteFeedback(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	services.DeleteAttachmentFiles(r.Context(), attachmentKeys)
+	recordAudit(r, "feedback.delete", "feedback", feedbackID, snapshot, nil)
+
+	// Notify the application's webhooks
+	services.EmitWebhookEvent(r.Context(), f.ApplicationID, services.EventFeedbackDeleted, f)
+
 	json.NewEncoder(w).Encode(map[string]string{"message": "Feedback deleted successfully"})
 }
 
@@ -385,6 +671,11 @@ func GetPublicFeedbackStatus(w http.ResponseWriter, r *http.Request) {
 		WHERE id = $1 AND application_id = $2
 	`, feedbackID, appID).Scan(&status, &priority, &createdAt)
 
+	if err == sql.ErrNoRows {
+		// A discarded submission looks like new feedback that's never been looked at
+		createdAt, err = services.GetDiscardedSubmission(r.Context(), appID, feedbackID)
+		status, priority = models.FeedbackStatusNew, "medium"
+	}
 	if err == sql.ErrNoRows {
 		http.Error(w, `{"error":"Feedback not found"}`, http.StatusNotFound)
 		return
@@ -394,6 +685,14 @@ func GetPublicFeedbackStatus(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Moderation is invisible to submitters: held feedback looks new, rejected looks closed
+	switch status {
+	case models.FeedbackStatusHeld:
+		status = models.FeedbackStatusNew
+	case models.FeedbackStatusRejected:
+		status = "closed"
+	}
+
 	json.NewEncoder(w).Encode(map[string]interface{}{
 		"id":         feedbackID,
 		"status":     status,
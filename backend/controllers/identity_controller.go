@@ -0,0 +1,246 @@
+package controllers
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/frallan97/feedback-service/backend/database"
+	"github.com/frallan97/feedback-service/backend/models"
+	"github.com/frallan97/feedback-service/backend/services"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+// generateIdentitySecret creates a random secret client applications sign identity tokens with
+func generateIdentitySecret() (string, error) {
+	b := make([]byte, 32)
+	_, err := rand.Read(b)
+	if err != nil {
+		return "", err
+	}
+	return "idsec_" + hex.EncodeToString(b), nil
+}
+
+// GetApplicationIdentity returns the keys an application signs end-user identity tokens with (application owners and admins)
+func GetApplicationIdentity(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	appID, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, `{"error":"Invalid application ID"}`, http.StatusBadRequest)
+		return
+	}
+
+	identity, err := services.GetApplicationIdentity(r.Context(), appID)
+	if errors.Is(err, sql.ErrNoRows) {
+		http.Error(w, `{"error":"Application not found"}`, http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, `{"error":"Failed to fetch identity keys"}`, http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(identity)
+}
+
+// UpdateApplicationIdentity sets or clears the PEM public key for RS256/ES256 identity tokens (application owners and admins)
+func UpdateApplicationIdentity(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	vars := mux.Vars(r)
+	appID := vars["id"]
+
+	var req struct {
+		PublicKey *string `json:"public_key"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, `{"error":"Invalid request body"}`, http.StatusBadRequest)
+		return
+	}
+	if req.PublicKey == nil {
+		http.Error(w, `{"error":"public_key is required (empty to remove it)"}`, http.StatusBadRequest)
+		return
+	}
+
+	publicKey := strings.TrimSpace(*req.PublicKey)
+	if publicKey != "" {
+		if _, err := services.ParseIdentityPublicKey(publicKey); err != nil {
+			http.Error(w, `{"error":"`+err.Error()+`"}`, http.StatusBadRequest)
+			return
+		}
+	}
+
+	snapshot := auditSnapshot(r, "applications", appID)
+
+	result, err := database.DB.ExecContext(r.Context(),
+		"UPDATE applications SET identity_public_key = NULLIF($1, '') WHERE id = $2",
+		publicKey, appID,
+	)
+	if err != nil {
+		http.Error(w, `{"error":"Failed to update identity key"}`, http.StatusInternalServerError)
+		return
+	}
+
+	rows, _ := result.RowsAffected()
+	if rows == 0 {
+		http.Error(w, `{"error":"Application not found"}`, http.StatusNotFound)
+		return
+	}
+
+	recordAudit(r, "application.update_identity_key", "application", appID, snapshot, auditSnapshot(r, "applications", appID))
+
+	json.NewEncoder(w).Encode(map[string]string{"message": "Identity key updated successfully"})
+}
+
+// RotateIdentitySecret generates a new secret for HS256 identity tokens (application owners and admins).
+// Tokens signed with the old secret stop verifying immediately.
+func RotateIdentitySecret(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	vars := mux.Vars(r)
+	appID := vars["id"]
+
+	secret, err := generateIdentitySecret()
+	if err != nil {
+		http.Error(w, `{"error":"Failed to generate identity secret"}`, http.StatusInternalServerError)
+		return
+	}
+
+	snapshot := auditSnapshot(r, "applications", appID)
+
+	result, err := database.DB.ExecContext(r.Context(),
+		"UPDATE applications SET identity_secret = $1 WHERE id = $2",
+		secret, appID,
+	)
+	if err != nil {
+		http.Error(w, `{"error":"Failed to update identity secret"}`, http.StatusInternalServerError)
+		return
+	}
+
+	rows, _ := result.RowsAffected()
+	if rows == 0 {
+		http.Error(w, `{"error":"Application not found"}`, http.StatusNotFound)
+		return
+	}
+
+	recordAudit(r, "application.rotate_identity_secret", "application", appID, snapshot, auditSnapshot(r, "applications", appID))
+
+	json.NewEncoder(w).Encode(map[string]string{
+		"secret":  secret,
+		"message": "Identity secret rotated successfully",
+	})
+}
+
+// DeleteIdentitySecret removes the HS256 identity secret, so only tokens signed for the public key verify (application owners and admins)
+func DeleteIdentitySecret(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	vars := mux.Vars(r)
+	appID := vars["id"]
+
+	snapshot := auditSnapshot(r, "applications", appID)
+
+	result, err := database.DB.ExecContext(r.Context(),
+		"UPDATE applications SET identity_secret = NULL WHERE id = $1",
+		appID,
+	)
+	if err != nil {
+		http.Error(w, `{"error":"Failed to remove identity secret"}`, http.StatusInternalServerError)
+		return
+	}
+
+	rows, _ := result.RowsAffected()
+	if rows == 0 {
+		http.Error(w, `{"error":"Application not found"}`, http.StatusNotFound)
+		return
+	}
+
+	recordAudit(r, "application.delete_identity_secret", "application", appID, snapshot, auditSnapshot(r, "applications", appID))
+
+	json.NewEncoder(w).Encode(map[string]string{"message": "Identity secret removed successfully"})
+}
+
+// GetEndUsers returns an application's verified end users with their feedback counts (application members)
+func GetEndUsers(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	vars := mux.Vars(r)
+	appID, err := uuid.Parse(vars["id"])
+	if err != nil {
+		http.Error(w, `{"error":"Invalid application ID"}`, http.StatusBadRequest)
+		return
+	}
+
+	// Parse query parameters
+	query := r.URL.Query()
+	search := query.Get("search")
+	page, _ := strconv.Atoi(query.Get("page"))
+	limit, _ := strconv.Atoi(query.Get("limit"))
+
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+	offset := (page - 1) * limit
+
+	// Build filters
+	where := " WHERE eu.application_id = $1"
+	args := []interface{}{appID}
+	argPos := 2
+
+	if search != "" {
+		where += " AND (eu.external_id = $" + strconv.Itoa(argPos) +
+			" OR eu.email ILIKE '%' || $" + strconv.Itoa(argPos) + " || '%'" +
+			" OR eu.name ILIKE '%' || $" + strconv.Itoa(argPos) + " || '%')"
+		args = append(args, search)
+		argPos++
+	}
+
+	var total int
+	database.DB.QueryRowContext(r.Context(), "SELECT COUNT(*) FROM end_users eu"+where, args...).Scan(&total)
+
+	queryStr := `
+		SELECT eu.id, eu.application_id, eu.external_id, COALESCE(eu.email, ''), COALESCE(eu.name, ''),
+			   eu.first_seen_at, eu.last_seen_at,
+			   (SELECT COUNT(*) FROM feedback f WHERE f.end_user_id = eu.id)
+		FROM end_users eu` + where +
+		" ORDER BY eu.last_seen_at DESC LIMIT $" + strconv.Itoa(argPos) + " OFFSET $" + strconv.Itoa(argPos+1)
+	args = append(args, limit, offset)
+
+	rows, err := database.DB.QueryContext(r.Context(), queryStr, args...)
+	if err != nil {
+		http.Error(w, `{"error":"Failed to fetch end users"}`, http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	endUsers := []models.EndUser{}
+	for rows.Next() {
+		var u models.EndUser
+		err := rows.Scan(
+			&u.ID, &u.ApplicationID, &u.ExternalID, &u.Email, &u.Name,
+			&u.FirstSeenAt, &u.LastSeenAt, &u.FeedbackCount,
+		)
+		if err != nil {
+			continue
+		}
+		endUsers = append(endUsers, u)
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"end_users": endUsers,
+		"total":     total,
+		"page":      page,
+		"limit":     limit,
+	})
+}
@@ -0,0 +1,369 @@
+package controllers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/frallan97/feedback-service/backend/database"
+	"github.com/frallan97/feedback-service/backend/middleware"
+	"github.com/frallan97/feedback-service/backend/models"
+	"github.com/frallan97/feedback-service/backend/services"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+// memberScope returns the user whose memberships limit what a request can list.
+// Admins aren't scoped.
+func memberScope(r *http.Request) (uuid.UUID, bool) {
+	if middleware.GetRole(r.Context()) == models.RoleAdmin {
+		return uuid.Nil, false
+	}
+	userID, ok := middleware.GetUserID(r.Context())
+	return userID, ok
+}
+
+// GetMembers returns an application's members and pending invitations (application members)
+func GetMembers(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	vars := mux.Vars(r)
+	appID := vars["id"]
+
+	rows, err := database.DB.QueryContext(r.Context(), `
+		SELECT m.application_id, m.user_id, u.email, u.name, m.role, m.added_by, m.created_at, m.updated_at
+		FROM application_members m
+		JOIN users u ON u.id = m.user_id
+		WHERE m.application_id = $1
+		ORDER BY u.email
+	`, appID)
+	if err != nil {
+		http.Error(w, `{"error":"Failed to fetch members"}`, http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	members := []models.ApplicationMember{}
+	for rows.Next() {
+		var m models.ApplicationMember
+		if err := rows.Scan(&m.ApplicationID, &m.UserID, &m.Email, &m.Name, &m.Role, &m.AddedBy, &m.CreatedAt, &m.UpdatedAt); err != nil {
+			continue
+		}
+		members = append(members, m)
+	}
+
+	invitationRows, err := database.DB.QueryContext(r.Context(), `
+		SELECT id, application_id, email, role, invited_by, created_at
+		FROM application_invitations
+		WHERE application_id = $1
+		ORDER BY created_at
+	`, appID)
+	if err != nil {
+		http.Error(w, `{"error":"Failed to fetch invitations"}`, http.StatusInternalServerError)
+		return
+	}
+	defer invitationRows.Close()
+
+	invitations := []models.ApplicationInvitation{}
+	for invitationRows.Next() {
+		var inv models.ApplicationInvitation
+		if err := invitationRows.Scan(&inv.ID, &inv.ApplicationID, &inv.Email, &inv.Role, &inv.InvitedBy, &inv.CreatedAt); err != nil {
+			continue
+		}
+		invitations = append(invitations, inv)
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"members":     members,
+		"invitations": invitations,
+	})
+}
+
+// InviteMember adds a user to an application by email (application owners and admins).
+// Users who have signed in before are added immediately; anyone else gets a pending
+// invitation that is accepted the first time they sign in.
+func InviteMember(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	vars := mux.Vars(r)
+	appID, err := uuid.Parse(vars["id"])
+	if err != nil {
+		http.Error(w, `{"error":"Invalid application ID"}`, http.StatusBadRequest)
+		return
+	}
+
+	var req struct {
+		Email string `json:"email"`
+		Role  string `json:"role"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, `{"error":"Invalid request body"}`, http.StatusBadRequest)
+		return
+	}
+
+	req.Email = strings.TrimSpace(req.Email)
+	if req.Email == "" || !strings.Contains(req.Email, "@") {
+		http.Error(w, `{"error":"A valid email is required"}`, http.StatusBadRequest)
+		return
+	}
+	if req.Role == "" {
+		req.Role = models.RoleViewer
+	}
+	if !models.IsValidMemberRole(req.Role) {
+		http.Error(w, `{"error":"Role must be one of owner, triager, viewer"}`, http.StatusBadRequest)
+		return
+	}
+
+	var inviterID interface{}
+	if id, ok := middleware.GetUserID(r.Context()); ok {
+		inviterID = id
+	}
+
+	var userID uuid.UUID
+	err = database.DB.QueryRowContext(r.Context(),
+		"SELECT id FROM users WHERE LOWER(email) = LOWER($1)",
+		req.Email,
+	).Scan(&userID)
+
+	if err != nil && err != sql.ErrNoRows {
+		http.Error(w, `{"error":"Failed to look up user"}`, http.StatusInternalServerError)
+		return
+	}
+
+	// Existing user: add the membership right away
+	if err == nil {
+		var m models.ApplicationMember
+		err = database.DB.QueryRowContext(r.Context(), `
+			INSERT INTO application_members (application_id, user_id, role, added_by)
+			VALUES ($1, $2, $3, $4)
+			ON CONFLICT (application_id, user_id) DO NOTHING
+			RETURNING application_id, user_id, role, added_by, created_at, updated_at
+		`, appID, userID, req.Role, inviterID).Scan(
+			&m.ApplicationID, &m.UserID, &m.Role, &m.AddedBy, &m.CreatedAt, &m.UpdatedAt,
+		)
+		if err == sql.ErrNoRows {
+			http.Error(w, `{"error":"User is already a member; update their role instead"}`, http.StatusConflict)
+			return
+		}
+		if err != nil {
+			http.Error(w, `{"error":"Failed to add member"}`, http.StatusInternalServerError)
+			return
+		}
+		m.Email = req.Email
+
+		recordAudit(r, "member.add", "member", userID, nil, memberSnapshot(r, appID, userID))
+
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"member":  m,
+			"message": "Member added successfully",
+		})
+		return
+	}
+
+	// Unknown user: keep a pending invitation for when they first sign in
+	var inv models.ApplicationInvitation
+	err = database.DB.QueryRowContext(r.Context(), `
+		INSERT INTO application_invitations (application_id, email, role, invited_by)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (application_id, LOWER(email)) DO UPDATE
+		SET role = EXCLUDED.role, invited_by = EXCLUDED.invited_by
+		RETURNING id, application_id, email, role, invited_by, created_at
+	`, appID, req.Email, req.Role, inviterID).Scan(
+		&inv.ID, &inv.ApplicationID, &inv.Email, &inv.Role, &inv.InvitedBy, &inv.CreatedAt,
+	)
+	if err != nil {
+		http.Error(w, `{"error":"Failed to create invitation"}`, http.StatusInternalServerError)
+		return
+	}
+
+	recordAudit(r, "invitation.create", "invitation", inv.ID, nil, auditSnapshot(r, "application_invitations", inv.ID))
+
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"invitation": inv,
+		"message":    "Invitation created; it will be accepted when the user first signs in",
+	})
+}
+
+// UpdateMember changes a member's role in an application (application owners and admins)
+func UpdateMember(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	vars := mux.Vars(r)
+	appID := vars["id"]
+	userID := vars["user_id"]
+
+	var req struct {
+		Role string `json:"role"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, `{"error":"Invalid request body"}`, http.StatusBadRequest)
+		return
+	}
+	if !models.IsValidMemberRole(req.Role) {
+		http.Error(w, `{"error":"Role must be one of owner, triager, viewer"}`, http.StatusBadRequest)
+		return
+	}
+
+	snapshot := memberSnapshot(r, appID, userID)
+
+	tx, err := database.DB.BeginTx(r.Context(), nil)
+	if err != nil {
+		http.Error(w, `{"error":"Failed to update member"}`, http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	if req.Role != models.RoleOwner {
+		if status, msg := checkNotLastOwner(r, tx, appID, userID); status != 0 {
+			http.Error(w, msg, status)
+			return
+		}
+	}
+
+	result, err := tx.ExecContext(r.Context(),
+		"UPDATE application_members SET role = $1 WHERE application_id = $2 AND user_id = $3",
+		req.Role, appID, userID,
+	)
+	if err != nil {
+		http.Error(w, `{"error":"Failed to update member"}`, http.StatusInternalServerError)
+		return
+	}
+
+	rowsAffected, _ := result.RowsAffected()
+	if rowsAffected == 0 {
+		http.Error(w, `{"error":"Member not found"}`, http.StatusNotFound)
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		http.Error(w, `{"error":"Failed to update member"}`, http.StatusInternalServerError)
+		return
+	}
+
+	recordAudit(r, "member.update", "member", userID, snapshot, memberSnapshot(r, appID, userID))
+
+	json.NewEncoder(w).Encode(map[string]string{"message": "Member updated successfully"})
+}
+
+// RemoveMember removes a user from an application (application owners and admins)
+func RemoveMember(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	vars := mux.Vars(r)
+	appID := vars["id"]
+	userID := vars["user_id"]
+
+	snapshot := memberSnapshot(r, appID, userID)
+
+	tx, err := database.DB.BeginTx(r.Context(), nil)
+	if err != nil {
+		http.Error(w, `{"error":"Failed to remove member"}`, http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	if status, msg := checkNotLastOwner(r, tx, appID, userID); status != 0 {
+		http.Error(w, msg, status)
+		return
+	}
+
+	result, err := tx.ExecContext(r.Context(),
+		"DELETE FROM application_members WHERE application_id = $1 AND user_id = $2",
+		appID, userID,
+	)
+	if err != nil {
+		http.Error(w, `{"error":"Failed to remove member"}`, http.StatusInternalServerError)
+		return
+	}
+
+	rowsAffected, _ := result.RowsAffected()
+	if rowsAffected == 0 {
+		http.Error(w, `{"error":"Member not found"}`, http.StatusNotFound)
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		http.Error(w, `{"error":"Failed to remove member"}`, http.StatusInternalServerError)
+		return
+	}
+
+	recordAudit(r, "member.remove", "member", userID, snapshot, nil)
+
+	json.NewEncoder(w).Encode(map[string]string{"message": "Member removed successfully"})
+}
+
+// RevokeInvitation deletes a pending invitation (application owners and admins)
+func RevokeInvitation(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	vars := mux.Vars(r)
+	appID := vars["id"]
+	invitationID := vars["invitation_id"]
+
+	snapshot := auditSnapshot(r, "application_invitations", invitationID)
+
+	result, err := database.DB.ExecContext(r.Context(),
+		"DELETE FROM application_invitations WHERE id = $1 AND application_id = $2",
+		invitationID, appID,
+	)
+	if err != nil {
+		http.Error(w, `{"error":"Failed to revoke invitation"}`, http.StatusInternalServerError)
+		return
+	}
+
+	rowsAffected, _ := result.RowsAffected()
+	if rowsAffected == 0 {
+		http.Error(w, `{"error":"Invitation not found"}`, http.StatusNotFound)
+		return
+	}
+
+	recordAudit(r, "invitation.revoke", "invitation", invitationID, snapshot, nil)
+
+	json.NewEncoder(w).Encode(map[string]string{"message": "Invitation revoked successfully"})
+}
+
+// memberSnapshot returns a membership for an audit event's before or after state
+func memberSnapshot(r *http.Request, appID, userID interface{}) json.RawMessage {
+	return services.SnapshotRow(r.Context(), "application_members", "application_id = $1 AND user_id = $2", appID, userID)
+}
+
+// checkNotLastOwner rejects changes that would leave an application without an owner.
+// It locks the application's owners in tx, so concurrent changes to them wait for it to
+// finish, and must run in the transaction that makes the change. It returns a zero
+// status if the change is allowed.
+func checkNotLastOwner(r *http.Request, tx *sql.Tx, appID, userID string) (int, string) {
+	memberID, err := uuid.Parse(userID)
+	if err != nil {
+		return http.StatusNotFound, `{"error":"Member not found"}`
+	}
+
+	rows, err := tx.QueryContext(r.Context(), `
+		SELECT user_id FROM application_members
+		WHERE application_id = $1 AND role = 'owner'
+		FOR UPDATE
+	`, appID)
+	if err != nil {
+		return http.StatusInternalServerError, `{"error":"Failed to fetch member"}`
+	}
+	defer rows.Close()
+
+	owners := 0
+	isOwner := false
+	for rows.Next() {
+		var ownerID uuid.UUID
+		if err := rows.Scan(&ownerID); err != nil {
+			return http.StatusInternalServerError, `{"error":"Failed to fetch member"}`
+		}
+		owners++
+		isOwner = isOwner || ownerID == memberID
+	}
+	if err := rows.Err(); err != nil {
+		return http.StatusInternalServerError, `{"error":"Failed to fetch member"}`
+	}
+
+	if isOwner && owners <= 1 {
+		return http.StatusConflict, `{"error":"An application must keep at least one owner"}`
+	}
+	return 0, ""
+}
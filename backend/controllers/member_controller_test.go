@@ -0,0 +1,65 @@
+package controllers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+func removeMemberRequest(appID, userID uuid.UUID) *http.Request {
+	req := httptest.NewRequest(http.MethodDelete, "/api/v1/applications/"+appID.String()+"/members/"+userID.String(), nil)
+	return mux.SetURLVars(req, map[string]string{"id": appID.String(), "user_id": userID.String()})
+}
+
+func TestRemoveMemberKeepsLastOwner(t *testing.T) {
+	mock := mockDatabase(t)
+	appID, ownerID := uuid.New(), uuid.New()
+
+	mock.ExpectQuery(`FROM application_members t`).WillReturnRows(sqlmock.NewRows([]string{"snapshot"}).AddRow(`{}`))
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT user_id FROM application_members\s+WHERE application_id = \$1 AND role = 'owner'\s+FOR UPDATE`).
+		WithArgs(appID.String()).
+		WillReturnRows(sqlmock.NewRows([]string{"user_id"}).AddRow(ownerID.String()))
+	mock.ExpectRollback()
+
+	rec := httptest.NewRecorder()
+	RemoveMember(rec, removeMemberRequest(appID, ownerID))
+
+	if rec.Code != http.StatusConflict {
+		t.Fatalf("status = %d, want %d: %s", rec.Code, http.StatusConflict, rec.Body.String())
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestRemoveMemberWithAnotherOwner(t *testing.T) {
+	mock := mockDatabase(t)
+	appID, ownerID, otherOwnerID := uuid.New(), uuid.New(), uuid.New()
+
+	// The owners stay locked until the delete commits
+	mock.ExpectQuery(`FROM application_members t`).WillReturnRows(sqlmock.NewRows([]string{"snapshot"}).AddRow(`{}`))
+	mock.ExpectBegin()
+	mock.ExpectQuery(`FOR UPDATE`).
+		WithArgs(appID.String()).
+		WillReturnRows(sqlmock.NewRows([]string{"user_id"}).AddRow(ownerID.String()).AddRow(otherOwnerID.String()))
+	mock.ExpectExec(`DELETE FROM application_members WHERE application_id = \$1 AND user_id = \$2`).
+		WithArgs(appID.String(), ownerID.String()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	mock.ExpectExec(`INSERT INTO audit_events`).WillReturnResult(sqlmock.NewResult(0, 1))
+
+	rec := httptest.NewRecorder()
+	RemoveMember(rec, removeMemberRequest(appID, ownerID))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d: %s", rec.Code, http.StatusOK, rec.Body.String())
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,236 @@
+package controllers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/frallan97/feedback-service/backend/database"
+	"github.com/frallan97/feedback-service/backend/models"
+	"github.com/frallan97/feedback-service/backend/services"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/lib/pq"
+)
+
+// spamVerdict is a submission's spam score and whether it's held for moderation
+type spamVerdict struct {
+	services.SpamScore
+	held bool
+}
+
+// scoreSubmission scores a submission against the application's spam settings. It returns
+// nil if the application doesn't filter spam.
+func scoreSubmission(ctx context.Context, appID uuid.UUID, sub services.SpamSubmission) (*spamVerdict, error) {
+	settings, err := services.GetSpamSettings(ctx, appID)
+	if err != nil {
+		return nil, err
+	}
+	if !settings.Enabled {
+		return nil, nil
+	}
+
+	score, err := services.ScoreSpam(ctx, appID, sub)
+	if err != nil {
+		return nil, err
+	}
+	return &spamVerdict{SpamScore: score, held: score.Score >= settings.Threshold}, nil
+}
+
+// GetModerationQueue returns an application's held feedback, oldest first, with the spam
+// score and reasons it was held for (application triagers and above)
+func GetModerationQueue(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	vars := mux.Vars(r)
+	appID := vars["id"]
+
+	query := r.URL.Query()
+	page, _ := strconv.Atoi(query.Get("page"))
+	limit, _ := strconv.Atoi(query.Get("limit"))
+
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+	offset := (page - 1) * limit
+
+	rows, err := database.DB.QueryContext(r.Context(), `
+		SELECT feedback.id, feedback.application_id, user_id, category_id, title, content, rating,
+			   status, priority, page_url, browser_info, app_version, metadata,
+			   contact_email, created_at, updated_at, spam_score, spam_reasons,
+			   `+reporterColumns+`
+		FROM feedback
+		LEFT JOIN end_users eu ON eu.id = feedback.end_user_id
+		WHERE feedback.application_id = $1 AND status = $2
+		ORDER BY feedback.created_at
+		LIMIT $3 OFFSET $4
+	`, appID, models.FeedbackStatusHeld, limit, offset)
+	if err != nil {
+		http.Error(w, `{"error":"Failed to fetch moderation queue"}`, http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	feedbacks := []models.Feedback{}
+	for rows.Next() {
+		var f models.Feedback
+		var browserInfoJSON, metadataJSON []byte
+		var reporter reporterRow
+
+		dest := []interface{}{
+			&f.ID, &f.ApplicationID, &f.UserID, &f.CategoryID, &f.Title, &f.Content, &f.Rating,
+			&f.Status, &f.Priority, &f.PageURL, &browserInfoJSON, &f.AppVersion, &metadataJSON,
+			&f.ContactEmail, &f.CreatedAt, &f.UpdatedAt, &f.SpamScore, pq.Array(&f.SpamReasons),
+		}
+		if err := rows.Scan(append(dest, reporter.dest()...)...); err != nil {
+			continue
+		}
+		f.Reporter = reporter.endUser(f.ApplicationID)
+
+		if browserInfoJSON != nil {
+			json.Unmarshal(browserInfoJSON, &f.BrowserInfo)
+		}
+		if metadataJSON != nil {
+			json.Unmarshal(metadataJSON, &f.Metadata)
+		}
+
+		feedbacks = append(feedbacks, f)
+	}
+
+	var total int
+	database.DB.QueryRowContext(r.Context(),
+		"SELECT COUNT(*) FROM feedback WHERE application_id = $1 AND status = $2",
+		appID, models.FeedbackStatusHeld,
+	).Scan(&total)
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"feedback": feedbacks,
+		"total":    total,
+		"page":     page,
+		"limit":    limit,
+	})
+}
+
+// errNotModeratable is returned when feedback can't take a moderation decision, e.g.
+// approving feedback that was never held
+var errNotModeratable = errors.New("feedback cannot be moderated this way")
+
+// moderateFeedback moves feedback to status and trains its application's spam classifier
+// with label.
+// It returns the feedback's previous status.
+func moderateFeedback(ctx context.Context, feedbackID uuid.UUID, status, label string) (string, error) {
+	tx, err := database.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return "", err
+	}
+	defer tx.Rollback()
+
+	var appID uuid.UUID
+	var text, previous string
+	var trained sql.NullString
+	err = tx.QueryRowContext(ctx, `
+		SELECT application_id, COALESCE(title, '') || ' ' || content, status, spam_trained
+		FROM feedback WHERE id = $1
+		FOR UPDATE
+	`, feedbackID).Scan(&appID, &text, &previous, &trained)
+	if err != nil {
+		return "", err
+	}
+
+	// Only held or rejected feedback can be approved; anything not yet rejected can be
+	if previous == status ||
+		(status == models.FeedbackStatusNew && previous != models.FeedbackStatusHeld && previous != models.FeedbackStatusRejected) {
+		return previous, errNotModeratable
+	}
+
+	if _, err := tx.ExecContext(ctx,
+		"UPDATE feedback SET status = $1, spam_trained = $2 WHERE id = $3",
+		status, label, feedbackID,
+	); err != nil {
+		return "", err
+	}
+
+	if err := services.TrainSpamClassifier(ctx, tx, appID, text, label, trained.String); err != nil {
+		return "", err
+	}
+
+	return previous, tx.Commit()
+}
+
+// ApproveFeedback releases held (or rejected) feedback as new and trains the spam filter
+// that it's legitimate (application triagers and above)
+func ApproveFeedback(w http.ResponseWriter, r *http.Request) {
+	moderationDecision(w, r, models.FeedbackStatusNew, services.SpamLabelHam)
+}
+
+// RejectFeedback marks feedback as spam, hiding it from feedback lists, and trains the spam
+// filter with it (application triagers and above)
+func RejectFeedback(w http.ResponseWriter, r *http.Request) {
+	moderationDecision(w, r, models.FeedbackStatusRejected, services.SpamLabelSpam)
+}
+
+func moderationDecision(w http.ResponseWriter, r *http.Request, status, label string) {
+	w.Header().Set("Content-Type", "application/json")
+
+	vars := mux.Vars(r)
+	feedbackID, err := uuid.Parse(vars["id"])
+	if err != nil {
+		http.Error(w, `{"error":"Feedback not found"}`, http.StatusNotFound)
+		return
+	}
+
+	action := "feedback.approve"
+	if status == models.FeedbackStatusRejected {
+		action = "feedback.reject"
+	}
+
+	snapshot := auditSnapshot(r, "feedback", feedbackID)
+
+	previous, err := moderateFeedback(r.Context(), feedbackID, status, label)
+	if err == sql.ErrNoRows {
+		http.Error(w, `{"error":"Feedback not found"}`, http.StatusNotFound)
+		return
+	}
+	if err == errNotModeratable {
+		if status == models.FeedbackStatusRejected {
+			http.Error(w, `{"error":"Feedback is already rejected"}`, http.StatusConflict)
+		} else {
+			http.Error(w, `{"error":"Only held or rejected feedback can be approved"}`, http.StatusConflict)
+		}
+		return
+	}
+	if err != nil {
+		log.Printf("[SPAM] Failed to moderate feedback %s: %v", feedbackID, err)
+		http.Error(w, `{"error":"Failed to moderate feedback"}`, http.StatusInternalServerError)
+		return
+	}
+
+	recordAudit(r, action, "feedback", feedbackID, snapshot, auditSnapshot(r, "feedback", feedbackID))
+
+	f, err := loadFeedback(r.Context(), feedbackID)
+	if err != nil {
+		http.Error(w, `{"error":"Failed to fetch feedback"}`, http.StatusInternalServerError)
+		return
+	}
+
+	// Held feedback was never announced, so approving it is its creation; other changes
+	// are announced like any status change
+	switch {
+	case previous == models.FeedbackStatusHeld && status == models.FeedbackStatusNew:
+		services.EmitWebhookEvent(r.Context(), f.ApplicationID, services.EventFeedbackCreated, f)
+	case previous != models.FeedbackStatusHeld:
+		services.EmitWebhookEvent(r.Context(), f.ApplicationID, services.EventFeedbackStatusChanged, map[string]interface{}{
+			"feedback":        f,
+			"previous_status": previous,
+		})
+	}
+
+	json.NewEncoder(w).Encode(f)
+}
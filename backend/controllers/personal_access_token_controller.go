@@ -0,0 +1,138 @@
+package controllers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/frallan97/feedback-service/backend/middleware"
+	"github.com/frallan97/feedback-service/backend/models"
+	"github.com/frallan97/feedback-service/backend/services"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+// GetPersonalAccessTokens returns the current user's personal access tokens, including revoked and expired ones
+func GetPersonalAccessTokens(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	claims, ok := middleware.GetUserClaims(r.Context())
+	if !ok {
+		http.Error(w, `{"error":"User not found in context"}`, http.StatusUnauthorized)
+		return
+	}
+
+	tokens, err := services.ListPersonalAccessTokens(r.Context(), claims.UserID)
+	if err != nil {
+		log.Printf("[AUTH] %v", err)
+		http.Error(w, `{"error":"Failed to fetch tokens"}`, http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(tokens)
+}
+
+// CreatePersonalAccessToken issues a personal access token for the current user. The token
+// acts with the user's permissions, limited to its scopes. The response is the only time
+// the full token is shown.
+func CreatePersonalAccessToken(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	claims, ok := middleware.GetUserClaims(r.Context())
+	if !ok {
+		http.Error(w, `{"error":"User not found in context"}`, http.StatusUnauthorized)
+		return
+	}
+
+	// A leaked token must not be able to mint longer-lived or broader ones
+	if claims.TokenID != uuid.Nil {
+		http.Error(w, `{"error":"Personal access tokens can't create tokens; sign in to the dashboard"}`, http.StatusForbidden)
+		return
+	}
+
+	var req struct {
+		Name      string     `json:"name"`
+		Scopes    []string   `json:"scopes"`
+		ExpiresAt *time.Time `json:"expires_at"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, `{"error":"Invalid request body"}`, http.StatusBadRequest)
+		return
+	}
+
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" || len(req.Name) > 100 {
+		http.Error(w, `{"error":"Name is required (at most 100 characters)"}`, http.StatusBadRequest)
+		return
+	}
+	if len(req.Scopes) == 0 {
+		http.Error(w, `{"error":"At least one scope is required"}`, http.StatusBadRequest)
+		return
+	}
+	for _, scope := range req.Scopes {
+		if !models.IsValidTokenScope(scope) {
+			http.Error(w, `{"error":"Unknown scope `+scope+`; scopes are <feedback|applications|users|authz>:<read|write> and audit:read"}`, http.StatusBadRequest)
+			return
+		}
+	}
+
+	now := time.Now()
+	expiresAt := now.Add(models.DefaultTokenLifetime)
+	if req.ExpiresAt != nil {
+		expiresAt = *req.ExpiresAt
+	}
+	if !expiresAt.After(now) || expiresAt.Sub(now) > models.MaxTokenLifetime {
+		http.Error(w, `{"error":"expires_at must be in the future and at most 365 days away"}`, http.StatusBadRequest)
+		return
+	}
+
+	t, err := services.CreatePersonalAccessToken(r.Context(), claims.UserID, req.Name, req.Scopes, expiresAt)
+	if err != nil {
+		log.Printf("[AUTH] %v", err)
+		http.Error(w, `{"error":"Failed to create token"}`, http.StatusInternalServerError)
+		return
+	}
+
+	recordAudit(r, "personal_access_token.create", "personal_access_token", t.ID, nil, auditSnapshot(r, "personal_access_tokens", t.ID))
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(t)
+}
+
+// RevokePersonalAccessToken revokes one of the current user's personal access tokens
+func RevokePersonalAccessToken(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	claims, ok := middleware.GetUserClaims(r.Context())
+	if !ok {
+		http.Error(w, `{"error":"User not found in context"}`, http.StatusUnauthorized)
+		return
+	}
+
+	tokenID, err := uuid.Parse(mux.Vars(r)["token_id"])
+	if err != nil {
+		http.Error(w, `{"error":"Token not found or already revoked"}`, http.StatusNotFound)
+		return
+	}
+
+	snapshot := auditSnapshot(r, "personal_access_tokens", tokenID)
+
+	err = services.RevokePersonalAccessToken(r.Context(), claims.UserID, tokenID)
+	if errors.Is(err, sql.ErrNoRows) {
+		http.Error(w, `{"error":"Token not found or already revoked"}`, http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("[AUTH] %v", err)
+		http.Error(w, `{"error":"Failed to revoke token"}`, http.StatusInternalServerError)
+		return
+	}
+
+	recordAudit(r, "personal_access_token.revoke", "personal_access_token", tokenID, snapshot, auditSnapshot(r, "personal_access_tokens", tokenID))
+
+	json.NewEncoder(w).Encode(map[string]string{"message": "Token revoked successfully"})
+}
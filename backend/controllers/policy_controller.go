@@ -0,0 +1,344 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/casbin/casbin/v2"
+	"github.com/frallan97/feedback-service/backend/database"
+	"github.com/frallan97/feedback-service/backend/models"
+	"github.com/lib/pq"
+)
+
+// Policy is a Casbin permission rule: subject may perform action (an anchored regex of
+// HTTP methods) on object (a keyMatch2 path pattern)
+type Policy struct {
+	Subject string `json:"subject"`
+	Object  string `json:"object"`
+	Action  string `json:"action"`
+}
+
+// RoleAssignment is a Casbin grouping rule: user (a user ID or role) inherits role
+type RoleAssignment struct {
+	User string `json:"user"`
+	Role string `json:"role"`
+}
+
+// target identifies a policy in the audit log
+func (p Policy) target() string {
+	return p.Subject + " " + p.Object + " " + p.Action
+}
+
+// target identifies a role assignment in the audit log
+func (a RoleAssignment) target() string {
+	return a.User + " " + a.Role
+}
+
+// builtInRoleLinks is the role hierarchy the authorization model relies on:
+// admin > owner > triager > viewer
+var builtInRoleLinks = []RoleAssignment{
+	{User: models.RoleAdmin, Role: models.RoleOwner},
+	{User: models.RoleOwner, Role: models.RoleTriager},
+	{User: models.RoleTriager, Role: models.RoleViewer},
+}
+
+// builtIn reports whether a is a link in the built-in role hierarchy
+func (a RoleAssignment) builtIn() bool {
+	for _, link := range builtInRoleLinks {
+		if a == link {
+			return true
+		}
+	}
+	return false
+}
+
+// policyAPIPath is a path under the policy API, used to check who can manage policies
+const policyAPIPath = "/api/v1/authz/policies"
+
+// grantsPolicyAccess reports whether role, directly or by inheritance, can use the policy API
+func grantsPolicyAccess(enforcer *casbin.SyncedEnforcer, role string) bool {
+	allowed, err := enforcer.Enforce("", role, policyAPIPath, http.MethodGet)
+	return err == nil && allowed
+}
+
+// otherPolicyAccess reports whether someone other than through role assignment a can use
+// the policy API: an active user whose global role grants it, or another role assignment
+// that does
+func otherPolicyAccess(ctx context.Context, enforcer *casbin.SyncedEnforcer, a RoleAssignment) (bool, error) {
+	for _, rule := range enforcer.GetGroupingPolicy() {
+		if len(rule) < 2 || (rule[0] == a.User && rule[1] == a.Role) || rule[1] == a.User {
+			continue
+		}
+		if grantsPolicyAccess(enforcer, rule[1]) {
+			return true, nil
+		}
+	}
+
+	roles := []string{}
+	for _, role := range []string{models.RoleViewer, models.RoleTriager, models.RoleAdmin} {
+		if role != a.User && grantsPolicyAccess(enforcer, role) {
+			roles = append(roles, role)
+		}
+	}
+	var exists bool
+	err := database.DB.QueryRowContext(ctx,
+		"SELECT EXISTS(SELECT 1 FROM users WHERE role = ANY($1) AND is_active)",
+		pq.Array(roles),
+	).Scan(&exists)
+	return exists, err
+}
+
+// validatePolicy checks a policy is well-formed before it reaches the enforcer
+func validatePolicy(p *Policy) string {
+	p.Subject = strings.TrimSpace(p.Subject)
+	p.Object = strings.TrimSpace(p.Object)
+	p.Action = strings.TrimSpace(p.Action)
+
+	if p.Subject == "" || p.Object == "" || p.Action == "" {
+		return `{"error":"Subject, object and action are required"}`
+	}
+	if !strings.HasPrefix(p.Object, "/api/v1/") {
+		return `{"error":"Object must be a path under /api/v1/"}`
+	}
+	if _, err := regexp.Compile("^(" + p.Action + ")$"); err != nil {
+		return `{"error":"Action must be a valid regular expression, e.g. (GET)|(POST)"}`
+	}
+	return ""
+}
+
+// validateRoleAssignment checks a role assignment targets one of the known roles
+func validateRoleAssignment(a *RoleAssignment) string {
+	a.User = strings.TrimSpace(a.User)
+	a.Role = strings.TrimSpace(a.Role)
+
+	if a.User == "" || a.Role == "" {
+		return `{"error":"User and role are required"}`
+	}
+	switch a.Role {
+	case models.RoleViewer, models.RoleTriager, models.RoleOwner, models.RoleAdmin:
+		return ""
+	}
+	return `{"error":"Role must be one of viewer, triager, owner, admin"}`
+}
+
+// GetPolicies returns every permission rule (admin only)
+func GetPolicies(enforcer *casbin.SyncedEnforcer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		subject := r.URL.Query().Get("subject")
+
+		policies := []Policy{}
+		for _, rule := range enforcer.GetPolicy() {
+			if len(rule) < 3 || (subject != "" && rule[0] != subject) {
+				continue
+			}
+			policies = append(policies, Policy{Subject: rule[0], Object: rule[1], Action: rule[2]})
+		}
+
+		json.NewEncoder(w).Encode(policies)
+	}
+}
+
+// CreatePolicy adds a permission rule, saving it to the database (admin only)
+func CreatePolicy(enforcer *casbin.SyncedEnforcer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		var p Policy
+		if err := json.NewDecoder(r.Body).Decode(&p); err != nil {
+			http.Error(w, `{"error":"Invalid request body"}`, http.StatusBadRequest)
+			return
+		}
+		if msg := validatePolicy(&p); msg != "" {
+			http.Error(w, msg, http.StatusBadRequest)
+			return
+		}
+
+		added, err := enforcer.AddPolicy(p.Subject, p.Object, p.Action)
+		if err != nil {
+			http.Error(w, `{"error":"Failed to add policy"}`, http.StatusInternalServerError)
+			return
+		}
+		if !added {
+			http.Error(w, `{"error":"Policy already exists"}`, http.StatusConflict)
+			return
+		}
+
+		recordAudit(r, "policy.create", "policy", p.target(), nil, auditJSON(p))
+
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(p)
+	}
+}
+
+// DeletePolicy removes the permission rule given by the subject, object and action
+// query parameters (admin only)
+func DeletePolicy(enforcer *casbin.SyncedEnforcer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		query := r.URL.Query()
+		p := Policy{Subject: query.Get("subject"), Object: query.Get("object"), Action: query.Get("action")}
+		if p.Subject == "" || p.Object == "" || p.Action == "" {
+			http.Error(w, `{"error":"Subject, object and action are required"}`, http.StatusBadRequest)
+			return
+		}
+		// Removing this would lock every admin out of the policy API
+		if p.Subject == models.RoleAdmin && p.Object == "/api/v1/authz/*" {
+			http.Error(w, `{"error":"The admin policy management rule can't be removed"}`, http.StatusConflict)
+			return
+		}
+
+		removed, err := enforcer.RemovePolicy(p.Subject, p.Object, p.Action)
+		if err != nil {
+			http.Error(w, `{"error":"Failed to remove policy"}`, http.StatusInternalServerError)
+			return
+		}
+		if !removed {
+			http.Error(w, `{"error":"Policy not found"}`, http.StatusNotFound)
+			return
+		}
+
+		recordAudit(r, "policy.delete", "policy", p.target(), auditJSON(p), nil)
+
+		json.NewEncoder(w).Encode(map[string]string{"message": "Policy removed successfully"})
+	}
+}
+
+// GetRoleAssignments returns every role assignment and role inheritance rule (admin only)
+func GetRoleAssignments(enforcer *casbin.SyncedEnforcer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		assignments := []RoleAssignment{}
+		for _, rule := range enforcer.GetGroupingPolicy() {
+			if len(rule) < 2 {
+				continue
+			}
+			assignments = append(assignments, RoleAssignment{User: rule[0], Role: rule[1]})
+		}
+
+		json.NewEncoder(w).Encode(assignments)
+	}
+}
+
+// CreateRoleAssignment grants a user (or role) a role, saving it to the database (admin only)
+func CreateRoleAssignment(enforcer *casbin.SyncedEnforcer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		var a RoleAssignment
+		if err := json.NewDecoder(r.Body).Decode(&a); err != nil {
+			http.Error(w, `{"error":"Invalid request body"}`, http.StatusBadRequest)
+			return
+		}
+		if msg := validateRoleAssignment(&a); msg != "" {
+			http.Error(w, msg, http.StatusBadRequest)
+			return
+		}
+
+		added, err := enforcer.AddGroupingPolicy(a.User, a.Role)
+		if err != nil {
+			http.Error(w, `{"error":"Failed to add role assignment"}`, http.StatusInternalServerError)
+			return
+		}
+		if !added {
+			http.Error(w, `{"error":"Role assignment already exists"}`, http.StatusConflict)
+			return
+		}
+
+		recordAudit(r, "role_assignment.create", "role_assignment", a.target(), nil, auditJSON(a))
+
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(a)
+	}
+}
+
+// DeleteRoleAssignment removes the role assignment given by the user and role query
+// parameters (admin only)
+func DeleteRoleAssignment(enforcer *casbin.SyncedEnforcer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		query := r.URL.Query()
+		a := RoleAssignment{User: query.Get("user"), Role: query.Get("role")}
+		if a.User == "" || a.Role == "" {
+			http.Error(w, `{"error":"User and role are required"}`, http.StatusBadRequest)
+			return
+		}
+
+		if a.builtIn() {
+			http.Error(w, `{"error":"The built-in role hierarchy can't be changed"}`, http.StatusConflict)
+			return
+		}
+		if grantsPolicyAccess(enforcer, a.Role) {
+			others, err := otherPolicyAccess(r.Context(), enforcer, a)
+			if err != nil {
+				http.Error(w, `{"error":"Failed to remove role assignment"}`, http.StatusInternalServerError)
+				return
+			}
+			if !others {
+				http.Error(w, `{"error":"Removing this would leave nobody able to manage policies"}`, http.StatusConflict)
+				return
+			}
+		}
+
+		removed, err := enforcer.RemoveGroupingPolicy(a.User, a.Role)
+		if err != nil {
+			http.Error(w, `{"error":"Failed to remove role assignment"}`, http.StatusInternalServerError)
+			return
+		}
+		if !removed {
+			http.Error(w, `{"error":"Role assignment not found"}`, http.StatusNotFound)
+			return
+		}
+
+		recordAudit(r, "role_assignment.delete", "role_assignment", a.target(), auditJSON(a), nil)
+
+		json.NewEncoder(w).Encode(map[string]string{"message": "Role assignment removed successfully"})
+	}
+}
+
+// CheckPermission is a dry run of the policy check for a subject, role, method and path,
+// returning the rule that allowed it (admin only). Application membership, which
+// Authorize checks before the policy, is not evaluated.
+func CheckPermission(enforcer *casbin.SyncedEnforcer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		var req struct {
+			Subject string `json:"subject"`
+			Role    string `json:"role"`
+			Method  string `json:"method"`
+			Path    string `json:"path"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, `{"error":"Invalid request body"}`, http.StatusBadRequest)
+			return
+		}
+		if (req.Subject == "" && req.Role == "") || req.Method == "" || req.Path == "" {
+			http.Error(w, `{"error":"Subject or role, method and path are required"}`, http.StatusBadRequest)
+			return
+		}
+		req.Method = strings.ToUpper(req.Method)
+
+		allowed, explain, err := enforcer.EnforceEx(req.Subject, req.Role, req.Path, req.Method)
+		if err != nil {
+			http.Error(w, `{"error":"Failed to evaluate policy"}`, http.StatusInternalServerError)
+			return
+		}
+
+		response := map[string]interface{}{
+			"allowed": allowed,
+		}
+		if allowed && len(explain) >= 3 {
+			response["matched_policy"] = Policy{Subject: explain[0], Object: explain[1], Action: explain[2]}
+		}
+
+		json.NewEncoder(w).Encode(response)
+	}
+}
@@ -0,0 +1,118 @@
+package controllers
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+
+	"github.com/frallan97/feedback-service/backend/database"
+	"github.com/gorilla/mux"
+)
+
+// generateSigningSecret creates a random secret an application's servers sign requests with
+func generateSigningSecret() (string, error) {
+	b := make([]byte, 32)
+	_, err := rand.Read(b)
+	if err != nil {
+		return "", err
+	}
+	return "sigsec_" + hex.EncodeToString(b), nil
+}
+
+// GetSigningSecret returns an application's request signing secret (application owners and admins)
+func GetSigningSecret(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	vars := mux.Vars(r)
+	appID := vars["id"]
+
+	var secret sql.NullString
+	err := database.DB.QueryRowContext(r.Context(),
+		"SELECT signing_secret FROM applications WHERE id = $1",
+		appID,
+	).Scan(&secret)
+	if err == sql.ErrNoRows {
+		http.Error(w, `{"error":"Application not found"}`, http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, `{"error":"Failed to fetch signing secret"}`, http.StatusInternalServerError)
+		return
+	}
+	if !secret.Valid {
+		http.Error(w, `{"error":"Request signing is not enabled for this application"}`, http.StatusNotFound)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]string{"secret": secret.String})
+}
+
+// RotateSigningSecret enables request signing with a new secret (application owners and admins).
+// Requests signed with the old secret are rejected immediately.
+func RotateSigningSecret(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	vars := mux.Vars(r)
+	appID := vars["id"]
+
+	secret, err := generateSigningSecret()
+	if err != nil {
+		http.Error(w, `{"error":"Failed to generate signing secret"}`, http.StatusInternalServerError)
+		return
+	}
+
+	snapshot := auditSnapshot(r, "applications", appID)
+
+	result, err := database.DB.ExecContext(r.Context(),
+		"UPDATE applications SET signing_secret = $1 WHERE id = $2",
+		secret, appID,
+	)
+	if err != nil {
+		http.Error(w, `{"error":"Failed to update signing secret"}`, http.StatusInternalServerError)
+		return
+	}
+
+	rows, _ := result.RowsAffected()
+	if rows == 0 {
+		http.Error(w, `{"error":"Application not found"}`, http.StatusNotFound)
+		return
+	}
+
+	recordAudit(r, "application.rotate_signing_secret", "application", appID, snapshot, auditSnapshot(r, "applications", appID))
+
+	json.NewEncoder(w).Encode(map[string]string{
+		"secret":  secret,
+		"message": "Signing secret rotated successfully",
+	})
+}
+
+// DeleteSigningSecret disables request signing for an application (application owners and admins)
+func DeleteSigningSecret(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	vars := mux.Vars(r)
+	appID := vars["id"]
+
+	snapshot := auditSnapshot(r, "applications", appID)
+
+	result, err := database.DB.ExecContext(r.Context(),
+		"UPDATE applications SET signing_secret = NULL WHERE id = $1",
+		appID,
+	)
+	if err != nil {
+		http.Error(w, `{"error":"Failed to remove signing secret"}`, http.StatusInternalServerError)
+		return
+	}
+
+	rows, _ := result.RowsAffected()
+	if rows == 0 {
+		http.Error(w, `{"error":"Application not found"}`, http.StatusNotFound)
+		return
+	}
+
+	recordAudit(r, "application.delete_signing_secret", "application", appID, snapshot, auditSnapshot(r, "applications", appID))
+
+	json.NewEncoder(w).Encode(map[string]string{"message": "Request signing disabled"})
+}
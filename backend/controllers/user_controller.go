@@ -0,0 +1,186 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/frallan97/feedback-service/backend/models"
+	"github.com/frallan97/feedback-service/backend/services"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+// GetUsers returns users with their role, status and last activity (admins).
+// Filter with ?search= (email or name), ?role= and ?is_active=.
+func GetUsers(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	// Parse query parameters
+	query := r.URL.Query()
+	page, _ := strconv.Atoi(query.Get("page"))
+	limit, _ := strconv.Atoi(query.Get("limit"))
+
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+
+	filter := services.UserFilter{
+		Search: query.Get("search"),
+		Role:   query.Get("role"),
+		Limit:  limit,
+		Offset: (page - 1) * limit,
+	}
+	if isActive := query.Get("is_active"); isActive != "" {
+		active, err := strconv.ParseBool(isActive)
+		if err != nil {
+			http.Error(w, `{"error":"is_active must be true or false"}`, http.StatusBadRequest)
+			return
+		}
+		filter.IsActive = &active
+	}
+
+	users, total, err := services.ListUsers(r.Context(), filter)
+	if err != nil {
+		log.Printf("[USERS] %v", err)
+		http.Error(w, `{"error":"Failed to fetch users"}`, http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"users": users,
+		"total": total,
+		"page":  page,
+		"limit": limit,
+	})
+}
+
+// GetUser returns a single user (admins)
+func GetUser(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	userID, ok := parseUserID(w, r)
+	if !ok {
+		return
+	}
+
+	user, err := services.GetUserByID(r.Context(), userID)
+	if errors.Is(err, services.ErrUserNotFound) {
+		http.Error(w, `{"error":"User not found"}`, http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, `{"error":"Failed to fetch user"}`, http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(user)
+}
+
+// UpdateUser changes a user's global role, e.g. to promote or demote an admin (admins)
+func UpdateUser(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	userID, ok := parseUserID(w, r)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		Role string `json:"role"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, `{"error":"Invalid request body"}`, http.StatusBadRequest)
+		return
+	}
+
+	if !models.IsValidUserRole(req.Role) {
+		http.Error(w, `{"error":"Role must be viewer, triager or admin"}`, http.StatusBadRequest)
+		return
+	}
+
+	snapshot := auditSnapshot(r, "users", userID)
+
+	err := services.SetUserRole(r.Context(), userID, req.Role)
+	if !writeUserUpdateError(w, err) {
+		return
+	}
+
+	log.Printf("[USERS] %v set the role of user %s to %s", currentUserID(r), userID, req.Role)
+	recordAudit(r, "user.update", "user", userID, snapshot, auditSnapshot(r, "users", userID))
+	json.NewEncoder(w).Encode(map[string]string{"message": "User role updated successfully"})
+}
+
+// DeactivateUser blocks a user from signing in until they are reactivated (admins)
+func DeactivateUser(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	userID, ok := parseUserID(w, r)
+	if !ok {
+		return
+	}
+
+	snapshot := auditSnapshot(r, "users", userID)
+
+	err := services.DeactivateUser(r.Context(), userID)
+	if !writeUserUpdateError(w, err) {
+		return
+	}
+
+	log.Printf("[USERS] %v deactivated user %s", currentUserID(r), userID)
+	recordAudit(r, "user.deactivate", "user", userID, snapshot, auditSnapshot(r, "users", userID))
+	json.NewEncoder(w).Encode(map[string]string{"message": "User deactivated successfully"})
+}
+
+// ReactivateUser lets a deactivated user sign in again (admins)
+func ReactivateUser(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	userID, ok := parseUserID(w, r)
+	if !ok {
+		return
+	}
+
+	snapshot := auditSnapshot(r, "users", userID)
+
+	err := services.ReactivateUser(r.Context(), userID)
+	if !writeUserUpdateError(w, err) {
+		return
+	}
+
+	log.Printf("[USERS] %v reactivated user %s", currentUserID(r), userID)
+	recordAudit(r, "user.reactivate", "user", userID, snapshot, auditSnapshot(r, "users", userID))
+	json.NewEncoder(w).Encode(map[string]string{"message": "User reactivated successfully"})
+}
+
+// parseUserID reads the user_id route variable, writing a 400 if it's malformed
+func parseUserID(w http.ResponseWriter, r *http.Request) (uuid.UUID, bool) {
+	userID, err := uuid.Parse(mux.Vars(r)["user_id"])
+	if err != nil {
+		http.Error(w, `{"error":"Invalid user ID"}`, http.StatusBadRequest)
+		return uuid.Nil, false
+	}
+	return userID, true
+}
+
+// writeUserUpdateError writes the response for a failed user update. It returns true if err is nil.
+func writeUserUpdateError(w http.ResponseWriter, err error) bool {
+	switch {
+	case err == nil:
+		return true
+	case errors.Is(err, services.ErrUserNotFound):
+		http.Error(w, `{"error":"User not found"}`, http.StatusNotFound)
+	case errors.Is(err, services.ErrLastAdmin):
+		http.Error(w, `{"error":"Cannot demote or deactivate the last active admin"}`, http.StatusConflict)
+	default:
+		log.Printf("[USERS] %v", err)
+		http.Error(w, `{"error":"Failed to update user"}`, http.StatusInternalServerError)
+	}
+	return false
+}
@@ -0,0 +1,539 @@
+package controllers
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/frallan97/feedback-service/backend/database"
+	"github.com/frallan97/feedback-service/backend/models"
+	"github.com/frallan97/feedback-service/backend/services"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/lib/pq"
+)
+
+// generateWebhookSecret creates a random secret used to sign webhook deliveries
+func generateWebhookSecret() (string, error) {
+	b := make([]byte, 32)
+	_, err := rand.Read(b)
+	if err != nil {
+		return "", err
+	}
+	return "whsec_" + hex.EncodeToString(b), nil
+}
+
+// validateWebhookURL checks that a webhook URL is an absolute http(s) URL whose host isn't
+// an internal address
+func validateWebhookURL(raw string) bool {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return false
+	}
+	return (u.Scheme == "http" || u.Scheme == "https") && u.Hostname() != "" &&
+		!services.IsInternalWebhookHost(u.Hostname())
+}
+
+// validateWebhookEventTypes checks that every subscribed event type is known
+func validateWebhookEventTypes(eventTypes []string) bool {
+	if len(eventTypes) == 0 {
+		return false
+	}
+	for _, t := range eventTypes {
+		if !services.IsValidWebhookEventType(t) {
+			return false
+		}
+	}
+	return true
+}
+
+// GetWebhookEndpoints returns all webhook endpoints for an application without their secrets (application owners and admins)
+func GetWebhookEndpoints(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	vars := mux.Vars(r)
+	appID := vars["id"]
+
+	rows, err := database.DB.QueryContext(r.Context(), `
+		SELECT id, application_id, url, COALESCE(description, ''), enabled, event_types, created_at, updated_at
+		FROM webhook_endpoints
+		WHERE application_id = $1
+		ORDER BY created_at
+	`, appID)
+	if err != nil {
+		http.Error(w, `{"error":"Failed to fetch webhook endpoints"}`, http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	endpoints := []models.WebhookEndpoint{}
+	for rows.Next() {
+		var e models.WebhookEndpoint
+		err := rows.Scan(
+			&e.ID, &e.ApplicationID, &e.URL, &e.Description, &e.Enabled,
+			pq.Array(&e.EventTypes), &e.CreatedAt, &e.UpdatedAt,
+		)
+		if err != nil {
+			continue
+		}
+		endpoints = append(endpoints, e)
+	}
+
+	json.NewEncoder(w).Encode(endpoints)
+}
+
+// GetWebhookEndpoint returns a single webhook endpoint with its signing secret (application owners and admins)
+func GetWebhookEndpoint(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	vars := mux.Vars(r)
+	appID := vars["id"]
+	endpointID := vars["endpoint_id"]
+
+	var e models.WebhookEndpoint
+	err := database.DB.QueryRowContext(r.Context(), `
+		SELECT id, application_id, url, secret, COALESCE(description, ''), enabled, event_types, created_at, updated_at
+		FROM webhook_endpoints
+		WHERE id = $1 AND application_id = $2
+	`, endpointID, appID).Scan(
+		&e.ID, &e.ApplicationID, &e.URL, &e.Secret, &e.Description, &e.Enabled,
+		pq.Array(&e.EventTypes), &e.CreatedAt, &e.UpdatedAt,
+	)
+
+	if err == sql.ErrNoRows {
+		http.Error(w, `{"error":"Webhook endpoint not found"}`, http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, `{"error":"Failed to fetch webhook endpoint"}`, http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(e)
+}
+
+// CreateWebhookEndpoint registers a new webhook endpoint for an application (application owners and admins)
+func CreateWebhookEndpoint(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	vars := mux.Vars(r)
+	appID, err := uuid.Parse(vars["id"])
+	if err != nil {
+		http.Error(w, `{"error":"Invalid application ID"}`, http.StatusBadRequest)
+		return
+	}
+
+	var req struct {
+		URL         string   `json:"url"`
+		Description string   `json:"description"`
+		Enabled     *bool    `json:"enabled"`
+		EventTypes  []string `json:"event_types"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, `{"error":"Invalid request body"}`, http.StatusBadRequest)
+		return
+	}
+
+	if !validateWebhookURL(req.URL) {
+		http.Error(w, `{"error":"A valid http(s) URL with a public host is required"}`, http.StatusBadRequest)
+		return
+	}
+
+	// Set defaults
+	if req.EventTypes == nil {
+		req.EventTypes = []string{services.EventWildcard}
+	}
+	if !validateWebhookEventTypes(req.EventTypes) {
+		http.Error(w, `{"error":"Unknown event type"}`, http.StatusBadRequest)
+		return
+	}
+	enabled := true
+	if req.Enabled != nil {
+		enabled = *req.Enabled
+	}
+
+	secret, err := generateWebhookSecret()
+	if err != nil {
+		http.Error(w, `{"error":"Failed to generate webhook secret"}`, http.StatusInternalServerError)
+		return
+	}
+
+	var e models.WebhookEndpoint
+	err = database.DB.QueryRowContext(r.Context(), `
+		INSERT INTO webhook_endpoints (application_id, url, secret, description, enabled, event_types)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, application_id, url, secret, COALESCE(description, ''), enabled, event_types, created_at, updated_at
+	`, appID, req.URL, secret, req.Description, enabled, pq.Array(req.EventTypes)).Scan(
+		&e.ID, &e.ApplicationID, &e.URL, &e.Secret, &e.Description, &e.Enabled,
+		pq.Array(&e.EventTypes), &e.CreatedAt, &e.UpdatedAt,
+	)
+
+	if err != nil {
+		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23503" {
+			http.Error(w, `{"error":"Application not found"}`, http.StatusNotFound)
+			return
+		}
+		http.Error(w, `{"error":"Failed to create webhook endpoint"}`, http.StatusInternalServerError)
+		return
+	}
+
+	recordAudit(r, "webhook_endpoint.create", "webhook_endpoint", e.ID, nil, auditSnapshot(r, "webhook_endpoints", e.ID))
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(e)
+}
+
+// UpdateWebhookEndpoint updates a webhook endpoint's URL, description, subscriptions or enabled flag (application owners and admins)
+func UpdateWebhookEndpoint(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	vars := mux.Vars(r)
+	appID := vars["id"]
+	endpointID := vars["endpoint_id"]
+
+	var req struct {
+		URL         *string  `json:"url"`
+		Description *string  `json:"description"`
+		Enabled     *bool    `json:"enabled"`
+		EventTypes  []string `json:"event_types"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, `{"error":"Invalid request body"}`, http.StatusBadRequest)
+		return
+	}
+
+	// Build update query dynamically
+	updates := []string{}
+	args := []interface{}{}
+	argPos := 1
+
+	if req.URL != nil {
+		if !validateWebhookURL(*req.URL) {
+			http.Error(w, `{"error":"A valid http(s) URL with a public host is required"}`, http.StatusBadRequest)
+			return
+		}
+		updates = append(updates, "url = $"+strconv.Itoa(argPos))
+		args = append(args, *req.URL)
+		argPos++
+	}
+
+	if req.Description != nil {
+		updates = append(updates, "description = $"+strconv.Itoa(argPos))
+		args = append(args, *req.Description)
+		argPos++
+	}
+
+	if req.Enabled != nil {
+		updates = append(updates, "enabled = $"+strconv.Itoa(argPos))
+		args = append(args, *req.Enabled)
+		argPos++
+	}
+
+	if req.EventTypes != nil {
+		if !validateWebhookEventTypes(req.EventTypes) {
+			http.Error(w, `{"error":"Unknown event type"}`, http.StatusBadRequest)
+			return
+		}
+		updates = append(updates, "event_types = $"+strconv.Itoa(argPos))
+		args = append(args, pq.Array(req.EventTypes))
+		argPos++
+	}
+
+	if len(updates) == 0 {
+		http.Error(w, `{"error":"No fields to update"}`, http.StatusBadRequest)
+		return
+	}
+
+	// Add endpoint and application IDs to args
+	args = append(args, endpointID, appID)
+
+	// Execute update
+	query := "UPDATE webhook_endpoints SET " + updates[0]
+	for i := 1; i < len(updates); i++ {
+		query += ", " + updates[i]
+	}
+	query += " WHERE id = $" + strconv.Itoa(argPos) + " AND application_id = $" + strconv.Itoa(argPos+1)
+
+	snapshot := auditSnapshot(r, "webhook_endpoints", endpointID)
+
+	result, err := database.DB.ExecContext(r.Context(), query, args...)
+	if err != nil {
+		http.Error(w, `{"error":"Failed to update webhook endpoint"}`, http.StatusInternalServerError)
+		return
+	}
+
+	rows, _ := result.RowsAffected()
+	if rows == 0 {
+		http.Error(w, `{"error":"Webhook endpoint not found"}`, http.StatusNotFound)
+		return
+	}
+
+	recordAudit(r, "webhook_endpoint.update", "webhook_endpoint", endpointID, snapshot, auditSnapshot(r, "webhook_endpoints", endpointID))
+
+	json.NewEncoder(w).Encode(map[string]string{"message": "Webhook endpoint updated successfully"})
+}
+
+// RotateWebhookEndpointSecret generates a new signing secret for a webhook endpoint (application owners and admins)
+func RotateWebhookEndpointSecret(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	vars := mux.Vars(r)
+	appID := vars["id"]
+	endpointID := vars["endpoint_id"]
+
+	secret, err := generateWebhookSecret()
+	if err != nil {
+		http.Error(w, `{"error":"Failed to generate webhook secret"}`, http.StatusInternalServerError)
+		return
+	}
+
+	snapshot := auditSnapshot(r, "webhook_endpoints", endpointID)
+
+	result, err := database.DB.ExecContext(r.Context(),
+		"UPDATE webhook_endpoints SET secret = $1 WHERE id = $2 AND application_id = $3",
+		secret, endpointID, appID,
+	)
+	if err != nil {
+		http.Error(w, `{"error":"Failed to update webhook secret"}`, http.StatusInternalServerError)
+		return
+	}
+
+	rows, _ := result.RowsAffected()
+	if rows == 0 {
+		http.Error(w, `{"error":"Webhook endpoint not found"}`, http.StatusNotFound)
+		return
+	}
+
+	recordAudit(r, "webhook_endpoint.rotate_secret", "webhook_endpoint", endpointID, snapshot, auditSnapshot(r, "webhook_endpoints", endpointID))
+
+	json.NewEncoder(w).Encode(map[string]string{
+		"secret":  secret,
+		"message": "Webhook secret rotated successfully",
+	})
+}
+
+// DeleteWebhookEndpoint removes a webhook endpoint, dead-lettering its pending deliveries (application owners and admins)
+func DeleteWebhookEndpoint(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	vars := mux.Vars(r)
+	appID, err := uuid.Parse(vars["id"])
+	if err != nil {
+		http.Error(w, `{"error":"Invalid application ID"}`, http.StatusBadRequest)
+		return
+	}
+	endpointID, err := uuid.Parse(vars["endpoint_id"])
+	if err != nil {
+		http.Error(w, `{"error":"Invalid endpoint ID"}`, http.StatusBadRequest)
+		return
+	}
+
+	snapshot := auditSnapshot(r, "webhook_endpoints", endpointID)
+
+	deleted, err := services.DeleteWebhookEndpoint(r.Context(), appID, endpointID)
+	if err != nil {
+		http.Error(w, `{"error":"Failed to delete webhook endpoint"}`, http.StatusInternalServerError)
+		return
+	}
+	if !deleted {
+		http.Error(w, `{"error":"Webhook endpoint not found"}`, http.StatusNotFound)
+		return
+	}
+
+	recordAudit(r, "webhook_endpoint.delete", "webhook_endpoint", endpointID, snapshot, nil)
+
+	json.NewEncoder(w).Encode(map[string]string{"message": "Webhook endpoint deleted successfully"})
+}
+
+// GetWebhookDeliveries returns the webhook delivery log for an application (application owners and admins).
+// Use ?status=dead to list the dead-letter queue.
+func GetWebhookDeliveries(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	vars := mux.Vars(r)
+	appID, err := uuid.Parse(vars["id"])
+	if err != nil {
+		http.Error(w, `{"error":"Invalid application ID"}`, http.StatusBadRequest)
+		return
+	}
+
+	// Parse query parameters
+	query := r.URL.Query()
+	status := query.Get("status")
+	eventType := query.Get("event_type")
+	endpointID := query.Get("endpoint_id")
+	page, _ := strconv.Atoi(query.Get("page"))
+	limit, _ := strconv.Atoi(query.Get("limit"))
+
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+	offset := (page - 1) * limit
+
+	// Build filters
+	where := " WHERE application_id = $1"
+	args := []interface{}{appID}
+	argPos := 2
+
+	if status != "" {
+		where += " AND status = $" + strconv.Itoa(argPos)
+		args = append(args, status)
+		argPos++
+	}
+	if eventType != "" {
+		where += " AND event_type = $" + strconv.Itoa(argPos)
+		args = append(args, eventType)
+		argPos++
+	}
+	if endpointID != "" {
+		where += " AND endpoint_id = $" + strconv.Itoa(argPos)
+		args = append(args, endpointID)
+		argPos++
+	}
+
+	var total int
+	database.DB.QueryRowContext(r.Context(), "SELECT COUNT(*) FROM webhook_deliveries"+where, args...).Scan(&total)
+
+	queryStr := `
+		SELECT id, application_id, endpoint_id, event_id, event_type, url, status, attempts, next_attempt_at,
+			   last_response_status, last_latency_ms, last_error, redelivery_of,
+			   created_at, updated_at, completed_at
+		FROM webhook_deliveries` + where +
+		" ORDER BY created_at DESC LIMIT $" + strconv.Itoa(argPos) + " OFFSET $" + strconv.Itoa(argPos+1)
+	args = append(args, limit, offset)
+
+	rows, err := database.DB.QueryContext(r.Context(), queryStr, args...)
+	if err != nil {
+		http.Error(w, `{"error":"Failed to fetch webhook deliveries"}`, http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	deliveries := []models.WebhookDelivery{}
+	for rows.Next() {
+		var d models.WebhookDelivery
+		err := rows.Scan(
+			&d.ID, &d.ApplicationID, &d.EndpointID, &d.EventID, &d.EventType, &d.URL, &d.Status, &d.Attempts, &d.NextAttemptAt,
+			&d.LastResponseStatus, &d.LastLatencyMs, &d.LastError, &d.RedeliveryOf,
+			&d.CreatedAt, &d.UpdatedAt, &d.CompletedAt,
+		)
+		if err != nil {
+			continue
+		}
+		deliveries = append(deliveries, d)
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"deliveries": deliveries,
+		"total":      total,
+		"page":       page,
+		"limit":      limit,
+	})
+}
+
+// GetWebhookDelivery returns a single delivery with its payload and every attempt (application owners and admins).
+// Receivers' response bodies are only shown to admins.
+func GetWebhookDelivery(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	vars := mux.Vars(r)
+	appID := vars["id"]
+	deliveryID := vars["delivery_id"]
+
+	var d models.WebhookDelivery
+	var payload []byte
+	err := database.DB.QueryRowContext(r.Context(), `
+		SELECT id, application_id, endpoint_id, event_id, event_type, url, payload, status, attempts, next_attempt_at,
+			   last_response_status, last_latency_ms, last_error, redelivery_of,
+			   created_at, updated_at, completed_at
+		FROM webhook_deliveries
+		WHERE id = $1 AND application_id = $2
+	`, deliveryID, appID).Scan(
+		&d.ID, &d.ApplicationID, &d.EndpointID, &d.EventID, &d.EventType, &d.URL, &payload, &d.Status, &d.Attempts, &d.NextAttemptAt,
+		&d.LastResponseStatus, &d.LastLatencyMs, &d.LastError, &d.RedeliveryOf,
+		&d.CreatedAt, &d.UpdatedAt, &d.CompletedAt,
+	)
+
+	if err == sql.ErrNoRows {
+		http.Error(w, `{"error":"Delivery not found"}`, http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, `{"error":"Failed to fetch delivery"}`, http.StatusInternalServerError)
+		return
+	}
+	d.Payload = payload
+
+	rows, err := database.DB.QueryContext(r.Context(), `
+		SELECT id, delivery_id, attempt, response_status, response_body, latency_ms, error, created_at
+		FROM webhook_delivery_attempts
+		WHERE delivery_id = $1
+		ORDER BY attempt ASC
+	`, d.ID)
+	if err != nil {
+		http.Error(w, `{"error":"Failed to fetch delivery attempts"}`, http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	// Response bodies come from whatever the URL reached, so only admins see them
+	_, scoped := memberScope(r)
+
+	d.AttemptLog = []models.WebhookDeliveryAttempt{}
+	for rows.Next() {
+		var a models.WebhookDeliveryAttempt
+		if err := rows.Scan(&a.ID, &a.DeliveryID, &a.Attempt, &a.ResponseStatus, &a.ResponseBody, &a.LatencyMs, &a.Error, &a.CreatedAt); err != nil {
+			continue
+		}
+		if scoped {
+			a.ResponseBody = nil
+		}
+		d.AttemptLog = append(d.AttemptLog, a)
+	}
+
+	json.NewEncoder(w).Encode(d)
+}
+
+// RedeliverWebhook re-sends a delivery's event as a new delivery (application owners and admins)
+func RedeliverWebhook(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	vars := mux.Vars(r)
+	appID, err := uuid.Parse(vars["id"])
+	if err != nil {
+		http.Error(w, `{"error":"Invalid application ID"}`, http.StatusBadRequest)
+		return
+	}
+	deliveryID, err := uuid.Parse(vars["delivery_id"])
+	if err != nil {
+		http.Error(w, `{"error":"Invalid delivery ID"}`, http.StatusBadRequest)
+		return
+	}
+
+	newID, err := services.RedeliverWebhook(r.Context(), appID, deliveryID)
+	if err == services.ErrDeliveryNotFound {
+		http.Error(w, `{"error":"Delivery not found"}`, http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, `{"error":"Failed to queue redelivery"}`, http.StatusInternalServerError)
+		return
+	}
+
+	recordAudit(r, "webhook_delivery.redeliver", "webhook_delivery", deliveryID, nil, auditSnapshot(r, webhookDeliveryAuditTable, newID))
+
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"id":      newID,
+		"message": "Redelivery queued successfully",
+	})
+}
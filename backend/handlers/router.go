@@ -7,16 +7,19 @@ import (
 	"github.com/frallan97/feedback-service/backend/config"
 	"github.com/frallan97/feedback-service/backend/controllers"
 	"github.com/frallan97/feedback-service/backend/middleware"
+	"github.com/frallan97/feedback-service/backend/models"
+	customJWT "github.com/frallan97/feedback-service/backend/pkg/jwt"
 	"github.com/gorilla/mux"
 )
 
 // SetupRouter configures all routes
 // IMPORTANT: All routes include OPTIONS method for CORS preflight requests
-func SetupRouter(cfg *config.Config, enforcer *casbin.Enforcer) http.Handler {
+func SetupRouter(cfg *config.Config, enforcer *casbin.SyncedEnforcer, jwtKeys *customJWT.KeySet) http.Handler {
 	r := mux.NewRouter()
 
 	// Global middleware - CORS must be first!
-	r.Use(middleware.CORS)
+	r.Use(middleware.CORS(cfg.AllowedOrigins))
+	r.Use(middleware.RequestContext(cfg.TrustedProxies))
 	r.Use(middleware.Logger)
 	r.Use(middleware.Recovery)
 
@@ -29,41 +32,65 @@ func SetupRouter(cfg *config.Config, enforcer *casbin.Enforcer) http.Handler {
 		w.Write([]byte("OK"))
 	}).Methods("GET", "OPTIONS")
 
-	// Public API (API key authentication) - for client applications
+	// Public API (API key authentication) - for client applications; each route needs a key scope
 	public := api.PathPrefix("/public").Subrouter()
 	public.Use(middleware.AppAuth)
-	public.HandleFunc("/feedback", controllers.SubmitFeedback).Methods("POST", "OPTIONS")
-	public.HandleFunc("/feedback/{id}", controllers.GetPublicFeedbackStatus).Methods("GET", "OPTIONS")
-	public.HandleFunc("/categories", controllers.GetPublicCategories).Methods("GET", "OPTIONS")
-
-	// Auth endpoints (public except /me)
-	api.HandleFunc("/auth/refresh", controllers.RefreshToken).Methods("POST", "OPTIONS")
-	api.HandleFunc("/auth/logout", controllers.Logout).Methods("POST", "OPTIONS")
+	public.Use(middleware.RateLimit)
+	feedbackWrite := middleware.RequireScope(models.ScopeFeedbackWrite)
+	feedbackReadStatus := middleware.RequireScope(models.ScopeFeedbackReadStatus)
+	categoriesRead := middleware.RequireScope(models.ScopeCategoriesRead)
+	public.Handle("/challenge", feedbackWrite(http.HandlerFunc(controllers.GetPublicChallenge))).Methods("GET", "OPTIONS")
+	public.Handle("/feedback", feedbackWrite(middleware.Idempotency(http.HandlerFunc(controllers.SubmitFeedback)))).Methods("POST", "OPTIONS")
+	public.Handle("/feedback/{id}", feedbackReadStatus(http.HandlerFunc(controllers.GetPublicFeedbackStatus))).Methods("GET", "OPTIONS")
+	public.Handle("/feedback/{id}/attachments", feedbackWrite(http.HandlerFunc(controllers.UploadAttachment))).Methods("POST", "OPTIONS")
+	public.Handle("/categories", categoriesRead(http.HandlerFunc(controllers.GetPublicCategories))).Methods("GET", "OPTIONS")
+
+	// Attachment downloads (authorized by a short-lived signed URL)
+	api.HandleFunc("/attachments/{attachment_id}", controllers.ServeAttachment).Methods("GET", "OPTIONS")
+
+	// Auth endpoints (public except /me; refresh and logout are proxied to the auth-service)
+	api.HandleFunc("/auth/refresh", controllers.RefreshToken(cfg.AuthRefreshURL)).Methods("POST", "OPTIONS")
+	api.HandleFunc("/auth/logout", controllers.Logout(cfg.AuthLogoutURL, jwtKeys)).Methods("POST", "OPTIONS")
 
 	// Protected routes requiring JWT authentication
 	protected := api.PathPrefix("").Subrouter()
-	protected.Use(middleware.Auth(cfg.JWTPublicKey))
+	protected.Use(middleware.Auth(jwtKeys))
 
 	// Auth /me endpoint (authenticated)
 	protected.HandleFunc("/auth/me", controllers.GetCurrentUser).Methods("GET", "OPTIONS")
 
-	// Protected + Authorized routes (role-based access control)
+	// Personal access tokens for scripting the admin API (the current user's own tokens)
+	protected.HandleFunc("/auth/tokens", controllers.GetPersonalAccessTokens).Methods("GET", "OPTIONS")
+	protected.HandleFunc("/auth/tokens", controllers.CreatePersonalAccessToken).Methods("POST", "OPTIONS")
+	protected.HandleFunc("/auth/tokens/{token_id}", controllers.RevokePersonalAccessToken).Methods("DELETE", "OPTIONS")
+
+	// Protected + Authorized routes (role-based access control: viewer < triager < owner < admin)
 	authorized := protected.PathPrefix("").Subrouter()
 	authorized.Use(middleware.Authorize(enforcer))
 
-	// Feedback management (admin)
+	// Feedback management, scoped to the user's applications (viewers read, triagers update, owners delete)
 	authorized.HandleFunc("/feedback", controllers.GetFeedback).Methods("GET", "OPTIONS")
 	authorized.HandleFunc("/feedback/{id}", controllers.GetFeedbackByID).Methods("GET", "OPTIONS")
 	authorized.HandleFunc("/feedback/{id}", controllers.UpdateFeedback).Methods("PATCH", "OPTIONS")
 	authorized.HandleFunc("/feedback/{id}", controllers.DeleteFeedback).Methods("DELETE", "OPTIONS")
 
-	// Comments (authenticated users can view/create, admins can manage)
+	// Spam moderation queue (triagers review held feedback)
+	authorized.HandleFunc("/applications/{id}/moderation", controllers.GetModerationQueue).Methods("GET", "OPTIONS")
+	authorized.HandleFunc("/feedback/{id}/approve", controllers.ApproveFeedback).Methods("POST", "OPTIONS")
+	authorized.HandleFunc("/feedback/{id}/reject", controllers.RejectFeedback).Methods("POST", "OPTIONS")
+
+	// Comments (viewers read, triagers write, owners can manage any comment)
 	authorized.HandleFunc("/feedback/{id}/comments", controllers.GetComments).Methods("GET", "OPTIONS")
 	authorized.HandleFunc("/feedback/{id}/comments", controllers.CreateComment).Methods("POST", "OPTIONS")
 	authorized.HandleFunc("/feedback/{id}/comments/{comment_id}", controllers.UpdateComment).Methods("PATCH", "OPTIONS")
 	authorized.HandleFunc("/feedback/{id}/comments/{comment_id}", controllers.DeleteComment).Methods("DELETE", "OPTIONS")
 
-	// Application management (admin only)
+	// Attachments (viewers read, owners delete)
+	authorized.HandleFunc("/feedback/{id}/attachments", controllers.GetAttachments).Methods("GET", "OPTIONS")
+	authorized.HandleFunc("/feedback/{id}/attachments/{attachment_id}", controllers.GetAttachment).Methods("GET", "OPTIONS")
+	authorized.HandleFunc("/feedback/{id}/attachments/{attachment_id}", controllers.DeleteAttachment).Methods("DELETE", "OPTIONS")
+
+	// Application management (members read, owners manage, admins create)
 	authorized.HandleFunc("/applications", controllers.GetApplications).Methods("GET", "OPTIONS")
 	authorized.HandleFunc("/applications", controllers.CreateApplication).Methods("POST", "OPTIONS")
 	authorized.HandleFunc("/applications/{id}", controllers.GetApplicationByID).Methods("GET", "OPTIONS")
@@ -71,9 +98,76 @@ func SetupRouter(cfg *config.Config, enforcer *casbin.Enforcer) http.Handler {
 	authorized.HandleFunc("/applications/{id}", controllers.DeleteApplication).Methods("DELETE", "OPTIONS")
 	authorized.HandleFunc("/applications/{id}/regenerate-key", controllers.RegenerateAPIKey).Methods("POST", "OPTIONS")
 
-	// Categories (admin only)
+	// API keys (application owners and admins)
+	authorized.HandleFunc("/applications/{id}/api-keys", controllers.GetAPIKeys).Methods("GET", "OPTIONS")
+	authorized.HandleFunc("/applications/{id}/api-keys", controllers.CreateAPIKey).Methods("POST", "OPTIONS")
+	authorized.HandleFunc("/applications/{id}/api-keys/{key_id}", controllers.GetAPIKey).Methods("GET", "OPTIONS")
+	authorized.HandleFunc("/applications/{id}/api-keys/{key_id}", controllers.UpdateAPIKey).Methods("PATCH", "OPTIONS")
+	authorized.HandleFunc("/applications/{id}/api-keys/{key_id}", controllers.RevokeAPIKey).Methods("DELETE", "OPTIONS")
+	authorized.HandleFunc("/applications/{id}/api-keys/{key_id}/rotate", controllers.RotateAPIKey).Methods("POST", "OPTIONS")
+	authorized.HandleFunc("/applications/{id}/api-keys/{key_id}/end-grace", controllers.EndAPIKeyGracePeriod).Methods("POST", "OPTIONS")
+
+	// End-user identity keys (application owners and admins) and verified end users (members read)
+	authorized.HandleFunc("/applications/{id}/identity", controllers.GetApplicationIdentity).Methods("GET", "OPTIONS")
+	authorized.HandleFunc("/applications/{id}/identity", controllers.UpdateApplicationIdentity).Methods("PATCH", "OPTIONS")
+	authorized.HandleFunc("/applications/{id}/identity/rotate-secret", controllers.RotateIdentitySecret).Methods("POST", "OPTIONS")
+	authorized.HandleFunc("/applications/{id}/identity/secret", controllers.DeleteIdentitySecret).Methods("DELETE", "OPTIONS")
+	authorized.HandleFunc("/applications/{id}/end-users", controllers.GetEndUsers).Methods("GET", "OPTIONS")
+
+	// Request signing secret for server-to-server calls (application owners and admins)
+	authorized.HandleFunc("/applications/{id}/signing-secret", controllers.GetSigningSecret).Methods("GET", "OPTIONS")
+	authorized.HandleFunc("/applications/{id}/signing-secret", controllers.DeleteSigningSecret).Methods("DELETE", "OPTIONS")
+	authorized.HandleFunc("/applications/{id}/signing-secret/rotate", controllers.RotateSigningSecret).Methods("POST", "OPTIONS")
+
+	// Application members and invitations (members read, owners manage)
+	authorized.HandleFunc("/applications/{id}/members", controllers.GetMembers).Methods("GET", "OPTIONS")
+	authorized.HandleFunc("/applications/{id}/members", controllers.InviteMember).Methods("POST", "OPTIONS")
+	authorized.HandleFunc("/applications/{id}/members/{user_id}", controllers.UpdateMember).Methods("PATCH", "OPTIONS")
+	authorized.HandleFunc("/applications/{id}/members/{user_id}", controllers.RemoveMember).Methods("DELETE", "OPTIONS")
+	authorized.HandleFunc("/applications/{id}/invitations/{invitation_id}", controllers.RevokeInvitation).Methods("DELETE", "OPTIONS")
+
+	// Webhook endpoints (application owners and admins)
+	authorized.HandleFunc("/applications/{id}/webhooks/endpoints", controllers.GetWebhookEndpoints).Methods("GET", "OPTIONS")
+	authorized.HandleFunc("/applications/{id}/webhooks/endpoints", controllers.CreateWebhookEndpoint).Methods("POST", "OPTIONS")
+	authorized.HandleFunc("/applications/{id}/webhooks/endpoints/{endpoint_id}", controllers.GetWebhookEndpoint).Methods("GET", "OPTIONS")
+	authorized.HandleFunc("/applications/{id}/webhooks/endpoints/{endpoint_id}", controllers.UpdateWebhookEndpoint).Methods("PATCH", "OPTIONS")
+	authorized.HandleFunc("/applications/{id}/webhooks/endpoints/{endpoint_id}", controllers.DeleteWebhookEndpoint).Methods("DELETE", "OPTIONS")
+	authorized.HandleFunc("/applications/{id}/webhooks/endpoints/{endpoint_id}/rotate-secret", controllers.RotateWebhookEndpointSecret).Methods("POST", "OPTIONS")
+
+	// Webhook delivery log and dead-letter queue (application owners and admins)
+	authorized.HandleFunc("/applications/{id}/webhooks/deliveries", controllers.GetWebhookDeliveries).Methods("GET", "OPTIONS")
+	authorized.HandleFunc("/applications/{id}/webhooks/deliveries/{delivery_id}", controllers.GetWebhookDelivery).Methods("GET", "OPTIONS")
+	authorized.HandleFunc("/applications/{id}/webhooks/deliveries/{delivery_id}/redeliver", controllers.RedeliverWebhook).Methods("POST", "OPTIONS")
+
+	// Submission blocklists (application owners and admins)
+	authorized.HandleFunc("/applications/{id}/blocklist", controllers.GetBlocklistRules).Methods("GET", "OPTIONS")
+	authorized.HandleFunc("/applications/{id}/blocklist", controllers.CreateBlocklistRule).Methods("POST", "OPTIONS")
+	authorized.HandleFunc("/applications/{id}/blocklist/{rule_id}", controllers.GetBlocklistRule).Methods("GET", "OPTIONS")
+	authorized.HandleFunc("/applications/{id}/blocklist/{rule_id}", controllers.UpdateBlocklistRule).Methods("PATCH", "OPTIONS")
+	authorized.HandleFunc("/applications/{id}/blocklist/{rule_id}", controllers.DeleteBlocklistRule).Methods("DELETE", "OPTIONS")
+
+	// Categories (members read, owners manage)
 	authorized.HandleFunc("/applications/{app_id}/categories", controllers.GetCategories).Methods("GET", "OPTIONS")
 	authorized.HandleFunc("/applications/{app_id}/categories", controllers.CreateCategory).Methods("POST", "OPTIONS")
 
+	// User administration (admins)
+	authorized.HandleFunc("/users", controllers.GetUsers).Methods("GET", "OPTIONS")
+	authorized.HandleFunc("/users/{user_id}", controllers.GetUser).Methods("GET", "OPTIONS")
+	authorized.HandleFunc("/users/{user_id}", controllers.UpdateUser).Methods("PATCH", "OPTIONS")
+	authorized.HandleFunc("/users/{user_id}/deactivate", controllers.DeactivateUser).Methods("POST", "OPTIONS")
+	authorized.HandleFunc("/users/{user_id}/reactivate", controllers.ReactivateUser).Methods("POST", "OPTIONS")
+
+	// Audit log of administrative changes (admins)
+	authorized.HandleFunc("/audit", controllers.GetAuditEvents).Methods("GET", "OPTIONS")
+
+	// Policy management (admins)
+	authorized.HandleFunc("/authz/policies", controllers.GetPolicies(enforcer)).Methods("GET", "OPTIONS")
+	authorized.HandleFunc("/authz/policies", controllers.CreatePolicy(enforcer)).Methods("POST", "OPTIONS")
+	authorized.HandleFunc("/authz/policies", controllers.DeletePolicy(enforcer)).Methods("DELETE", "OPTIONS")
+	authorized.HandleFunc("/authz/roles", controllers.GetRoleAssignments(enforcer)).Methods("GET", "OPTIONS")
+	authorized.HandleFunc("/authz/roles", controllers.CreateRoleAssignment(enforcer)).Methods("POST", "OPTIONS")
+	authorized.HandleFunc("/authz/roles", controllers.DeleteRoleAssignment(enforcer)).Methods("DELETE", "OPTIONS")
+	authorized.HandleFunc("/authz/check", controllers.CheckPermission(enforcer)).Methods("POST", "OPTIONS")
+
 	return r
 }
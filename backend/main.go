@@ -1,14 +1,20 @@
 package main
 
 import (
+	"context"
+	"fmt"
 	"log"
 	"net/http"
+	"os"
 
 	"github.com/casbin/casbin/v2"
 	gormadapter "github.com/casbin/gorm-adapter/v3"
 	"github.com/frallan97/feedback-service/backend/config"
 	"github.com/frallan97/feedback-service/backend/database"
 	"github.com/frallan97/feedback-service/backend/handlers"
+	customJWT "github.com/frallan97/feedback-service/backend/pkg/jwt"
+	"github.com/frallan97/feedback-service/backend/pkg/storage"
+	"github.com/frallan97/feedback-service/backend/services"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 )
@@ -30,6 +36,20 @@ func main() {
 		log.Printf("Warning: Migration error: %v", err)
 	}
 
+	// One-off admin commands, e.g. "server promote-admin you@example.com"
+	if len(os.Args) > 1 {
+		if err := runCommand(os.Args[1:]); err != nil {
+			log.Fatalf("%s: %v", os.Args[1], err)
+		}
+		return
+	}
+
+	// Give a fresh install its first admin
+	services.SetBootstrapAdminEmail(cfg.BootstrapAdminEmail)
+	if err := services.BootstrapAdmin(context.Background()); err != nil {
+		log.Printf("Warning: %v", err)
+	}
+
 	// Initialize GORM DB for Casbin adapter
 	gormDB, err := gorm.Open(postgres.Open(cfg.DatabaseURL), &gorm.Config{})
 	if err != nil {
@@ -42,7 +62,7 @@ func main() {
 		log.Fatalf("Failed to create Casbin adapter: %v", err)
 	}
 
-	enforcer, err := casbin.NewEnforcer(cfg.CasbinModelPath, adapter)
+	enforcer, err := casbin.NewSyncedEnforcer(cfg.CasbinModelPath, adapter)
 	if err != nil {
 		log.Fatalf("Failed to create Casbin enforcer: %v", err)
 	}
@@ -52,10 +72,64 @@ func main() {
 		log.Fatalf("Failed to load Casbin policies: %v", err)
 	}
 
+	// Pick up policy changes made by other instances
+	if cfg.CasbinReloadInterval > 0 {
+		enforcer.StartAutoLoadPolicy(cfg.CasbinReloadInterval)
+	}
+
 	log.Println("Casbin enforcer initialized successfully")
 
+	// Load JWT verification keys, falling back to cached or static keys if the auth-service is down
+	jwtKeys, err := customJWT.NewKeySet(customJWT.KeySetConfig{
+		URL:             cfg.JWTKeysURL,
+		StaticKeyFile:   cfg.JWTPublicKeyFile,
+		CacheFile:       cfg.JWTKeyCacheFile,
+		RefreshInterval: cfg.JWTKeyRefreshInterval,
+	})
+	if err != nil {
+		log.Fatalf("Failed to load JWT public keys: %v", err)
+	}
+	jwtKeys.StartRefresh()
+	log.Printf("Loaded %d JWT public key(s)", jwtKeys.Len())
+
+	// Prepare attachment storage
+	store, err := newStorage(cfg)
+	if err != nil {
+		log.Fatalf("Failed to initialize attachment storage: %v", err)
+	}
+	services.InitAttachmentStorage(services.AttachmentStorageConfig{
+		Store:     store,
+		URLSecret: cfg.AttachmentURLSecret,
+		URLTTL:    cfg.AttachmentURLTTL,
+		PublicURL: cfg.PublicURL,
+	})
+	log.Printf("Attachment storage initialized (backend: %s)", cfg.StorageBackend)
+
+	// Sign proof-of-work challenges for public submissions
+	services.InitProofOfWork(services.ProofOfWorkConfig{
+		Secret: cfg.ChallengeSecret,
+		TTL:    cfg.ChallengeTTL,
+	})
+
+	// Start image attachment processing workers
+	services.StartAttachmentProcessor(services.AttachmentProcessorConfig{
+		Workers:       cfg.AttachmentWorkers,
+		MaxAttempts:   cfg.AttachmentMaxAttempts,
+		ThumbnailSize: cfg.ThumbnailSize,
+	})
+
+	// Start webhook delivery workers
+	services.StartWebhookDispatcher(services.WebhookDispatcherConfig{
+		Workers:      cfg.WebhookWorkers,
+		Timeout:      cfg.WebhookTimeout,
+		MaxAttempts:  cfg.WebhookMaxAttempts,
+		RetryDelay:   cfg.WebhookRetryDelay,
+		PollInterval: cfg.WebhookPollInterval,
+		Retention:    cfg.WebhookRetention,
+	})
+
 	// Setup router with auth
-	router := handlers.SetupRouter(cfg, enforcer)
+	router := handlers.SetupRouter(cfg, enforcer, jwtKeys)
 
 	// Start server
 	addr := ":" + cfg.Port
@@ -65,3 +139,42 @@ func main() {
 		log.Fatalf("Server failed to start: %v", err)
 	}
 }
+
+// runCommand runs an admin command given on the command line instead of starting the server
+func runCommand(args []string) error {
+	switch args[0] {
+	case "promote-admin":
+		if len(args) != 2 {
+			return fmt.Errorf("usage: promote-admin <email>")
+		}
+		if err := services.PromoteAdmin(context.Background(), args[1]); err != nil {
+			if err == services.ErrUserNotFound {
+				return fmt.Errorf("no user with email %s - they need to sign in once first, or set BOOTSTRAP_ADMIN_EMAIL", args[1])
+			}
+			return err
+		}
+		log.Printf("Promoted %s to admin", args[1])
+		return nil
+	default:
+		return fmt.Errorf("unknown command (available: promote-admin)")
+	}
+}
+
+// newStorage builds the attachment storage backend selected by STORAGE_BACKEND
+func newStorage(cfg *config.Config) (storage.Storage, error) {
+	switch cfg.StorageBackend {
+	case "local":
+		return storage.NewLocalStorage(cfg.AttachmentsDir)
+	case "s3":
+		return storage.NewS3Storage(storage.S3Config{
+			Endpoint:        cfg.S3Endpoint,
+			Region:          cfg.S3Region,
+			Bucket:          cfg.S3Bucket,
+			AccessKeyID:     cfg.S3AccessKeyID,
+			SecretAccessKey: cfg.S3SecretAccessKey,
+			PathStyle:       cfg.S3ForcePathStyle,
+		})
+	default:
+		return nil, fmt.Errorf("unknown storage backend %q", cfg.StorageBackend)
+	}
+}
@@ -2,20 +2,54 @@ package middleware
 
 import (
 	"context"
+	"crypto/subtle"
 	"database/sql"
+	"log"
 	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/frallan97/feedback-service/backend/database"
+	"github.com/frallan97/feedback-service/backend/services"
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 )
 
 type appContextKey string
 
-const AppIDKey appContextKey = "appID"
+const (
+	AppIDKey       appContextKey = "appID"
+	APIKeyIDKey    appContextKey = "apiKeyID"
+	APIKeyScopeKey appContextKey = "apiKeyScopes"
+)
+
+// publicExposedHeaders are the response headers browsers let client applications read
+const publicExposedHeaders = "Deprecation, Sunset, " + RequestIDHeader + ", RateLimit-Limit, RateLimit-Remaining, RateLimit-Reset, Retry-After, " + IdempotentReplayedHeader
+
+// lastUsedResolution limits how often a key's last_used_at is written
+const lastUsedResolution = time.Minute
+
+// apiKeyRecord is a stored API key matching a presented key's prefix
+type apiKeyRecord struct {
+	id        uuid.UUID
+	appID     uuid.UUID
+	appActive bool
+	scopes    []string
+	expiresAt *time.Time
+	revokedAt *time.Time
+	rotatedAt *time.Time
+	origins   []string
+}
 
-// AppAuth middleware validates API key and sets application ID in context
+// AppAuth middleware validates API key and sets application ID and the key's scopes in context.
+// Requests with an X-Feedback-Signature header are authenticated by their HMAC signature instead.
 func AppAuth(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get(services.RequestSignatureHeader) != "" {
+			signedRequest(next, w, r)
+			return
+		}
+
 		// Try to get API key from header first, then fall back to query param
 		apiKey := r.Header.Get("X-API-Key")
 		if apiKey == "" {
@@ -27,14 +61,8 @@ func AppAuth(next http.Handler) http.Handler {
 			return
 		}
 
-		// Validate API key and get application
-		var appID uuid.UUID
-		var isActive bool
-		err := database.DB.QueryRowContext(r.Context(),
-			"SELECT id, is_active FROM applications WHERE api_key = $1",
-			apiKey,
-		).Scan(&appID, &isActive)
-
+		// Look up the key by its visible prefix, then compare hashes
+		key, err := lookupAPIKey(r.Context(), apiKey)
 		if err == sql.ErrNoRows {
 			http.Error(w, `{"error":"Invalid API key"}`, http.StatusUnauthorized)
 			return
@@ -44,19 +72,124 @@ func AppAuth(next http.Handler) http.Handler {
 			return
 		}
 
-		if !isActive {
+		if key.revokedAt != nil {
+			http.Error(w, `{"error":"API key has been revoked"}`, http.StatusUnauthorized)
+			return
+		}
+		if key.expiresAt != nil && !key.expiresAt.After(time.Now()) {
+			http.Error(w, `{"error":"API key has expired"}`, http.StatusUnauthorized)
+			return
+		}
+
+		if !key.appActive {
 			http.Error(w, `{"error":"Application is inactive"}`, http.StatusForbidden)
 			return
 		}
 
-		// Set application ID in context
-		ctx := context.WithValue(r.Context(), AppIDKey, appID)
+		// Browsers send Origin on cross-site requests; only the application's own sites may
+		// use its key from a browser. Requests without Origin come from servers and apps.
+		if origin := r.Header.Get("Origin"); origin != "" {
+			if !OriginAllowed(origin, key.origins) {
+				log.Printf("[AUTH] Rejected request from origin %s for application %s", origin, key.appID)
+				http.Error(w, `{"error":"Origin not allowed for this application"}`, http.StatusForbidden)
+				return
+			}
+			w.Header().Set("Access-Control-Allow-Origin", origin)
+			w.Header().Set("Access-Control-Expose-Headers", publicExposedHeaders)
+		}
+
+		touchAPIKey(r.Context(), key.id)
+
+		// Rotated keys work until their grace period ends; tell clients to switch
+		if key.rotatedAt != nil {
+			w.Header().Set("Deprecation", "@"+strconv.FormatInt(key.rotatedAt.Unix(), 10))
+			if key.expiresAt != nil {
+				w.Header().Set("Sunset", key.expiresAt.UTC().Format(http.TimeFormat))
+			}
+		}
+
+		// Set application ID and key in context
+		ctx := context.WithValue(r.Context(), AppIDKey, key.appID)
+		ctx = context.WithValue(ctx, APIKeyIDKey, key.id)
+		ctx = context.WithValue(ctx, APIKeyScopeKey, key.scopes)
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
 
+// RequireScope is a middleware for AppAuth routes that rejects API keys without scope
+func RequireScope(scope string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if HasScope(r.Context(), scope) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			http.Error(w, `{"error":"API key is missing the `+scope+` scope"}`, http.StatusForbidden)
+		})
+	}
+}
+
+// lookupAPIKey returns the stored key matching a presented API key, or sql.ErrNoRows
+func lookupAPIKey(ctx context.Context, apiKey string) (*apiKeyRecord, error) {
+	rows, err := database.DB.QueryContext(ctx, `
+		SELECT k.id, k.application_id, a.is_active, k.key_hash, k.scopes, k.expires_at, k.revoked_at, k.rotated_at, a.allowed_origins
+		FROM api_keys k
+		JOIN applications a ON a.id = k.application_id
+		WHERE k.key_prefix = $1
+	`, services.APIKeyPrefix(apiKey))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	hash := []byte(services.HashAPIKey(apiKey))
+	for rows.Next() {
+		var key apiKeyRecord
+		var storedHash string
+		if err := rows.Scan(&key.id, &key.appID, &key.appActive, &storedHash, pq.Array(&key.scopes), &key.expiresAt, &key.revokedAt, &key.rotatedAt, pq.Array(&key.origins)); err != nil {
+			return nil, err
+		}
+		if subtle.ConstantTimeCompare(hash, []byte(storedHash)) == 1 {
+			return &key, nil
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return nil, sql.ErrNoRows
+}
+
+// touchAPIKey records that a key was used, at most once per lastUsedResolution
+func touchAPIKey(ctx context.Context, keyID uuid.UUID) {
+	_, err := database.DB.ExecContext(ctx, `
+		UPDATE api_keys SET last_used_at = NOW()
+		WHERE id = $1 AND (last_used_at IS NULL OR last_used_at < NOW() - make_interval(secs => $2))
+	`, keyID, lastUsedResolution.Seconds())
+	if err != nil {
+		log.Printf("[AUTH] Failed to record use of API key %s: %v", keyID, err)
+	}
+}
+
 // GetAppID retrieves the application ID from the request context
 func GetAppID(ctx context.Context) (uuid.UUID, bool) {
 	appID, ok := ctx.Value(AppIDKey).(uuid.UUID)
 	return appID, ok
 }
+
+// GetAPIKeyID retrieves the ID of the API key a request authenticated with
+func GetAPIKeyID(ctx context.Context) (uuid.UUID, bool) {
+	keyID, ok := ctx.Value(APIKeyIDKey).(uuid.UUID)
+	return keyID, ok
+}
+
+// HasScope reports whether the request's API key was granted scope
+func HasScope(ctx context.Context, scope string) bool {
+	scopes, _ := ctx.Value(APIKeyScopeKey).([]string)
+	for _, s := range scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
@@ -2,11 +2,11 @@ package middleware
 
 import (
 	"context"
-	"crypto/rsa"
 	"log"
 	"net/http"
 	"strings"
 
+	"github.com/frallan97/feedback-service/backend/models"
 	customJWT "github.com/frallan97/feedback-service/backend/pkg/jwt"
 	"github.com/frallan97/feedback-service/backend/services"
 	"github.com/google/uuid"
@@ -21,37 +21,58 @@ const (
 	UserClaimsKey contextKey = "userClaims"
 )
 
-// Claims represents user claims from JWT token
+// Claims represents user claims from JWT token or personal access token
 type Claims struct {
 	UserID uuid.UUID
 	Email  string
 	Name   string
 	Role   string
+
+	// Scopes restricts a personal access token to part of the admin API; nil for JWT sessions
+	Scopes []string
+
+	// TokenID is the personal access token the request authenticated with, uuid.Nil for JWT sessions
+	TokenID uuid.UUID
 }
 
-// Auth is a middleware that validates JWT tokens and adds user info to context
-func Auth(publicKey *rsa.PublicKey) func(http.Handler) http.Handler {
+// Auth is a middleware that validates JWT tokens, or personal access tokens, and adds user info to context
+func Auth(keys *customJWT.KeySet) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			authHeader := r.Header.Get("Authorization")
-			if authHeader == "" {
+			if r.Header.Get("Authorization") == "" {
 				http.Error(w, "Missing authorization header", http.StatusUnauthorized)
 				return
 			}
 
-			parts := strings.SplitN(authHeader, " ", 2)
-			if len(parts) != 2 || parts[0] != "Bearer" {
+			tokenString, ok := BearerToken(r)
+			if !ok {
 				http.Error(w, "Invalid authorization header format", http.StatusUnauthorized)
 				return
 			}
 
-			tokenString := parts[1]
-			claims, err := customJWT.ValidateAccessToken(tokenString, publicKey)
+			if services.IsPersonalAccessToken(tokenString) {
+				personalAccessTokenAuth(next, w, r, tokenString)
+				return
+			}
+
+			claims, err := customJWT.ValidateAccessToken(tokenString, keys)
 			if err != nil {
 				http.Error(w, "Invalid or expired token", http.StatusUnauthorized)
 				return
 			}
 
+			// Reject tokens revoked by logout. Fail closed: a revoked token must never slip through.
+			revoked, err := services.IsTokenRevoked(r.Context(), claims.TokenID(tokenString))
+			if err != nil {
+				log.Printf("[AUTH] %v", err)
+				http.Error(w, "Unable to verify token", http.StatusServiceUnavailable)
+				return
+			}
+			if revoked {
+				http.Error(w, "Token has been revoked", http.StatusUnauthorized)
+				return
+			}
+
 			// Sync user to local database (create or update)
 			user, err := services.CreateOrUpdateUser(r.Context(), claims.UserID, claims.Email, claims.Name)
 			if err != nil {
@@ -66,10 +87,10 @@ func Auth(publicKey *rsa.PublicKey) func(http.Handler) http.Handler {
 				return
 			}
 
-			// Determine role from user or default to "user"
-			role := "user"
-			if user != nil && user.IsAdmin {
-				role = "admin"
+			// Determine role from user, falling back to the least privileged role
+			role := models.RoleViewer
+			if user != nil {
+				role = user.Role
 			}
 
 			// Create claims struct
@@ -80,16 +101,28 @@ func Auth(publicKey *rsa.PublicKey) func(http.Handler) http.Handler {
 				Role:   role,
 			}
 
-			ctx := context.WithValue(r.Context(), UserIDKey, claims.UserID)
-			ctx = context.WithValue(ctx, EmailKey, claims.Email)
-			ctx = context.WithValue(ctx, NameKey, claims.Name)
-			ctx = context.WithValue(ctx, UserClaimsKey, userClaims)
-
-			next.ServeHTTP(w, r.WithContext(ctx))
+			next.ServeHTTP(w, r.WithContext(withUserClaims(r.Context(), userClaims)))
 		})
 	}
 }
 
+// withUserClaims adds an authenticated user's claims to a context
+func withUserClaims(ctx context.Context, claims *Claims) context.Context {
+	ctx = context.WithValue(ctx, UserIDKey, claims.UserID)
+	ctx = context.WithValue(ctx, EmailKey, claims.Email)
+	ctx = context.WithValue(ctx, NameKey, claims.Name)
+	return context.WithValue(ctx, UserClaimsKey, claims)
+}
+
+// BearerToken returns the token from a request's "Authorization: Bearer" header
+func BearerToken(r *http.Request) (string, bool) {
+	parts := strings.SplitN(r.Header.Get("Authorization"), " ", 2)
+	if len(parts) != 2 || parts[0] != "Bearer" || parts[1] == "" {
+		return "", false
+	}
+	return parts[1], true
+}
+
 // GetUserID extracts user ID from request context
 func GetUserID(ctx context.Context) (uuid.UUID, bool) {
 	userID, ok := ctx.Value(UserIDKey).(uuid.UUID)
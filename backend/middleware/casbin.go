@@ -1,27 +1,86 @@
 package middleware
 
 import (
+	"context"
+	"database/sql"
+	"log"
 	"net/http"
+	"strings"
 
 	"github.com/casbin/casbin/v2"
+	"github.com/frallan97/feedback-service/backend/models"
+	"github.com/frallan97/feedback-service/backend/services"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
 )
 
-// Authorize is a middleware that checks authorization using Casbin
-func Authorize(enforcer *casbin.Enforcer) func(http.Handler) http.Handler {
+const RoleKey contextKey = "role"
+
+// Authorize is a middleware that checks authorization using Casbin.
+//
+// Requests for a single application's data (/applications/{id}/..., /feedback/{id}/...)
+// are only allowed for members of that application, and are checked with the higher of
+// the user's global role and their role in the application. Global admins can access
+// every application. Other requests are checked with the global role, and controllers
+// scope the results to the user's applications.
+func Authorize(enforcer *casbin.SyncedEnforcer) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			// Get user ID from context (set by Auth middleware)
-			_, ok := GetUserID(r.Context())
+			// Get user claims from context (set by Auth middleware)
+			claims, ok := GetUserClaims(r.Context())
 			if !ok {
 				http.Error(w, "User not authenticated", http.StatusUnauthorized)
 				return
 			}
 
+			// Personal access tokens only reach the parts of the API they were scoped to
+			if claims.Scopes != nil {
+				scope := RequiredTokenScope(r.URL.Path, r.Method)
+				if !HasTokenScope(claims.Scopes, scope) {
+					if scope == "" {
+						http.Error(w, "Forbidden: personal access tokens can't be used here", http.StatusForbidden)
+					} else {
+						http.Error(w, "Forbidden: token is missing the "+scope+" scope", http.StatusForbidden)
+					}
+					return
+				}
+			}
+
+			ctx := r.Context()
+			role := claims.Role
+
+			appID, scoped, err := requestApplicationID(r)
+			if err == sql.ErrNoRows {
+				http.Error(w, "Not found", http.StatusNotFound)
+				return
+			}
+			if err != nil {
+				log.Printf("[AUTHZ] Failed to resolve application for %s: %v", r.URL.Path, err)
+				http.Error(w, "Authorization error", http.StatusInternalServerError)
+				return
+			}
+
+			if scoped && role != models.RoleAdmin {
+				memberRole, err := services.GetApplicationRole(ctx, appID, claims.UserID)
+				if err != nil {
+					log.Printf("[AUTHZ] %v", err)
+					http.Error(w, "Authorization error", http.StatusInternalServerError)
+					return
+				}
+				// Don't reveal that applications the user can't access exist
+				if memberRole == "" {
+					http.Error(w, "Not found", http.StatusNotFound)
+					return
+				}
+				role = models.HigherRole(role, memberRole)
+			}
+
 			// Check authorization with Casbin
-			// Subject: "user" role (all authenticated users)
+			// Subject: user ID (for per-user grants via "g" rules)
+			// Role: the user's effective role (viewer, triager, owner or admin)
 			// Object: request path
 			// Action: HTTP method
-			allowed, err := enforcer.Enforce("user", r.URL.Path, r.Method)
+			allowed, err := enforcer.Enforce(claims.UserID.String(), role, r.URL.Path, r.Method)
 			if err != nil {
 				http.Error(w, "Authorization error", http.StatusInternalServerError)
 				return
@@ -32,7 +91,58 @@ func Authorize(enforcer *casbin.Enforcer) func(http.Handler) http.Handler {
 				return
 			}
 
-			next.ServeHTTP(w, r)
+			ctx = context.WithValue(ctx, RoleKey, role)
+			next.ServeHTTP(w, r.WithContext(ctx))
 		})
 	}
 }
+
+// requestApplicationID returns the application a request's route is scoped to.
+// It returns sql.ErrNoRows if the route refers to a feedback item or application that
+// can't exist (malformed ID) or a feedback item that doesn't.
+func requestApplicationID(r *http.Request) (uuid.UUID, bool, error) {
+	route := mux.CurrentRoute(r)
+	if route == nil {
+		return uuid.Nil, false, nil
+	}
+	template, err := route.GetPathTemplate()
+	if err != nil {
+		return uuid.Nil, false, nil
+	}
+	vars := mux.Vars(r)
+
+	switch {
+	case strings.HasPrefix(template, "/api/v1/applications/{app_id}"):
+		return parseScopedID(vars["app_id"])
+	case strings.HasPrefix(template, "/api/v1/applications/{id}"):
+		return parseScopedID(vars["id"])
+	case strings.HasPrefix(template, "/api/v1/feedback/{id}"):
+		feedbackID, _, err := parseScopedID(vars["id"])
+		if err != nil {
+			return uuid.Nil, true, err
+		}
+		appID, err := services.GetFeedbackApplicationID(r.Context(), feedbackID)
+		return appID, true, err
+	}
+	return uuid.Nil, false, nil
+}
+
+func parseScopedID(value string) (uuid.UUID, bool, error) {
+	id, err := uuid.Parse(value)
+	if err != nil {
+		return uuid.Nil, true, sql.ErrNoRows
+	}
+	return id, true, nil
+}
+
+// GetRole returns the user's effective role for the request: the role Authorize enforced with,
+// or the global role outside authorized routes
+func GetRole(ctx context.Context) string {
+	if role, ok := ctx.Value(RoleKey).(string); ok {
+		return role
+	}
+	if claims, ok := GetUserClaims(ctx); ok {
+		return claims.Role
+	}
+	return ""
+}
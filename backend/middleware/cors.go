@@ -1,23 +1,98 @@
 package middleware
 
-import "net/http"
-
-// CORS middleware adds CORS headers to allow frontend to communicate with backend
-func CORS(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		// Allow requests from any origin in development
-		// TODO: Restrict this in production
-		w.Header().Set("Access-Control-Allow-Origin", "*")
-		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, PATCH, DELETE, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
-		w.Header().Set("Access-Control-Max-Age", "3600")
-
-		// Handle preflight requests
-		if r.Method == "OPTIONS" {
-			w.WriteHeader(http.StatusNoContent)
-			return
+import (
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// publicPathPrefix is where the API-key routes used by client applications live
+const publicPathPrefix = "/api/v1/public/"
+
+// CORS middleware adds CORS headers for the dashboard origins in allowedOrigins.
+//
+// Public API routes are called from client applications' own sites, so their preflight
+// requests are answered for any origin: a preflight carries no API key to identify the
+// application. AppAuth then checks the actual request's Origin against the application's
+// allowed origins and adds the CORS headers itself.
+func CORS(allowedOrigins []string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			origin := r.Header.Get("Origin")
+			w.Header().Add("Vary", "Origin")
+
+			if strings.HasPrefix(r.URL.Path, publicPathPrefix) {
+				if r.Method == "OPTIONS" {
+					if origin != "" {
+						w.Header().Set("Access-Control-Allow-Origin", origin)
+					}
+					w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
+					w.Header().Set("Access-Control-Allow-Headers", "Content-Type, X-API-Key, X-User-Token, "+IdempotencyKeyHeader)
+					w.Header().Set("Access-Control-Max-Age", "3600")
+					w.WriteHeader(http.StatusNoContent)
+					return
+				}
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if origin != "" && OriginAllowed(origin, allowedOrigins) {
+				w.Header().Set("Access-Control-Allow-Origin", origin)
+				w.Header().Set("Access-Control-Allow-Credentials", "true")
+				w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, PATCH, DELETE, OPTIONS")
+				w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+				w.Header().Set("Access-Control-Expose-Headers", RequestIDHeader)
+				w.Header().Set("Access-Control-Max-Age", "3600")
+			}
+
+			// Handle preflight requests
+			if r.Method == "OPTIONS" {
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// OriginAllowed reports whether origin matches an entry in allowed. Entries are exact
+// origins ("https://example.com"), "*" for any origin, or a subdomain wildcard
+// ("https://*.example.com").
+func OriginAllowed(origin string, allowed []string) bool {
+	origin = strings.ToLower(strings.TrimSuffix(origin, "/"))
+	for _, entry := range allowed {
+		entry = strings.ToLower(strings.TrimSuffix(entry, "/"))
+		switch {
+		case entry == "*":
+			return true
+		case entry == origin:
+			return true
+		case strings.Contains(entry, "://*."):
+			scheme, host, _ := strings.Cut(entry, "://*")
+			if strings.HasPrefix(origin, scheme+"://") && strings.HasSuffix(origin, host) &&
+				len(origin) > len(scheme)+3+len(host) {
+				return true
+			}
 		}
+	}
+	return false
+}
+
+// NormalizeOrigin validates an allowed-origins entry and returns it in canonical form.
+// It accepts "*", "scheme://host[:port]" and "scheme://*.host[:port]".
+func NormalizeOrigin(entry string) (string, bool) {
+	entry = strings.TrimSpace(entry)
+	if entry == "*" {
+		return entry, true
+	}
+
+	u, err := url.Parse(strings.Replace(entry, "://*.", "://wildcard.", 1))
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" ||
+		(u.Path != "" && u.Path != "/") || u.RawQuery != "" || u.Fragment != "" || u.User != nil {
+		return "", false
+	}
 
-		next.ServeHTTP(w, r)
-	})
+	normalized := strings.ToLower(u.Scheme + "://" + u.Host)
+	return strings.Replace(normalized, "://wildcard.", "://*.", 1), true
 }
@@ -0,0 +1,117 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"log"
+	"net/http"
+
+	"github.com/frallan97/feedback-service/backend/services"
+)
+
+// Headers for making public API requests safe to retry
+const (
+	IdempotencyKeyHeader     = "Idempotency-Key"
+	IdempotentReplayedHeader = "Idempotent-Replayed"
+)
+
+const (
+	maxIdempotencyKeyLength = 255
+
+	// maxIdempotentRequestBytes bounds the request bodies read to compare retries
+	maxIdempotentRequestBytes = 1 << 20
+)
+
+// recordingResponseWriter keeps a copy of the response it writes
+type recordingResponseWriter struct {
+	responseWriter
+	body bytes.Buffer
+}
+
+func (rw *recordingResponseWriter) Write(b []byte) (int, error) {
+	rw.body.Write(b)
+	return rw.ResponseWriter.Write(b)
+}
+
+// Idempotency middleware makes a public API route safe to retry. The first request with an
+// Idempotency-Key header is handled and its successful response stored for
+// services.IdempotencyKeyTTL; retries with the same key and body get that response again,
+// with an Idempotent-Replayed header. Reusing a key for a different body, or while the
+// first request is still being handled, is a 409. Keys are scoped to the application, so
+// this must run after AppAuth. Failed requests don't keep the key.
+func Idempotency(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get(IdempotencyKeyHeader)
+		appID, ok := GetAppID(r.Context())
+		if key == "" || !ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if !validIdempotencyKey(key) {
+			http.Error(w, `{"error":"Idempotency-Key must be 1 to 255 printable ASCII characters"}`, http.StatusBadRequest)
+			return
+		}
+
+		body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxIdempotentRequestBytes))
+		if err != nil {
+			http.Error(w, `{"error":"Invalid request body"}`, http.StatusBadRequest)
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+		sum := sha256.Sum256(body)
+		requestHash := hex.EncodeToString(sum[:])
+
+		claimID, held, err := services.ClaimIdempotencyKey(r.Context(), appID, key, requestHash)
+		if err != nil {
+			// Handle the request rather than fail it; a retry may then create a duplicate
+			log.Printf("[IDEMPOTENCY] %v", err)
+			next.ServeHTTP(w, r)
+			return
+		}
+		if held != nil {
+			switch {
+			case held.RequestHash != requestHash:
+				http.Error(w, `{"error":"Idempotency-Key was already used for a different request"}`, http.StatusConflict)
+			case held.StatusCode == 0:
+				http.Error(w, `{"error":"A request with this Idempotency-Key is still being processed"}`, http.StatusConflict)
+			default:
+				w.Header().Set("Content-Type", "application/json")
+				w.Header().Set(IdempotentReplayedHeader, "true")
+				w.WriteHeader(held.StatusCode)
+				w.Write(held.Response)
+			}
+			return
+		}
+
+		recorder := &recordingResponseWriter{responseWriter: responseWriter{ResponseWriter: w, statusCode: http.StatusOK}}
+		next.ServeHTTP(recorder, r)
+
+		// Clients retry when the connection drops, so store the response even if this
+		// request's client has gone
+		ctx := context.WithoutCancel(r.Context())
+		if recorder.statusCode >= 200 && recorder.statusCode < 300 {
+			if err := services.CompleteIdempotencyKey(ctx, appID, key, claimID, recorder.statusCode, recorder.body.Bytes()); err != nil {
+				log.Printf("[IDEMPOTENCY] %v", err)
+				services.ReleaseIdempotencyKey(ctx, appID, key, claimID)
+			}
+			return
+		}
+		services.ReleaseIdempotencyKey(ctx, appID, key, claimID)
+	})
+}
+
+// validIdempotencyKey reports whether key is 1 to maxIdempotencyKeyLength printable ASCII characters
+func validIdempotencyKey(key string) bool {
+	if len(key) == 0 || len(key) > maxIdempotencyKeyLength {
+		return false
+	}
+	for i := 0; i < len(key); i++ {
+		if key[i] < 0x20 || key[i] > 0x7e {
+			return false
+		}
+	}
+	return true
+}
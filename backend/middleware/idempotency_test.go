@@ -0,0 +1,221 @@
+package middleware
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql/driver"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/frallan97/feedback-service/backend/database"
+	"github.com/google/uuid"
+)
+
+const idempotencyTestBody = `{"content":"The export button does nothing"}`
+
+// mockDatabase replaces database.DB with a sqlmock for the test
+func mockDatabase(t *testing.T) sqlmock.Sqlmock {
+	t.Helper()
+
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	previous := database.DB
+	database.DB = db
+	t.Cleanup(func() {
+		database.DB = previous
+		db.Close()
+	})
+	return mock
+}
+
+var idempotencyPurgeOnce sync.Once
+
+// expectIdempotencyPurge expects the purge of expired keys the first claim in the test
+// binary runs; later claims within a minute skip it
+func expectIdempotencyPurge(mock sqlmock.Sqlmock) {
+	idempotencyPurgeOnce.Do(func() {
+		mock.ExpectExec(`DELETE FROM idempotency_keys WHERE expires_at < NOW\(\)`).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+	})
+}
+
+// sameValue matches the first value it sees, and after that only equal values
+type sameValue struct {
+	seen  bool
+	value driver.Value
+}
+
+func (s *sameValue) Match(v driver.Value) bool {
+	if !s.seen {
+		s.seen, s.value = true, v
+		return true
+	}
+	return v == s.value
+}
+
+func idempotencyTestHash(body string) string {
+	sum := sha256.Sum256([]byte(body))
+	return hex.EncodeToString(sum[:])
+}
+
+// serveIdempotent sends body with Idempotency-Key key through Idempotency in front of a
+// handler answering 201, and reports whether the handler ran
+func serveIdempotent(appID uuid.UUID, key, body string) (*httptest.ResponseRecorder, bool) {
+	called := false
+	handler := Idempotency(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"id":"new"}`))
+	}))
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/public/feedback", strings.NewReader(body))
+	req.Header.Set(IdempotencyKeyHeader, key)
+	req = req.WithContext(context.WithValue(req.Context(), AppIDKey, appID))
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	return rec, called
+}
+
+// expectHeldKey expects a claim that finds key held, and returns the holder's columns
+func expectHeldKey(mock sqlmock.Sqlmock, appID uuid.UUID, key, requestHash string, statusCode interface{}, response []byte) {
+	expectIdempotencyPurge(mock)
+	mock.ExpectQuery(`INSERT INTO idempotency_keys`).
+		WillReturnRows(sqlmock.NewRows([]string{"bool"}))
+	mock.ExpectQuery(`SELECT request_hash, status_code, response FROM idempotency_keys`).
+		WithArgs(appID, key).
+		WillReturnRows(sqlmock.NewRows([]string{"request_hash", "status_code", "response"}).
+			AddRow(requestHash, statusCode, response))
+}
+
+func TestIdempotencyReplaysStoredResponse(t *testing.T) {
+	mock := mockDatabase(t)
+	appID := uuid.New()
+
+	expectHeldKey(mock, appID, "retry-1", idempotencyTestHash(idempotencyTestBody), 201, []byte(`{"id":"first"}`))
+
+	rec, called := serveIdempotent(appID, "retry-1", idempotencyTestBody)
+
+	if called {
+		t.Error("handler ran for a replayed request")
+	}
+	if rec.Code != http.StatusCreated {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusCreated)
+	}
+	if got := rec.Body.String(); got != `{"id":"first"}` {
+		t.Errorf("body = %q, want the stored response", got)
+	}
+	if rec.Header().Get(IdempotentReplayedHeader) != "true" {
+		t.Errorf("%s header not set", IdempotentReplayedHeader)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestIdempotencyRejectsKeyReusedForDifferentBody(t *testing.T) {
+	mock := mockDatabase(t)
+	appID := uuid.New()
+
+	expectHeldKey(mock, appID, "retry-2", idempotencyTestHash(`{"content":"something else"}`), 201, []byte(`{"id":"first"}`))
+
+	rec, called := serveIdempotent(appID, "retry-2", idempotencyTestBody)
+
+	if called {
+		t.Error("handler ran for a reused key")
+	}
+	if rec.Code != http.StatusConflict {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusConflict)
+	}
+	if !strings.Contains(rec.Body.String(), "different request") {
+		t.Errorf("body = %q", rec.Body.String())
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestIdempotencyRejectsKeyInFlight(t *testing.T) {
+	mock := mockDatabase(t)
+	appID := uuid.New()
+
+	expectHeldKey(mock, appID, "retry-3", idempotencyTestHash(idempotencyTestBody), nil, nil)
+
+	rec, called := serveIdempotent(appID, "retry-3", idempotencyTestBody)
+
+	if called {
+		t.Error("handler ran while the key was in flight")
+	}
+	if rec.Code != http.StatusConflict {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusConflict)
+	}
+	if !strings.Contains(rec.Body.String(), "still being processed") {
+		t.Errorf("body = %q", rec.Body.String())
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestIdempotencyTakesOverStaleClaim(t *testing.T) {
+	mock := mockDatabase(t)
+	appID := uuid.New()
+	hash := idempotencyTestHash(idempotencyTestBody)
+	claimID := &sameValue{}
+
+	// The claim succeeds over a key whose request has held it longer than the lock timeout,
+	// and the response is stored only under the new claim
+	// time.Minute is services' lock timeout
+	expectIdempotencyPurge(mock)
+	mock.ExpectQuery(`ON CONFLICT \(application_id, key\) DO UPDATE\s+SET claim_id = EXCLUDED.claim_id.*status_code IS NULL AND idempotency_keys.created_at < NOW\(\) - make_interval\(secs => \$6\)`).
+		WithArgs(appID, "retry-4", claimID, hash, sqlmock.AnyArg(), time.Minute.Seconds()).
+		WillReturnRows(sqlmock.NewRows([]string{"bool"}).AddRow(true))
+	mock.ExpectExec(`UPDATE idempotency_keys SET status_code = \$1, response = \$2 WHERE application_id = \$3 AND key = \$4 AND claim_id = \$5`).
+		WithArgs(http.StatusCreated, []byte(`{"id":"new"}`), appID, "retry-4", claimID).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	rec, called := serveIdempotent(appID, "retry-4", idempotencyTestBody)
+
+	if !called {
+		t.Error("handler didn't run after taking over the key")
+	}
+	if rec.Code != http.StatusCreated {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusCreated)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestIdempotencyReleasesOnlyItsOwnClaim(t *testing.T) {
+	mock := mockDatabase(t)
+	appID := uuid.New()
+	claimID := &sameValue{}
+
+	expectIdempotencyPurge(mock)
+	mock.ExpectQuery(`INSERT INTO idempotency_keys`).
+		WithArgs(appID, "retry-5", claimID, sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"bool"}).AddRow(true))
+	mock.ExpectExec(`DELETE FROM idempotency_keys WHERE application_id = \$1 AND key = \$2 AND claim_id = \$3 AND status_code IS NULL`).
+		WithArgs(appID, "retry-5", claimID).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	handler := Idempotency(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"error":"Failed to create feedback"}`, http.StatusInternalServerError)
+	}))
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/public/feedback", strings.NewReader(idempotencyTestBody))
+	req.Header.Set(IdempotencyKeyHeader, "retry-5")
+	req = req.WithContext(context.WithValue(req.Context(), AppIDKey, appID))
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
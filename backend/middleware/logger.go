@@ -34,11 +34,12 @@ func Logger(next http.Handler) http.Handler {
 		// Log request details
 		duration := time.Since(start)
 		log.Printf(
-			"%s %s %d %v",
+			"%s %s %d %v %s",
 			r.Method,
 			r.RequestURI,
 			wrapped.statusCode,
 			duration,
+			GetRequestID(r.Context()),
 		)
 	})
 }
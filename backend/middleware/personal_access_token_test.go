@@ -0,0 +1,42 @@
+package middleware
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestRequiredTokenScope(t *testing.T) {
+	const appID = "7c1f4a2e-5b7d-4c39-9a51-0e8f2d6b3c10"
+
+	tests := []struct {
+		method string
+		path   string
+		want   string
+	}{
+		{http.MethodGet, "/api/v1/feedback", "feedback:read"},
+		{http.MethodPatch, "/api/v1/feedback/1", "feedback:write"},
+		{http.MethodGet, "/api/v1/applications/" + appID, "applications:read"},
+		{http.MethodGet, "/api/v1/applications/" + appID + "/webhooks/endpoints", "applications:read"},
+		{http.MethodGet, "/api/v1/applications/" + appID + "/identity", "applications:write"},
+		{http.MethodHead, "/api/v1/applications/" + appID + "/signing-secret", "applications:write"},
+		{http.MethodGet, "/api/v1/applications/" + appID + "/webhooks/endpoints/1", "applications:write"},
+		{http.MethodGet, "/api/v1/audit", "audit:read"},
+		{http.MethodGet, "/api/v1/auth/tokens", ""},
+	}
+
+	for _, tt := range tests {
+		if got := RequiredTokenScope(tt.path, tt.method); got != tt.want {
+			t.Errorf("RequiredTokenScope(%q, %s) = %q, want %q", tt.path, tt.method, got, tt.want)
+		}
+	}
+}
+
+func TestReadScopeCantReadSecrets(t *testing.T) {
+	scope := RequiredTokenScope("/api/v1/applications/1/signing-secret", http.MethodGet)
+	if HasTokenScope([]string{"applications:read"}, scope) {
+		t.Error("applications:read grants a signing secret")
+	}
+	if !HasTokenScope([]string{"applications:write"}, scope) {
+		t.Error("applications:write doesn't grant a signing secret")
+	}
+}
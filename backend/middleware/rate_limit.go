@@ -0,0 +1,86 @@
+package middleware
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/frallan97/feedback-service/backend/services"
+)
+
+// RateLimit middleware limits public API requests per API key and per client IP, using the
+// application's limits; it must run after AppAuth. Every response carries RateLimit-Limit,
+// RateLimit-Remaining and RateLimit-Reset for the tightest limit, and requests over a limit
+// get a 429 with Retry-After; rejections raise the application's proof-of-work difficulty.
+// If the counters can't be reached, requests are let through.
+func RateLimit(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		appID, ok := GetAppID(ctx)
+		if !ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		limits, err := services.GetApplicationRateLimits(ctx, appID)
+		if err != nil {
+			log.Printf("[RATELIMIT] %v", err)
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		// Signed requests come from the application's own servers, so they share one bucket
+		// per application and aren't limited by IP
+		buckets := map[string]int{}
+		keyID, hasKey := GetAPIKeyID(ctx)
+		if limits.PerKey > 0 {
+			if hasKey {
+				buckets["key:"+keyID.String()] = limits.PerKey
+			} else {
+				buckets["signed:"+appID.String()] = limits.PerKey
+			}
+		}
+		if ip := GetClientIP(ctx); hasKey && limits.PerIP > 0 && ip != "" {
+			buckets[services.IPRateLimitBucket(appID, ip)] = limits.PerIP
+		}
+		if len(buckets) == 0 {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		counts, err := services.CountRateLimitedRequest(ctx, buckets)
+		if err != nil || len(counts) == 0 {
+			log.Printf("[RATELIMIT] Failed to count request for application %s: %v", appID, err)
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		tightest := counts[0]
+		var exceeded *services.RateLimitCount
+		for i, c := range counts {
+			if c.Remaining() < tightest.Remaining() {
+				tightest = c
+			}
+			if c.Exceeded() && (exceeded == nil || c.Reset > exceeded.Reset) {
+				exceeded = &counts[i]
+			}
+		}
+		if exceeded != nil {
+			tightest = *exceeded
+		}
+
+		reset := strconv.Itoa(int(tightest.Reset.Seconds()))
+		w.Header().Set("RateLimit-Limit", strconv.Itoa(tightest.Limit))
+		w.Header().Set("RateLimit-Remaining", strconv.Itoa(tightest.Remaining()))
+		w.Header().Set("RateLimit-Reset", reset)
+
+		if exceeded != nil {
+			services.RecordRateLimitRejection(ctx, appID)
+			w.Header().Set("Retry-After", reset)
+			http.Error(w, `{"error":"Rate limit exceeded, retry later"}`, http.StatusTooManyRequests)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,99 @@
+package middleware
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+const (
+	RequestIDKey contextKey = "requestID"
+	ClientIPKey  contextKey = "clientIP"
+)
+
+// RequestIDHeader carries a request's ID; an incoming one (e.g. from a load balancer) is kept
+const RequestIDHeader = "X-Request-ID"
+
+var requestIDFormat = regexp.MustCompile(`^[A-Za-z0-9._:-]{1,128}$`)
+
+// RequestContext middleware assigns every request an ID, echoed in the X-Request-ID response
+// header, and resolves the client's IP address. X-Forwarded-For and X-Real-IP are only
+// believed when the connection comes from one of trustedProxies.
+func RequestContext(trustedProxies []*net.IPNet) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requestID := r.Header.Get(RequestIDHeader)
+			if !requestIDFormat.MatchString(requestID) {
+				requestID = uuid.New().String()
+			}
+			w.Header().Set(RequestIDHeader, requestID)
+
+			ctx := context.WithValue(r.Context(), RequestIDKey, requestID)
+			ctx = context.WithValue(ctx, ClientIPKey, clientIP(r, trustedProxies))
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// clientIP returns the address of the client that sent r. Proxies append the address
+// they received a request from to X-Forwarded-For, so the client is the right-most
+// entry that isn't one of our proxies; anything left of it could be forged.
+func clientIP(r *http.Request, trustedProxies []*net.IPNet) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	remote := net.ParseIP(host)
+	if remote == nil || !isTrustedProxy(remote, trustedProxies) {
+		return host
+	}
+
+	var forwarded []string
+	for _, header := range r.Header.Values("X-Forwarded-For") {
+		forwarded = append(forwarded, strings.Split(header, ",")...)
+	}
+	if len(forwarded) == 0 {
+		if ip := net.ParseIP(strings.TrimSpace(r.Header.Get("X-Real-IP"))); ip != nil {
+			return ip.String()
+		}
+		return remote.String()
+	}
+
+	client := remote
+	for i := len(forwarded) - 1; i >= 0; i-- {
+		ip := net.ParseIP(strings.TrimSpace(forwarded[i]))
+		if ip == nil {
+			break
+		}
+		client = ip
+		if !isTrustedProxy(ip, trustedProxies) {
+			break
+		}
+	}
+	return client.String()
+}
+
+func isTrustedProxy(ip net.IP, trustedProxies []*net.IPNet) bool {
+	for _, network := range trustedProxies {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// GetRequestID returns the request's ID, or "" outside RequestContext
+func GetRequestID(ctx context.Context) string {
+	id, _ := ctx.Value(RequestIDKey).(string)
+	return id
+}
+
+// GetClientIP returns the request's client IP address, or "" outside RequestContext
+func GetClientIP(ctx context.Context) string {
+	ip, _ := ctx.Value(ClientIPKey).(string)
+	return ip
+}
@@ -0,0 +1,99 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"database/sql"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/frallan97/feedback-service/backend/models"
+	"github.com/frallan97/feedback-service/backend/services"
+	"github.com/google/uuid"
+)
+
+const (
+	// SignatureMaxAge is how far a signed request's timestamp may be from the server's clock
+	SignatureMaxAge = 5 * time.Minute
+
+	// maxSignedBodySize bounds the body read to verify a signature
+	maxSignedBodySize = 32 << 20
+)
+
+var nonceFormat = regexp.MustCompile(`^[A-Za-z0-9_-]{16,128}$`)
+
+// signedRequest authenticates a request signed with its application's signing secret, for
+// AppAuth. Signed requests come from the application's own servers, so they get every scope.
+func signedRequest(next http.Handler, w http.ResponseWriter, r *http.Request) {
+	appID, err := uuid.Parse(r.Header.Get(services.RequestApplicationHeader))
+	if err != nil {
+		http.Error(w, `{"error":"Missing or invalid `+services.RequestApplicationHeader+` header"}`, http.StatusUnauthorized)
+		return
+	}
+
+	timestamp, err := strconv.ParseInt(r.Header.Get(services.RequestTimestampHeader), 10, 64)
+	if err != nil {
+		http.Error(w, `{"error":"Missing or invalid `+services.RequestTimestampHeader+` header"}`, http.StatusUnauthorized)
+		return
+	}
+	signedAt := time.Unix(timestamp, 0)
+	if age := time.Since(signedAt); age > SignatureMaxAge || age < -SignatureMaxAge {
+		http.Error(w, `{"error":"Request timestamp is too old or too far in the future"}`, http.StatusUnauthorized)
+		return
+	}
+
+	nonce := r.Header.Get(services.RequestNonceHeader)
+	if !nonceFormat.MatchString(nonce) {
+		http.Error(w, `{"error":"`+services.RequestNonceHeader+` must be 16-128 letters, digits, '-' or '_'"}`, http.StatusUnauthorized)
+		return
+	}
+
+	// Read the body to verify it, then hand an unread copy to the handler
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxSignedBodySize))
+	if err != nil {
+		http.Error(w, `{"error":"Request body too large"}`, http.StatusRequestEntityTooLarge)
+		return
+	}
+	r.Body = io.NopCloser(bytes.NewReader(body))
+
+	secret, active, err := services.GetRequestSigningSecret(r.Context(), appID)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	signature := strings.TrimPrefix(r.Header.Get(services.RequestSignatureHeader), "sha256=")
+	expected := services.SignRequest(secret, timestamp, nonce, r.Method, r.URL.RequestURI(), body)
+	if secret == "" || !hmac.Equal([]byte(signature), []byte(expected)) {
+		http.Error(w, `{"error":"Invalid signature"}`, http.StatusUnauthorized)
+		return
+	}
+
+	if !active {
+		http.Error(w, `{"error":"Application is inactive"}`, http.StatusForbidden)
+		return
+	}
+
+	// A nonce can't be reused while its timestamp is still accepted
+	claimed, err := services.ClaimRequestNonce(r.Context(), appID, nonce, signedAt.Add(SignatureMaxAge).UTC())
+	if err != nil {
+		http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+		return
+	}
+	if !claimed {
+		log.Printf("[AUTH] Rejected replayed request for application %s (nonce %s)", appID, nonce)
+		http.Error(w, `{"error":"Request has already been processed"}`, http.StatusUnauthorized)
+		return
+	}
+
+	ctx := context.WithValue(r.Context(), AppIDKey, appID)
+	ctx = context.WithValue(ctx, APIKeyScopeKey, models.APIKeyScopes)
+	next.ServeHTTP(w, r.WithContext(ctx))
+}
@@ -0,0 +1,2 @@
+DELETE FROM casbin_rule WHERE v1 = '/api/v1/applications/*/regenerate-webhook-secret';
+ALTER TABLE applications DROP COLUMN IF EXISTS webhook_secret;
@@ -0,0 +1,13 @@
+-- Per-application secret used to sign outgoing webhook deliveries
+ALTER TABLE applications ADD COLUMN webhook_secret VARCHAR(255);
+
+-- Backfill existing applications with a random secret
+UPDATE applications
+SET webhook_secret = 'whsec_' || replace(gen_random_uuid()::text || gen_random_uuid()::text, '-', '')
+WHERE webhook_secret IS NULL;
+
+ALTER TABLE applications ALTER COLUMN webhook_secret SET NOT NULL;
+
+INSERT INTO casbin_rule (ptype, v0, v1, v2) VALUES
+    ('p', 'admin', '/api/v1/applications/*/regenerate-webhook-secret', 'POST')
+ON CONFLICT DO NOTHING;
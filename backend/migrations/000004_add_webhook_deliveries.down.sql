@@ -0,0 +1,4 @@
+DELETE FROM casbin_rule WHERE v1 = '/api/v1/applications/*/webhooks/*';
+DROP TRIGGER IF EXISTS webhook_deliveries_updated_at ON webhook_deliveries;
+DROP TABLE IF EXISTS webhook_delivery_attempts CASCADE;
+DROP TABLE IF EXISTS webhook_deliveries CASCADE;
@@ -0,0 +1,46 @@
+-- webhook_deliveries: One row per event queued for an application's webhook
+CREATE TABLE webhook_deliveries (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    application_id UUID NOT NULL REFERENCES applications(id) ON DELETE CASCADE,
+    event_id UUID NOT NULL,
+    event_type VARCHAR(100) NOT NULL,
+    url TEXT NOT NULL,
+    payload JSONB NOT NULL,
+
+    -- pending: waiting for (re)delivery, succeeded: 2xx received, dead: retries exhausted
+    status VARCHAR(20) NOT NULL DEFAULT 'pending',
+    attempts INT NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMP DEFAULT NOW(),
+    last_response_status INT,
+    last_latency_ms INT,
+    last_error TEXT,
+    redelivery_of UUID REFERENCES webhook_deliveries(id) ON DELETE SET NULL,
+
+    created_at TIMESTAMP DEFAULT NOW(),
+    updated_at TIMESTAMP DEFAULT NOW(),
+    completed_at TIMESTAMP
+);
+
+CREATE INDEX idx_webhook_deliveries_app_created ON webhook_deliveries(application_id, created_at DESC);
+CREATE INDEX idx_webhook_deliveries_due ON webhook_deliveries(next_attempt_at) WHERE status = 'pending';
+
+-- webhook_delivery_attempts: Every HTTP attempt made for a delivery
+CREATE TABLE webhook_delivery_attempts (
+    id BIGSERIAL PRIMARY KEY,
+    delivery_id UUID NOT NULL REFERENCES webhook_deliveries(id) ON DELETE CASCADE,
+    attempt INT NOT NULL,
+    response_status INT,
+    response_body TEXT,
+    latency_ms INT NOT NULL,
+    error TEXT,
+    created_at TIMESTAMP DEFAULT NOW()
+);
+
+CREATE INDEX idx_webhook_attempts_delivery ON webhook_delivery_attempts(delivery_id);
+
+CREATE TRIGGER webhook_deliveries_updated_at BEFORE UPDATE ON webhook_deliveries
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+
+INSERT INTO casbin_rule (ptype, v0, v1, v2) VALUES
+    ('p', 'admin', '/api/v1/applications/*/webhooks/*', '(GET)|(POST)')
+ON CONFLICT DO NOTHING;
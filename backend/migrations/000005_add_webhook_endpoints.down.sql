@@ -0,0 +1,30 @@
+DELETE FROM casbin_rule WHERE v1 = '/api/v1/applications/*/webhooks/*';
+INSERT INTO casbin_rule (ptype, v0, v1, v2) VALUES
+    ('p', 'admin', '/api/v1/applications/*/regenerate-webhook-secret', 'POST'),
+    ('p', 'admin', '/api/v1/applications/*/webhooks/*', '(GET)|(POST)')
+ON CONFLICT DO NOTHING;
+
+ALTER TABLE applications ADD COLUMN webhook_url TEXT;
+ALTER TABLE applications ADD COLUMN webhook_secret VARCHAR(255);
+
+-- Keep the oldest endpoint of each application as its single webhook
+UPDATE applications a
+SET webhook_url = e.url, webhook_secret = e.secret
+FROM (
+    SELECT DISTINCT ON (application_id) application_id, url, secret
+    FROM webhook_endpoints
+    ORDER BY application_id, created_at
+) e
+WHERE e.application_id = a.id;
+
+UPDATE applications
+SET webhook_secret = 'whsec_' || replace(gen_random_uuid()::text || gen_random_uuid()::text, '-', '')
+WHERE webhook_secret IS NULL;
+
+ALTER TABLE applications ALTER COLUMN webhook_secret SET NOT NULL;
+
+DROP INDEX IF EXISTS idx_webhook_deliveries_endpoint_id;
+ALTER TABLE webhook_deliveries DROP COLUMN IF EXISTS endpoint_id;
+
+DROP TRIGGER IF EXISTS webhook_endpoints_updated_at ON webhook_endpoints;
+DROP TABLE IF EXISTS webhook_endpoints CASCADE;
@@ -0,0 +1,47 @@
+-- webhook_endpoints: Multiple webhook receivers per application, each with its own event subscriptions
+CREATE TABLE webhook_endpoints (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    application_id UUID NOT NULL REFERENCES applications(id) ON DELETE CASCADE,
+    url TEXT NOT NULL,
+    secret VARCHAR(255) NOT NULL,
+    description TEXT,
+    enabled BOOLEAN NOT NULL DEFAULT TRUE,
+    -- Subscribed event types, '*' subscribes to everything
+    event_types TEXT[] NOT NULL DEFAULT '{*}',
+    created_at TIMESTAMP DEFAULT NOW(),
+    updated_at TIMESTAMP DEFAULT NOW()
+);
+
+CREATE INDEX idx_webhook_endpoints_app_id ON webhook_endpoints(application_id);
+
+CREATE TRIGGER webhook_endpoints_updated_at BEFORE UPDATE ON webhook_endpoints
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+
+-- Move existing single-URL webhooks into endpoints subscribed to all events
+INSERT INTO webhook_endpoints (application_id, url, secret, description)
+SELECT id, webhook_url, webhook_secret, 'Migrated from application webhook_url'
+FROM applications
+WHERE webhook_url IS NOT NULL AND webhook_url <> '';
+
+-- Deliveries now belong to an endpoint
+ALTER TABLE webhook_deliveries ADD COLUMN endpoint_id UUID REFERENCES webhook_endpoints(id) ON DELETE SET NULL;
+
+UPDATE webhook_deliveries d
+SET endpoint_id = e.id
+FROM webhook_endpoints e
+WHERE e.application_id = d.application_id AND e.url = d.url;
+
+-- Deliveries to a URL that is no longer the application's webhook have no endpoint to go to
+UPDATE webhook_deliveries
+SET status = 'dead', last_error = 'No webhook endpoint matches the delivery URL', completed_at = NOW()
+WHERE endpoint_id IS NULL AND status = 'pending';
+
+CREATE INDEX idx_webhook_deliveries_endpoint_id ON webhook_deliveries(endpoint_id);
+
+ALTER TABLE applications DROP COLUMN webhook_url;
+ALTER TABLE applications DROP COLUMN webhook_secret;
+
+DELETE FROM casbin_rule WHERE v1 IN ('/api/v1/applications/*/regenerate-webhook-secret', '/api/v1/applications/*/webhooks/*');
+INSERT INTO casbin_rule (ptype, v0, v1, v2) VALUES
+    ('p', 'admin', '/api/v1/applications/*/webhooks/*', '(GET)|(POST)|(PATCH)|(DELETE)')
+ON CONFLICT DO NOTHING;
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_attachments_feedback_id;
+ALTER TABLE feedback_attachments DROP COLUMN IF EXISTS file_name;
+ALTER TABLE applications DROP COLUMN IF EXISTS allowed_attachment_types;
+ALTER TABLE applications DROP COLUMN IF EXISTS max_attachments_per_feedback;
+ALTER TABLE applications DROP COLUMN IF EXISTS max_attachment_size;
@@ -0,0 +1,9 @@
+-- Per-application attachment limits
+ALTER TABLE applications ADD COLUMN max_attachment_size BIGINT NOT NULL DEFAULT 5242880;
+ALTER TABLE applications ADD COLUMN max_attachments_per_feedback INT NOT NULL DEFAULT 5;
+ALTER TABLE applications ADD COLUMN allowed_attachment_types TEXT[] NOT NULL DEFAULT '{image/png,image/jpeg,image/gif,image/webp}';
+
+-- Original file name as sent by the client (display only)
+ALTER TABLE feedback_attachments ADD COLUMN file_name VARCHAR(255);
+
+CREATE INDEX idx_attachments_feedback_id ON feedback_attachments(feedback_id);
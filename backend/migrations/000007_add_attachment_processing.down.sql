@@ -0,0 +1,9 @@
+DROP INDEX IF EXISTS idx_attachments_processing;
+ALTER TABLE feedback_attachments DROP COLUMN IF EXISTS thumbnail_key;
+ALTER TABLE feedback_attachments DROP COLUMN IF EXISTS height;
+ALTER TABLE feedback_attachments DROP COLUMN IF EXISTS width;
+ALTER TABLE feedback_attachments DROP COLUMN IF EXISTS processed_at;
+ALTER TABLE feedback_attachments DROP COLUMN IF EXISTS processing_error;
+ALTER TABLE feedback_attachments DROP COLUMN IF EXISTS processing_locked_until;
+ALTER TABLE feedback_attachments DROP COLUMN IF EXISTS processing_attempts;
+ALTER TABLE feedback_attachments DROP COLUMN IF EXISTS processing_status;
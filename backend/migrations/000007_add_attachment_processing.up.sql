@@ -0,0 +1,16 @@
+-- Background processing of image attachments (metadata stripping, thumbnails, dimensions)
+ALTER TABLE feedback_attachments ADD COLUMN processing_status VARCHAR(20) NOT NULL DEFAULT 'skipped'
+    CHECK (processing_status IN ('pending', 'processing', 'done', 'failed', 'skipped'));
+ALTER TABLE feedback_attachments ADD COLUMN processing_attempts INT NOT NULL DEFAULT 0;
+ALTER TABLE feedback_attachments ADD COLUMN processing_locked_until TIMESTAMP;
+ALTER TABLE feedback_attachments ADD COLUMN processing_error TEXT;
+ALTER TABLE feedback_attachments ADD COLUMN processed_at TIMESTAMP;
+ALTER TABLE feedback_attachments ADD COLUMN width INT;
+ALTER TABLE feedback_attachments ADD COLUMN height INT;
+ALTER TABLE feedback_attachments ADD COLUMN thumbnail_key TEXT;
+
+-- Process images uploaded before this migration as well
+UPDATE feedback_attachments SET processing_status = 'pending' WHERE file_type LIKE 'image/%';
+
+CREATE INDEX idx_attachments_processing ON feedback_attachments(created_at)
+    WHERE processing_status IN ('pending', 'processing');
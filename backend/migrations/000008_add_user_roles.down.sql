@@ -0,0 +1,21 @@
+DELETE FROM casbin_rule WHERE ptype = 'g' AND v0 IN ('triager', 'admin') AND v1 IN ('viewer', 'triager');
+DELETE FROM casbin_rule WHERE ptype = 'p' AND v0 IN ('viewer', 'triager', 'admin');
+
+INSERT INTO casbin_rule (ptype, v0, v1, v2) VALUES
+    ('p', 'user', '/api/v1/feedback', 'GET'),
+    ('p', 'user', '/api/v1/feedback/*', 'GET'),
+    ('p', 'user', '/api/v1/feedback/*/comments', '(GET)|(POST)'),
+    ('p', 'admin', '/api/v1/feedback', '(GET)|(POST)'),
+    ('p', 'admin', '/api/v1/feedback/*', '(GET)|(PATCH)|(DELETE)'),
+    ('p', 'admin', '/api/v1/applications', '(GET)|(POST)'),
+    ('p', 'admin', '/api/v1/applications/*', '(GET)|(PATCH)|(DELETE)'),
+    ('p', 'admin', '/api/v1/applications/*/categories', '(GET)|(POST)|(PATCH)|(DELETE)'),
+    ('p', 'admin', '/api/v1/applications/*/webhooks/*', '(GET)|(POST)|(PATCH)|(DELETE)'),
+    ('p', 'admin', '/api/v1/statistics', 'GET')
+ON CONFLICT DO NOTHING;
+
+DROP TRIGGER IF EXISTS users_sync_is_admin ON users;
+DROP FUNCTION IF EXISTS sync_user_is_admin();
+
+UPDATE users SET is_admin = (role = 'admin');
+ALTER TABLE users DROP COLUMN IF EXISTS role;
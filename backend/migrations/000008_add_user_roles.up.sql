@@ -0,0 +1,58 @@
+-- Users get one of three roles: viewer (read-only), triager (manage feedback) or admin
+ALTER TABLE users ADD COLUMN role VARCHAR(20) NOT NULL DEFAULT 'viewer'
+    CHECK (role IN ('viewer', 'triager', 'admin'));
+UPDATE users SET role = 'admin' WHERE is_admin;
+
+-- is_admin is kept for existing readers and writers and mirrors role: setting it promotes
+-- to admin or demotes to viewer, and changing role updates it
+CREATE OR REPLACE FUNCTION sync_user_is_admin()
+RETURNS TRIGGER AS $$
+BEGIN
+    IF TG_OP = 'UPDATE' AND NEW.is_admin IS DISTINCT FROM OLD.is_admin AND NEW.role = OLD.role THEN
+        NEW.role := CASE WHEN NEW.is_admin THEN 'admin' WHEN NEW.role = 'admin' THEN 'viewer' ELSE NEW.role END;
+    ELSIF TG_OP = 'INSERT' AND NEW.is_admin AND NEW.role = 'viewer' THEN
+        NEW.role := 'admin';
+    END IF;
+    NEW.is_admin := NEW.role = 'admin';
+    RETURN NEW;
+END;
+$$ language 'plpgsql';
+
+CREATE TRIGGER users_sync_is_admin BEFORE INSERT OR UPDATE ON users
+    FOR EACH ROW EXECUTE FUNCTION sync_user_is_admin();
+
+-- Replace the "user"/"admin" policies with per-role policies. Paths use keyMatch2
+-- (":id" matches one segment, "*" matches the rest of the path) and actions are
+-- anchored regexes.
+DELETE FROM casbin_rule WHERE ptype = 'p' AND v0 IN ('user', 'admin');
+
+INSERT INTO casbin_rule (ptype, v0, v1, v2) VALUES
+    -- Viewers can read feedback, comments, attachments and applications
+    ('p', 'viewer', '/api/v1/feedback', 'GET'),
+    ('p', 'viewer', '/api/v1/feedback/:id', 'GET'),
+    ('p', 'viewer', '/api/v1/feedback/:id/comments', 'GET'),
+    ('p', 'viewer', '/api/v1/feedback/:id/attachments', 'GET'),
+    ('p', 'viewer', '/api/v1/feedback/:id/attachments/:attachment_id', 'GET'),
+    ('p', 'viewer', '/api/v1/applications', 'GET'),
+    ('p', 'viewer', '/api/v1/applications/:id', 'GET'),
+    ('p', 'viewer', '/api/v1/applications/:id/categories', 'GET'),
+    ('p', 'viewer', '/api/v1/statistics', 'GET'),
+
+    -- Triagers can also update feedback status/priority and comment
+    ('p', 'triager', '/api/v1/feedback/:id', 'PATCH'),
+    ('p', 'triager', '/api/v1/feedback/:id/comments', 'POST'),
+    ('p', 'triager', '/api/v1/feedback/:id/comments/:comment_id', '(PATCH)|(DELETE)'),
+
+    -- Admins can also delete feedback and manage applications
+    ('p', 'admin', '/api/v1/feedback/:id', 'DELETE'),
+    ('p', 'admin', '/api/v1/feedback/:id/attachments/:attachment_id', 'DELETE'),
+    ('p', 'admin', '/api/v1/applications', 'POST'),
+    ('p', 'admin', '/api/v1/applications/:id', '(PATCH)|(DELETE)'),
+    ('p', 'admin', '/api/v1/applications/:id/*', '(GET)|(POST)|(PATCH)|(DELETE)')
+ON CONFLICT DO NOTHING;
+
+-- Role inheritance: admin > triager > viewer
+INSERT INTO casbin_rule (ptype, v0, v1) VALUES
+    ('g', 'triager', 'viewer'),
+    ('g', 'admin', 'triager')
+ON CONFLICT DO NOTHING;
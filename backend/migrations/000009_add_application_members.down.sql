@@ -0,0 +1,10 @@
+DELETE FROM casbin_rule WHERE ptype = 'p' AND v0 = 'viewer' AND v1 = '/api/v1/applications/:id/members';
+UPDATE casbin_rule SET v0 = 'admin' WHERE ptype = 'p' AND v0 = 'owner';
+DELETE FROM casbin_rule WHERE ptype = 'g' AND ((v0 = 'owner' AND v1 = 'triager') OR (v0 = 'admin' AND v1 = 'owner'));
+INSERT INTO casbin_rule (ptype, v0, v1) VALUES
+    ('g', 'admin', 'triager')
+ON CONFLICT DO NOTHING;
+
+DROP TABLE IF EXISTS application_invitations CASCADE;
+DROP TRIGGER IF EXISTS application_members_updated_at ON application_members;
+DROP TABLE IF EXISTS application_members CASCADE;
@@ -0,0 +1,41 @@
+-- application_members: which users can access an application, and with what role
+CREATE TABLE application_members (
+    application_id UUID NOT NULL REFERENCES applications(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    role VARCHAR(20) NOT NULL CHECK (role IN ('owner', 'triager', 'viewer')),
+    added_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP DEFAULT NOW(),
+    updated_at TIMESTAMP DEFAULT NOW(),
+    PRIMARY KEY (application_id, user_id)
+);
+
+CREATE INDEX idx_application_members_user_id ON application_members(user_id);
+
+CREATE TRIGGER application_members_updated_at BEFORE UPDATE ON application_members
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+
+-- application_invitations: pending memberships for people who haven't signed in yet
+CREATE TABLE application_invitations (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    application_id UUID NOT NULL REFERENCES applications(id) ON DELETE CASCADE,
+    email VARCHAR(255) NOT NULL,
+    role VARCHAR(20) NOT NULL CHECK (role IN ('owner', 'triager', 'viewer')),
+    invited_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP DEFAULT NOW()
+);
+
+CREATE UNIQUE INDEX idx_application_invitations_email ON application_invitations(application_id, LOWER(email));
+
+-- Owners manage their own applications; admins manage every application and can create new ones
+DELETE FROM casbin_rule WHERE ptype = 'g' AND v0 = 'admin' AND v1 = 'triager';
+INSERT INTO casbin_rule (ptype, v0, v1) VALUES
+    ('g', 'owner', 'triager'),
+    ('g', 'admin', 'owner')
+ON CONFLICT DO NOTHING;
+
+UPDATE casbin_rule SET v0 = 'owner'
+WHERE ptype = 'p' AND v0 = 'admin' AND NOT (v1 = '/api/v1/applications' AND v2 = 'POST');
+
+INSERT INTO casbin_rule (ptype, v0, v1, v2) VALUES
+    ('p', 'viewer', '/api/v1/applications/:id/members', 'GET')
+ON CONFLICT DO NOTHING;
@@ -0,0 +1,4 @@
+-- Admins manage policies and role assignments through the API
+INSERT INTO casbin_rule (ptype, v0, v1, v2) VALUES
+    ('p', 'admin', '/api/v1/authz/*', '(GET)|(POST)|(DELETE)')
+ON CONFLICT DO NOTHING;
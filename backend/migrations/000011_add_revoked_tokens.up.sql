@@ -0,0 +1,9 @@
+-- revoked_tokens: access tokens invalidated by logout, kept until they would have expired
+CREATE TABLE revoked_tokens (
+    token_id VARCHAR(255) PRIMARY KEY,
+    user_id UUID,
+    expires_at TIMESTAMP NOT NULL,
+    revoked_at TIMESTAMP DEFAULT NOW()
+);
+
+CREATE INDEX idx_revoked_tokens_expires_at ON revoked_tokens(expires_at);
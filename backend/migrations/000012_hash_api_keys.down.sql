@@ -0,0 +1,9 @@
+-- Plaintext keys can't be recovered; every application needs a new key after this
+ALTER TABLE applications ADD COLUMN api_key VARCHAR(255);
+UPDATE applications SET api_key = api_key_hash;
+ALTER TABLE applications ALTER COLUMN api_key SET NOT NULL;
+ALTER TABLE applications ADD CONSTRAINT applications_api_key_key UNIQUE (api_key);
+
+DROP INDEX IF EXISTS idx_applications_api_key_prefix;
+ALTER TABLE applications DROP COLUMN api_key_hash;
+ALTER TABLE applications DROP COLUMN api_key_prefix;
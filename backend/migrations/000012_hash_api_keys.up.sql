@@ -0,0 +1,15 @@
+-- Store API keys as SHA-256 hashes. The first 12 characters stay visible so keys can be
+-- looked up and told apart without revealing them.
+ALTER TABLE applications ADD COLUMN api_key_prefix VARCHAR(12);
+ALTER TABLE applications ADD COLUMN api_key_hash VARCHAR(64);
+
+UPDATE applications
+SET api_key_prefix = LEFT(api_key, 12),
+    api_key_hash = encode(sha256(convert_to(api_key, 'UTF8')), 'hex');
+
+ALTER TABLE applications ALTER COLUMN api_key_prefix SET NOT NULL;
+ALTER TABLE applications ALTER COLUMN api_key_hash SET NOT NULL;
+ALTER TABLE applications ADD CONSTRAINT applications_api_key_hash_key UNIQUE (api_key_hash);
+CREATE INDEX idx_applications_api_key_prefix ON applications(api_key_prefix);
+
+ALTER TABLE applications DROP COLUMN api_key;
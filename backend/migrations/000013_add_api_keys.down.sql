@@ -0,0 +1,21 @@
+ALTER TABLE applications ADD COLUMN api_key_prefix VARCHAR(12);
+ALTER TABLE applications ADD COLUMN api_key_hash VARCHAR(64);
+
+-- Keep each application's default key; applications without one get an unusable placeholder
+UPDATE applications a
+SET api_key_prefix = k.key_prefix, api_key_hash = k.key_hash
+FROM api_keys k
+WHERE k.application_id = a.id AND k.is_default AND k.revoked_at IS NULL;
+
+UPDATE applications
+SET api_key_prefix = 'revoked',
+    api_key_hash = encode(sha256(convert_to(gen_random_uuid()::text, 'UTF8')), 'hex')
+WHERE api_key_hash IS NULL;
+
+ALTER TABLE applications ALTER COLUMN api_key_prefix SET NOT NULL;
+ALTER TABLE applications ALTER COLUMN api_key_hash SET NOT NULL;
+ALTER TABLE applications ADD CONSTRAINT applications_api_key_hash_key UNIQUE (api_key_hash);
+CREATE INDEX idx_applications_api_key_prefix ON applications(api_key_prefix);
+
+DROP TRIGGER IF EXISTS api_keys_updated_at ON api_keys;
+DROP TABLE IF EXISTS api_keys CASCADE;
@@ -0,0 +1,36 @@
+-- api_keys: credentials client applications authenticate with, each limited to a set of scopes
+CREATE TABLE api_keys (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    application_id UUID NOT NULL REFERENCES applications(id) ON DELETE CASCADE,
+    name VARCHAR(100) NOT NULL,
+    key_prefix VARCHAR(12) NOT NULL,
+    key_hash VARCHAR(64) NOT NULL UNIQUE,
+    scopes TEXT[] NOT NULL,
+
+    -- The key returned when the application is created and replaced by regenerate-key
+    is_default BOOLEAN NOT NULL DEFAULT FALSE,
+
+    expires_at TIMESTAMP,
+    last_used_at TIMESTAMP,
+    revoked_at TIMESTAMP,
+    created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP DEFAULT NOW(),
+    updated_at TIMESTAMP DEFAULT NOW()
+);
+
+CREATE INDEX idx_api_keys_key_prefix ON api_keys(key_prefix);
+CREATE INDEX idx_api_keys_application_id ON api_keys(application_id, created_at);
+CREATE UNIQUE INDEX idx_api_keys_default ON api_keys(application_id) WHERE is_default AND revoked_at IS NULL;
+
+CREATE TRIGGER api_keys_updated_at BEFORE UPDATE ON api_keys
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+
+-- Each application's existing key becomes its default key, with every scope
+INSERT INTO api_keys (application_id, name, key_prefix, key_hash, scopes, is_default, created_at)
+SELECT id, 'Default', api_key_prefix, api_key_hash,
+       ARRAY['feedback:write', 'feedback:read_status', 'categories:read'], TRUE, created_at
+FROM applications;
+
+DROP INDEX IF EXISTS idx_applications_api_key_prefix;
+ALTER TABLE applications DROP COLUMN api_key_hash;
+ALTER TABLE applications DROP COLUMN api_key_prefix;
@@ -0,0 +1,3 @@
+-- Rotated keys keep working until expires_at (the end of the grace period), pointing at their replacement
+ALTER TABLE api_keys ADD COLUMN rotated_at TIMESTAMP;
+ALTER TABLE api_keys ADD COLUMN replaced_by UUID REFERENCES api_keys(id) ON DELETE SET NULL;
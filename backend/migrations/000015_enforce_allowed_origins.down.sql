@@ -0,0 +1,2 @@
+ALTER TABLE applications ALTER COLUMN allowed_origins DROP NOT NULL;
+ALTER TABLE applications ALTER COLUMN allowed_origins DROP DEFAULT;
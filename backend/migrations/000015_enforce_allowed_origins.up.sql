@@ -0,0 +1,7 @@
+-- Allowed origins are now enforced for browser requests to the public API, and an empty
+-- list allows none. Keep existing applications' widgets working until owners restrict them.
+UPDATE applications SET allowed_origins = ARRAY['*']
+WHERE allowed_origins IS NULL OR cardinality(allowed_origins) = 0;
+
+ALTER TABLE applications ALTER COLUMN allowed_origins SET DEFAULT '{}';
+ALTER TABLE applications ALTER COLUMN allowed_origins SET NOT NULL;
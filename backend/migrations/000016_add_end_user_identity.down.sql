@@ -0,0 +1,7 @@
+DELETE FROM casbin_rule WHERE ptype = 'p' AND v0 = 'viewer' AND v1 = '/api/v1/applications/:id/end-users';
+
+DROP INDEX IF EXISTS idx_feedback_end_user_id;
+ALTER TABLE feedback DROP COLUMN IF EXISTS end_user_id;
+DROP TABLE IF EXISTS end_users;
+ALTER TABLE applications DROP COLUMN IF EXISTS identity_public_key;
+ALTER TABLE applications DROP COLUMN IF EXISTS identity_secret;
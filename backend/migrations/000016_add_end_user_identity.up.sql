@@ -0,0 +1,23 @@
+-- Keys client applications sign end-user identity tokens with: a shared secret (HS256)
+-- and/or a PEM public key (RS256/ES256)
+ALTER TABLE applications ADD COLUMN identity_secret VARCHAR(255);
+ALTER TABLE applications ADD COLUMN identity_public_key TEXT;
+
+-- end_users: Client application users identified by a verified identity token
+CREATE TABLE end_users (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    application_id UUID NOT NULL REFERENCES applications(id) ON DELETE CASCADE,
+    external_id VARCHAR(255) NOT NULL,
+    email VARCHAR(255),
+    name VARCHAR(255),
+    first_seen_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    last_seen_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    UNIQUE (application_id, external_id)
+);
+
+ALTER TABLE feedback ADD COLUMN end_user_id UUID REFERENCES end_users(id) ON DELETE SET NULL;
+CREATE INDEX idx_feedback_end_user_id ON feedback(end_user_id);
+
+INSERT INTO casbin_rule (ptype, v0, v1, v2) VALUES
+    ('p', 'viewer', '/api/v1/applications/:id/end-users', 'GET')
+ON CONFLICT DO NOTHING;
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS request_nonces;
+ALTER TABLE applications DROP COLUMN IF EXISTS signing_secret;
@@ -0,0 +1,13 @@
+-- Secret an application's servers sign public API requests with (HMAC-SHA256)
+ALTER TABLE applications ADD COLUMN signing_secret VARCHAR(255);
+
+-- request_nonces: Nonces of signed requests, kept while their timestamp is still accepted
+-- so a captured request can't be replayed
+CREATE TABLE request_nonces (
+    application_id UUID NOT NULL REFERENCES applications(id) ON DELETE CASCADE,
+    nonce VARCHAR(128) NOT NULL,
+    expires_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (application_id, nonce)
+);
+
+CREATE INDEX idx_request_nonces_expires_at ON request_nonces(expires_at);
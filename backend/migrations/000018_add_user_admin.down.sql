@@ -0,0 +1,2 @@
+DELETE FROM casbin_rule WHERE ptype = 'p' AND v0 = 'admin' AND v1 IN ('/api/v1/users', '/api/v1/users/*');
+ALTER TABLE users DROP COLUMN IF EXISTS last_seen_at;
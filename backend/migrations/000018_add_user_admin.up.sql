@@ -0,0 +1,9 @@
+-- Last authenticated request, for the user administration API
+ALTER TABLE users ADD COLUMN last_seen_at TIMESTAMP;
+UPDATE users SET last_seen_at = updated_at;
+
+-- Admins manage users
+INSERT INTO casbin_rule (ptype, v0, v1, v2) VALUES
+    ('p', 'admin', '/api/v1/users', 'GET'),
+    ('p', 'admin', '/api/v1/users/*', '(GET)|(POST)|(PATCH)')
+ON CONFLICT DO NOTHING;
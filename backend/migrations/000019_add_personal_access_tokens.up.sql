@@ -0,0 +1,17 @@
+-- personal_access_tokens: Tokens users script the admin API with, limited to scopes.
+-- Stored as a SHA-256 hash plus a visible prefix, like API keys.
+CREATE TABLE personal_access_tokens (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name VARCHAR(100) NOT NULL,
+    token_prefix VARCHAR(12) NOT NULL,
+    token_hash VARCHAR(64) NOT NULL UNIQUE,
+    scopes TEXT[] NOT NULL,
+    expires_at TIMESTAMP NOT NULL,
+    last_used_at TIMESTAMP,
+    revoked_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_personal_access_tokens_prefix ON personal_access_tokens(token_prefix);
+CREATE INDEX idx_personal_access_tokens_user_id ON personal_access_tokens(user_id);
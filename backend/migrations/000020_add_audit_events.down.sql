@@ -0,0 +1,3 @@
+DELETE FROM casbin_rule WHERE ptype = 'p' AND v0 = 'admin' AND v1 = '/api/v1/audit';
+DROP TABLE IF EXISTS audit_events;
+DROP FUNCTION IF EXISTS prevent_audit_event_change();
@@ -0,0 +1,42 @@
+-- audit_events: Append-only record of administrative changes. Actors and targets are
+-- kept as plain values rather than foreign keys so events outlive what they describe.
+CREATE TABLE audit_events (
+    id BIGSERIAL PRIMARY KEY,
+    actor_id UUID,
+    actor_email VARCHAR(255),
+    actor_token_id UUID,
+    action VARCHAR(100) NOT NULL,
+    target_type VARCHAR(50) NOT NULL,
+    target_id TEXT NOT NULL,
+    application_id UUID,
+    before JSONB,
+    after JSONB,
+    ip VARCHAR(45),
+    request_id VARCHAR(128),
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_audit_events_created_at ON audit_events(created_at DESC);
+CREATE INDEX idx_audit_events_actor_id ON audit_events(actor_id, created_at DESC);
+CREATE INDEX idx_audit_events_target ON audit_events(target_type, target_id, created_at DESC);
+CREATE INDEX idx_audit_events_application_id ON audit_events(application_id, created_at DESC);
+
+CREATE OR REPLACE FUNCTION prevent_audit_event_change()
+RETURNS TRIGGER AS $$
+BEGIN
+    RAISE EXCEPTION 'audit_events is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER audit_events_append_only
+    BEFORE UPDATE OR DELETE ON audit_events
+    FOR EACH ROW EXECUTE FUNCTION prevent_audit_event_change();
+
+CREATE TRIGGER audit_events_no_truncate
+    BEFORE TRUNCATE ON audit_events
+    FOR EACH STATEMENT EXECUTE FUNCTION prevent_audit_event_change();
+
+-- Admins read the audit log
+INSERT INTO casbin_rule (ptype, v0, v1, v2) VALUES
+    ('p', 'admin', '/api/v1/audit', 'GET')
+ON CONFLICT DO NOTHING;
@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS rate_limit_counters;
+ALTER TABLE applications DROP CONSTRAINT IF EXISTS applications_rate_limits_check;
+ALTER TABLE applications DROP COLUMN IF EXISTS rate_limit_per_ip;
+ALTER TABLE applications DROP COLUMN IF EXISTS rate_limit_per_key;
@@ -0,0 +1,17 @@
+-- Public API requests allowed per minute for each API key and each client IP (0 = unlimited)
+ALTER TABLE applications ADD COLUMN rate_limit_per_key INT NOT NULL DEFAULT 600;
+ALTER TABLE applications ADD COLUMN rate_limit_per_ip INT NOT NULL DEFAULT 60;
+ALTER TABLE applications ADD CONSTRAINT applications_rate_limits_check
+    CHECK (rate_limit_per_key >= 0 AND rate_limit_per_ip >= 0);
+
+-- rate_limit_counters: Requests counted per bucket (a key or an application and IP) in
+-- fixed one-minute windows, shared by every instance. Unlogged: losing counts in a crash
+-- only briefly relaxes the limits.
+CREATE UNLOGGED TABLE rate_limit_counters (
+    bucket TEXT NOT NULL,
+    window_start TIMESTAMP NOT NULL,
+    count INT NOT NULL DEFAULT 0,
+    PRIMARY KEY (bucket, window_start)
+);
+
+CREATE INDEX idx_rate_limit_counters_window_start ON rate_limit_counters(window_start);
@@ -0,0 +1,12 @@
+DELETE FROM casbin_rule WHERE ptype = 'p' AND v0 = 'triager'
+    AND v1 IN ('/api/v1/applications/:id/moderation', '/api/v1/feedback/:id/approve', '/api/v1/feedback/:id/reject');
+DROP TABLE IF EXISTS spam_model;
+DROP TABLE IF EXISTS spam_tokens;
+DROP INDEX IF EXISTS idx_feedback_held;
+UPDATE feedback SET status = 'new' WHERE status = 'held';
+UPDATE feedback SET status = 'closed' WHERE status = 'rejected';
+ALTER TABLE feedback DROP COLUMN IF EXISTS spam_trained;
+ALTER TABLE feedback DROP COLUMN IF EXISTS spam_reasons;
+ALTER TABLE feedback DROP COLUMN IF EXISTS spam_score;
+ALTER TABLE applications DROP COLUMN IF EXISTS spam_threshold;
+ALTER TABLE applications DROP COLUMN IF EXISTS spam_filter_enabled;
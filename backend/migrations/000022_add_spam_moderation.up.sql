@@ -0,0 +1,38 @@
+-- Spam filtering: submissions scoring at least spam_threshold (0-1) are held for review.
+-- Off until an application's owner turns it on.
+ALTER TABLE applications ADD COLUMN spam_filter_enabled BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE applications ADD COLUMN spam_threshold DOUBLE PRECISION NOT NULL DEFAULT 0.7
+    CHECK (spam_threshold > 0 AND spam_threshold <= 1);
+
+-- Why a submission was scored as it was. spam_trained records which way a moderator's
+-- decision trained the model, so a reversed decision can be untrained.
+ALTER TABLE feedback ADD COLUMN spam_score DOUBLE PRECISION;
+ALTER TABLE feedback ADD COLUMN spam_reasons TEXT[];
+ALTER TABLE feedback ADD COLUMN spam_trained VARCHAR(4) CHECK (spam_trained IN ('spam', 'ham'));
+
+CREATE INDEX idx_feedback_held ON feedback(application_id, created_at) WHERE status = 'held';
+
+-- spam_tokens: Naive Bayes model trained by each application's moderation decisions - how
+-- many spam and ham (legitimate) documents each token appeared in
+CREATE TABLE spam_tokens (
+    application_id UUID NOT NULL REFERENCES applications(id) ON DELETE CASCADE,
+    token VARCHAR(64) NOT NULL,
+    spam_count INT NOT NULL DEFAULT 0,
+    ham_count INT NOT NULL DEFAULT 0,
+    PRIMARY KEY (application_id, token)
+);
+
+-- spam_model: The number of spam and ham documents each application's model was trained
+-- on. The row is created by the first moderation decision.
+CREATE TABLE spam_model (
+    application_id UUID PRIMARY KEY REFERENCES applications(id) ON DELETE CASCADE,
+    spam_docs INT NOT NULL DEFAULT 0,
+    ham_docs INT NOT NULL DEFAULT 0
+);
+
+-- Triagers review held feedback
+INSERT INTO casbin_rule (ptype, v0, v1, v2) VALUES
+    ('p', 'triager', '/api/v1/applications/:id/moderation', 'GET'),
+    ('p', 'triager', '/api/v1/feedback/:id/approve', 'POST'),
+    ('p', 'triager', '/api/v1/feedback/:id/reject', 'POST')
+ON CONFLICT DO NOTHING;
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS spent_challenges;
+ALTER TABLE applications DROP COLUMN IF EXISTS pow_difficulty;
+ALTER TABLE applications DROP COLUMN IF EXISTS pow_enabled;
@@ -0,0 +1,16 @@
+-- Proof of work: anonymous public submissions must solve a challenge with at least
+-- pow_difficulty leading zero bits (raised while the rate limiter sees abuse)
+ALTER TABLE applications ADD COLUMN pow_enabled BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE applications ADD COLUMN pow_difficulty INT NOT NULL DEFAULT 16
+    CHECK (pow_difficulty BETWEEN 1 AND 24);
+
+-- spent_challenges: Challenges already used for a submission, kept until they expire so a
+-- solved challenge can't be reused
+CREATE TABLE spent_challenges (
+    application_id UUID NOT NULL REFERENCES applications(id) ON DELETE CASCADE,
+    challenge_id VARCHAR(64) NOT NULL,
+    expires_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (application_id, challenge_id)
+);
+
+CREATE INDEX idx_spent_challenges_expires_at ON spent_challenges(expires_at);
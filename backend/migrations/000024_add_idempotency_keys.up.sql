@@ -0,0 +1,17 @@
+-- idempotency_keys: Responses to public API requests sent with an Idempotency-Key header,
+-- replayed when a client retries with the same key. A NULL status_code marks a request
+-- still being processed; claim_id identifies that request, so it can't complete or release
+-- a claim a retry has since taken over.
+CREATE TABLE idempotency_keys (
+    application_id UUID NOT NULL REFERENCES applications(id) ON DELETE CASCADE,
+    key VARCHAR(255) NOT NULL,
+    claim_id UUID NOT NULL,
+    request_hash CHAR(64) NOT NULL,
+    status_code INT,
+    response BYTEA,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    expires_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (application_id, key)
+);
+
+CREATE INDEX idx_idempotency_keys_expires_at ON idempotency_keys(expires_at);
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS discarded_submissions;
+DROP TABLE IF EXISTS blocklist_rules;
@@ -0,0 +1,28 @@
+-- blocklist_rules: Submissions matching a rule are rejected, or accepted and silently
+-- discarded. Rules match a contact email exactly, an email domain (and its subdomains),
+-- a client IP or CIDR range, or a regular expression on the title and content.
+CREATE TABLE blocklist_rules (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    application_id UUID NOT NULL REFERENCES applications(id) ON DELETE CASCADE,
+    type VARCHAR(20) NOT NULL CHECK (type IN ('email', 'email_domain', 'ip', 'phrase')),
+    value TEXT NOT NULL,
+    action VARCHAR(10) NOT NULL DEFAULT 'reject' CHECK (action IN ('reject', 'discard')),
+    description TEXT,
+    hit_count BIGINT NOT NULL DEFAULT 0,
+    last_hit_at TIMESTAMP,
+    created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP DEFAULT NOW(),
+    -- Set by edits only (no trigger), so counting hits doesn't change it
+    updated_at TIMESTAMP DEFAULT NOW(),
+    UNIQUE (application_id, type, value)
+);
+
+-- discarded_submissions: The IDs given to discarded submissions, so the status endpoint
+-- can answer for them like stored feedback. Kept for 30 days.
+CREATE TABLE discarded_submissions (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    application_id UUID NOT NULL REFERENCES applications(id) ON DELETE CASCADE,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_discarded_submissions_created_at ON discarded_submissions(created_at);
@@ -0,0 +1,74 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// API key scopes, checked per public route
+const (
+	ScopeFeedbackWrite      = "feedback:write"
+	ScopeFeedbackReadStatus = "feedback:read_status"
+	ScopeCategoriesRead     = "categories:read"
+)
+
+// APIKeyScopes lists every scope an API key can be granted
+var APIKeyScopes = []string{ScopeFeedbackWrite, ScopeFeedbackReadStatus, ScopeCategoriesRead}
+
+// IsValidAPIKeyScope reports whether scope is a known API key scope
+func IsValidAPIKeyScope(scope string) bool {
+	for _, s := range APIKeyScopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// API key states, derived from revoked_at, expires_at and rotated_at. Rotated keys are
+// deprecated: they keep working until their grace period ends.
+const (
+	APIKeyStatusActive     = "active"
+	APIKeyStatusDeprecated = "deprecated"
+	APIKeyStatusExpired    = "expired"
+	APIKeyStatusRevoked    = "revoked"
+)
+
+// MaxAPIKeyGracePeriod bounds how long a rotated key keeps working
+const MaxAPIKeyGracePeriod = 30 * 24 * time.Hour
+
+// APIKey is a credential a client application authenticates with. Only its prefix
+// and hash are stored; Key is set when the key is created.
+type APIKey struct {
+	ID            uuid.UUID  `json:"id"`
+	ApplicationID uuid.UUID  `json:"application_id"`
+	Name          string     `json:"name"`
+	Prefix        string     `json:"prefix"`
+	Key           string     `json:"key,omitempty"`
+	Scopes        []string   `json:"scopes"`
+	IsDefault     bool       `json:"is_default"`
+	Status        string     `json:"status"`
+	ExpiresAt     *time.Time `json:"expires_at,omitempty"`
+	LastUsedAt    *time.Time `json:"last_used_at,omitempty"`
+	RevokedAt     *time.Time `json:"revoked_at,omitempty"`
+	RotatedAt     *time.Time `json:"rotated_at,omitempty"`
+	ReplacedBy    *uuid.UUID `json:"replaced_by,omitempty"`
+	CreatedBy     *uuid.UUID `json:"created_by,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+}
+
+// SetStatus derives the key's status from its revocation, expiry and rotation times
+func (k *APIKey) SetStatus(now time.Time) {
+	switch {
+	case k.RevokedAt != nil:
+		k.Status = APIKeyStatusRevoked
+	case k.ExpiresAt != nil && !k.ExpiresAt.After(now):
+		k.Status = APIKeyStatusExpired
+	case k.RotatedAt != nil:
+		k.Status = APIKeyStatusDeprecated
+	default:
+		k.Status = APIKeyStatusActive
+	}
+}
@@ -11,12 +11,29 @@ type Application struct {
 	Name           string    `json:"name"`
 	Slug           string    `json:"slug"`
 	Description    string    `json:"description"`
-	APIKey         string    `json:"api_key,omitempty"`
+	APIKeyPrefix   string    `json:"api_key_prefix"`
+	APIKey         string    `json:"api_key,omitempty"` // Only set when a key is created or regenerated
 	IsActive       bool      `json:"is_active"`
-	WebhookURL     *string   `json:"webhook_url,omitempty"`
 	AllowedOrigins []string  `json:"allowed_origins"`
-	CreatedAt      time.Time `json:"created_at"`
-	UpdatedAt      time.Time `json:"updated_at"`
+
+	MaxAttachmentSize         int64    `json:"max_attachment_size"`
+	MaxAttachmentsPerFeedback int      `json:"max_attachments_per_feedback"`
+	AllowedAttachmentTypes    []string `json:"allowed_attachment_types"`
+
+	// Public API requests allowed per minute for each API key and each client IP; 0 is unlimited
+	RateLimitPerKey int `json:"rate_limit_per_key"`
+	RateLimitPerIP  int `json:"rate_limit_per_ip"`
+
+	// Submissions with a spam score (0-1) of at least SpamThreshold are held for moderation
+	SpamFilterEnabled bool    `json:"spam_filter_enabled"`
+	SpamThreshold     float64 `json:"spam_threshold"`
+
+	// Anonymous submissions must solve a proof-of-work challenge of at least PowDifficulty bits
+	PowEnabled    bool `json:"pow_enabled"`
+	PowDifficulty int  `json:"pow_difficulty"`
+
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
 }
 
 type Category struct {
@@ -0,0 +1,27 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// AuditEvent records one administrative change: who made it, what it changed, and the
+// target's state before and after. Actions are named "<target_type>.<verb>", e.g.
+// "application.update".
+type AuditEvent struct {
+	ID            int64           `json:"id"`
+	ActorID       *uuid.UUID      `json:"actor_id,omitempty"`
+	ActorEmail    *string         `json:"actor_email,omitempty"`
+	ActorTokenID  *uuid.UUID      `json:"actor_token_id,omitempty"`
+	Action        string          `json:"action"`
+	TargetType    string          `json:"target_type"`
+	TargetID      string          `json:"target_id"`
+	ApplicationID *uuid.UUID      `json:"application_id,omitempty"`
+	Before        json.RawMessage `json:"before,omitempty"`
+	After         json.RawMessage `json:"after,omitempty"`
+	IP            *string         `json:"ip,omitempty"`
+	RequestID     *string         `json:"request_id,omitempty"`
+	CreatedAt     time.Time       `json:"created_at"`
+}
@@ -0,0 +1,51 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Blocklist rule types: what part of a submission a rule's value is matched against
+const (
+	BlocklistTypeEmail       = "email"        // The contact email, exactly
+	BlocklistTypeEmailDomain = "email_domain" // The contact email's domain or a subdomain of it
+	BlocklistTypeIP          = "ip"           // The client IP, by address or CIDR range
+	BlocklistTypePhrase      = "phrase"       // A regular expression on the title and content
+)
+
+// Blocklist rule actions. Rejected submissions get an error; discarded ones look
+// accepted to the submitter but are never stored.
+const (
+	BlocklistActionReject  = "reject"
+	BlocklistActionDiscard = "discard"
+)
+
+// BlocklistRule blocks matching submissions to an application
+type BlocklistRule struct {
+	ID            uuid.UUID  `json:"id"`
+	ApplicationID uuid.UUID  `json:"application_id"`
+	Type          string     `json:"type"`
+	Value         string     `json:"value"`
+	Action        string     `json:"action"`
+	Description   string     `json:"description"`
+	HitCount      int64      `json:"hit_count"`
+	LastHitAt     *time.Time `json:"last_hit_at,omitempty"`
+	CreatedBy     *uuid.UUID `json:"created_by,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+}
+
+// IsValidBlocklistType reports whether t is a known blocklist rule type
+func IsValidBlocklistType(t string) bool {
+	switch t {
+	case BlocklistTypeEmail, BlocklistTypeEmailDomain, BlocklistTypeIP, BlocklistTypePhrase:
+		return true
+	}
+	return false
+}
+
+// IsValidBlocklistAction reports whether action is a known blocklist rule action
+func IsValidBlocklistAction(action string) bool {
+	return action == BlocklistActionReject || action == BlocklistActionDiscard
+}
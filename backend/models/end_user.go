@@ -0,0 +1,27 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// EndUser is a client application's user, identified by a signed identity token when
+// they submit feedback. ExternalID is the application's own ID for the user.
+type EndUser struct {
+	ID            uuid.UUID `json:"id"`
+	ApplicationID uuid.UUID `json:"application_id"`
+	ExternalID    string    `json:"external_id"`
+	Email         string    `json:"email,omitempty"`
+	Name          string    `json:"name,omitempty"`
+	FirstSeenAt   time.Time `json:"first_seen_at"`
+	LastSeenAt    time.Time `json:"last_seen_at"`
+	FeedbackCount int       `json:"feedback_count,omitempty"`
+}
+
+// ApplicationIdentity holds the keys an application signs end-user identity tokens with:
+// a shared secret for HS256 and/or a PEM public key for RS256/ES256
+type ApplicationIdentity struct {
+	Secret    string `json:"secret,omitempty"`
+	PublicKey string `json:"public_key,omitempty"`
+}
@@ -6,6 +6,14 @@ import (
 	"github.com/google/uuid"
 )
 
+// Moderation states outside the triage workflow. Submissions the spam filter holds wait
+// for review before they are treated as new; rejected ones are kept out of feedback lists.
+const (
+	FeedbackStatusNew      = "new"
+	FeedbackStatusHeld     = "held"
+	FeedbackStatusRejected = "rejected"
+)
+
 type Feedback struct {
 	ID            uuid.UUID              `json:"id"`
 	ApplicationID uuid.UUID              `json:"application_id"`
@@ -21,10 +29,14 @@ type Feedback struct {
 	AppVersion    string                 `json:"app_version"`
 	Metadata      map[string]interface{} `json:"metadata,omitempty"`
 	ContactEmail  string                 `json:"contact_email"`
+	Reporter      *EndUser               `json:"reporter,omitempty"` // Verified via an identity token
 	CreatedAt     time.Time              `json:"created_at"`
 	UpdatedAt     time.Time              `json:"updated_at"`
 	ReviewedAt    *time.Time             `json:"reviewed_at,omitempty"`
 	ResolvedAt    *time.Time             `json:"resolved_at,omitempty"`
+	ThumbnailURL  string                 `json:"thumbnail_url,omitempty"`
+	SpamScore     *float64               `json:"spam_score,omitempty"`   // Only in the moderation queue
+	SpamReasons   []string               `json:"spam_reasons,omitempty"` // Only in the moderation queue
 }
 
 type FeedbackComment struct {
@@ -37,11 +49,22 @@ type FeedbackComment struct {
 	UpdatedAt  time.Time `json:"updated_at"`
 }
 
+// FeedbackAttachment is a file uploaded for a feedback item. FileURL and ThumbnailKey hold
+// storage keys (not public URLs); clients receive short-lived signed DownloadURL and ThumbnailURL.
+// Images are processed in the background: ProcessingStatus tracks metadata stripping,
+// thumbnail generation and measuring Width and Height.
 type FeedbackAttachment struct {
-	ID         uuid.UUID `json:"id"`
-	FeedbackID uuid.UUID `json:"feedback_id"`
-	FileURL    string    `json:"file_url"`
-	FileType   string    `json:"file_type"`
-	FileSize   int64     `json:"file_size"`
-	CreatedAt  time.Time `json:"created_at"`
+	ID               uuid.UUID `json:"id"`
+	FeedbackID       uuid.UUID `json:"feedback_id"`
+	FileURL          string    `json:"-"`
+	FileName         string    `json:"file_name"`
+	FileType         string    `json:"file_type"`
+	FileSize         int64     `json:"file_size"`
+	Width            *int      `json:"width,omitempty"`
+	Height           *int      `json:"height,omitempty"`
+	ProcessingStatus string    `json:"processing_status"`
+	ThumbnailKey     string    `json:"-"`
+	DownloadURL      string    `json:"download_url,omitempty"`
+	ThumbnailURL     string    `json:"thumbnail_url,omitempty"`
+	CreatedAt        time.Time `json:"created_at"`
 }
@@ -0,0 +1,59 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// RoleOwner is a per-application role: owners manage an application's settings, webhooks and members
+const RoleOwner = "owner"
+
+// roleRanks orders global and per-application roles by privilege
+var roleRanks = map[string]int{
+	RoleViewer:  1,
+	RoleTriager: 2,
+	RoleOwner:   3,
+	RoleAdmin:   4,
+}
+
+// IsValidMemberRole reports whether role can be assigned to an application member
+func IsValidMemberRole(role string) bool {
+	return role == RoleViewer || role == RoleTriager || role == RoleOwner
+}
+
+// HigherRole returns the more privileged of two roles
+func HigherRole(a, b string) string {
+	if roleRanks[b] > roleRanks[a] {
+		return b
+	}
+	return a
+}
+
+// RoleAtLeast reports whether role is at least as privileged as min
+func RoleAtLeast(role, min string) bool {
+	return roleRanks[role] >= roleRanks[min]
+}
+
+// ApplicationMember grants a user access to an application
+type ApplicationMember struct {
+	ApplicationID uuid.UUID  `json:"application_id"`
+	UserID        uuid.UUID  `json:"user_id"`
+	Email         string     `json:"email"`
+	Name          string     `json:"name"`
+	Role          string     `json:"role"`
+	AddedBy       *uuid.UUID `json:"added_by,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+}
+
+// ApplicationInvitation is a pending membership for an email address that hasn't signed in yet.
+// It is turned into a membership the first time a user with that email signs in.
+type ApplicationInvitation struct {
+	ID            uuid.UUID  `json:"id"`
+	ApplicationID uuid.UUID  `json:"application_id"`
+	Email         string     `json:"email"`
+	Role          string     `json:"role"`
+	InvitedBy     *uuid.UUID `json:"invited_by,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+}
@@ -0,0 +1,77 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Personal access token scopes. Each admin API resource has a read scope (GET requests)
+// and a write scope, which includes read. The audit log is read-only.
+const (
+	TokenScopeFeedbackRead      = "feedback:read"
+	TokenScopeFeedbackWrite     = "feedback:write"
+	TokenScopeApplicationsRead  = "applications:read"
+	TokenScopeApplicationsWrite = "applications:write"
+	TokenScopeUsersRead         = "users:read"
+	TokenScopeUsersWrite        = "users:write"
+	TokenScopeAuthzRead         = "authz:read"
+	TokenScopeAuthzWrite        = "authz:write"
+	TokenScopeAuditRead         = "audit:read"
+)
+
+// TokenScopes lists every scope a personal access token can be granted
+var TokenScopes = []string{
+	TokenScopeFeedbackRead, TokenScopeFeedbackWrite,
+	TokenScopeApplicationsRead, TokenScopeApplicationsWrite,
+	TokenScopeUsersRead, TokenScopeUsersWrite,
+	TokenScopeAuthzRead, TokenScopeAuthzWrite,
+	TokenScopeAuditRead,
+}
+
+// IsValidTokenScope reports whether scope is a known personal access token scope
+func IsValidTokenScope(scope string) bool {
+	for _, s := range TokenScopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+const (
+	// DefaultTokenLifetime is how long a personal access token lasts without an explicit expiry
+	DefaultTokenLifetime = 90 * 24 * time.Hour
+
+	// MaxTokenLifetime bounds a personal access token's expiry
+	MaxTokenLifetime = 365 * 24 * time.Hour
+)
+
+// PersonalAccessToken lets a user call the admin API from scripts with their own
+// permissions, limited to Scopes. Only its prefix and hash are stored; Token is set
+// when the token is created.
+type PersonalAccessToken struct {
+	ID         uuid.UUID  `json:"id"`
+	UserID     uuid.UUID  `json:"user_id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	Token      string     `json:"token,omitempty"`
+	Scopes     []string   `json:"scopes"`
+	Status     string     `json:"status"`
+	ExpiresAt  time.Time  `json:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// SetStatus derives the token's status (active, expired or revoked, as for API keys)
+func (t *PersonalAccessToken) SetStatus(now time.Time) {
+	switch {
+	case t.RevokedAt != nil:
+		t.Status = APIKeyStatusRevoked
+	case !t.ExpiresAt.After(now):
+		t.Status = APIKeyStatusExpired
+	default:
+		t.Status = APIKeyStatusActive
+	}
+}
@@ -0,0 +1,14 @@
+package models
+
+import "time"
+
+// ProofOfWorkChallenge is a challenge a client solves before submitting feedback: a
+// solution is any string for which the Algorithm hash of "<challenge>:<solution>" starts
+// with Difficulty zero bits. Required is false for applications that don't ask for it.
+type ProofOfWorkChallenge struct {
+	Challenge  string    `json:"challenge"`
+	Algorithm  string    `json:"algorithm"`
+	Difficulty int       `json:"difficulty"`
+	ExpiresAt  time.Time `json:"expires_at"`
+	Required   bool      `json:"required"`
+}
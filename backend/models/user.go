@@ -6,15 +6,28 @@ import (
 	"github.com/google/uuid"
 )
 
+// User roles, from least to most privileged. Each role inherits the permissions of the one before it.
+const (
+	RoleViewer  = "viewer"
+	RoleTriager = "triager"
+	RoleAdmin   = "admin"
+)
+
+// IsValidUserRole reports whether role can be assigned to a user globally
+func IsValidUserRole(role string) bool {
+	return role == RoleViewer || role == RoleTriager || role == RoleAdmin
+}
+
 // User represents a user synced from auth-service
 type User struct {
-	ID        uuid.UUID  `json:"id"`
-	Email     string     `json:"email"`
-	Name      string     `json:"name"`
-	GoogleID  *string    `json:"google_id,omitempty"`
-	AvatarURL *string    `json:"avatar_url,omitempty"`
-	IsActive  bool       `json:"is_active"`
-	IsAdmin   bool       `json:"is_admin"`
-	CreatedAt time.Time  `json:"created_at"`
-	UpdatedAt time.Time  `json:"updated_at"`
+	ID         uuid.UUID  `json:"id"`
+	Email      string     `json:"email"`
+	Name       string     `json:"name"`
+	GoogleID   *string    `json:"google_id,omitempty"`
+	AvatarURL  *string    `json:"avatar_url,omitempty"`
+	IsActive   bool       `json:"is_active"`
+	Role       string     `json:"role"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+	LastSeenAt *time.Time `json:"last_seen_at,omitempty"` // Last authenticated request
 }
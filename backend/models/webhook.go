@@ -0,0 +1,64 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// WebhookEndpoint is a URL that receives an application's webhook events
+type WebhookEndpoint struct {
+	ID            uuid.UUID `json:"id"`
+	ApplicationID uuid.UUID `json:"application_id"`
+	URL           string    `json:"url"`
+	Secret        string    `json:"secret,omitempty"`
+	Description   string    `json:"description"`
+	Enabled       bool      `json:"enabled"`
+	EventTypes    []string  `json:"event_types"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
+// WebhookEvent is the JSON envelope POSTed to a webhook endpoint
+type WebhookEvent struct {
+	ID            uuid.UUID   `json:"id"`
+	Type          string      `json:"type"`
+	ApplicationID uuid.UUID   `json:"application_id"`
+	CreatedAt     time.Time   `json:"created_at"`
+	Data          interface{} `json:"data"`
+}
+
+// WebhookDelivery tracks an event queued for delivery to a webhook endpoint
+type WebhookDelivery struct {
+	ID                 uuid.UUID                `json:"id"`
+	ApplicationID      uuid.UUID                `json:"application_id"`
+	EndpointID         *uuid.UUID               `json:"endpoint_id,omitempty"`
+	EventID            uuid.UUID                `json:"event_id"`
+	EventType          string                   `json:"event_type"`
+	URL                string                   `json:"url"`
+	Payload            json.RawMessage          `json:"payload,omitempty"`
+	Status             string                   `json:"status"`
+	Attempts           int                      `json:"attempts"`
+	NextAttemptAt      *time.Time               `json:"next_attempt_at,omitempty"`
+	LastResponseStatus *int                     `json:"last_response_status,omitempty"`
+	LastLatencyMs      *int                     `json:"last_latency_ms,omitempty"`
+	LastError          *string                  `json:"last_error,omitempty"`
+	RedeliveryOf       *uuid.UUID               `json:"redelivery_of,omitempty"`
+	CreatedAt          time.Time                `json:"created_at"`
+	UpdatedAt          time.Time                `json:"updated_at"`
+	CompletedAt        *time.Time               `json:"completed_at,omitempty"`
+	AttemptLog         []WebhookDeliveryAttempt `json:"attempt_log,omitempty"`
+}
+
+// WebhookDeliveryAttempt records a single HTTP attempt for a delivery
+type WebhookDeliveryAttempt struct {
+	ID             int64     `json:"id"`
+	DeliveryID     uuid.UUID `json:"delivery_id"`
+	Attempt        int       `json:"attempt"`
+	ResponseStatus *int      `json:"response_status,omitempty"`
+	ResponseBody   *string   `json:"response_body,omitempty"`
+	LatencyMs      int       `json:"latency_ms"`
+	Error          *string   `json:"error,omitempty"`
+	CreatedAt      time.Time `json:"created_at"`
+}
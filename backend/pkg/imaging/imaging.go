@@ -0,0 +1,155 @@
+package imaging
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	_ "image/gif" // register GIF decoder
+	"image/jpeg"
+	_ "image/png" // register PNG decoder
+
+	xdraw "golang.org/x/image/draw"
+	_ "golang.org/x/image/webp" // register WebP decoder
+)
+
+// MaxPixels bounds the decoded size of an image to guard against decompression bombs
+const MaxPixels = 40_000_000
+
+// ErrTooLarge is returned for images whose dimensions exceed MaxPixels
+var ErrTooLarge = errors.New("image dimensions too large")
+
+// Result is the outcome of processing an uploaded image
+type Result struct {
+	// Data is the original image with metadata removed
+	Data []byte
+	// Width and Height are the display dimensions (after applying EXIF orientation)
+	Width  int
+	Height int
+	// Thumbnail is a JPEG that fits within the requested bounding box
+	Thumbnail []byte
+}
+
+// Process removes EXIF/XMP metadata from an image, measures it and renders a thumbnail
+// no larger than thumbSize on either side. PNG, JPEG and WebP metadata is stripped
+// losslessly where possible; JPEGs with a non-default EXIF orientation are re-encoded
+// upright so they still display correctly without the tag. GIFs carry no EXIF data, but
+// comments and XMP in application extensions are removed.
+func Process(data []byte, thumbSize int) (*Result, error) {
+	cfg, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read image header: %w", err)
+	}
+	if cfg.Width <= 0 || cfg.Height <= 0 || cfg.Width*cfg.Height > MaxPixels {
+		return nil, ErrTooLarge
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode image: %w", err)
+	}
+
+	var cleaned []byte
+	switch format {
+	case "jpeg":
+		if orientation := jpegOrientation(data); orientation > 1 {
+			img = orient(img, orientation)
+			cleaned, err = encodeJPEG(img, 92)
+		} else {
+			cleaned, err = stripJPEG(data)
+		}
+	case "png":
+		cleaned, err = stripPNG(data)
+	case "webp":
+		cleaned, err = stripWebP(data)
+	case "gif":
+		cleaned, err = stripGIF(data)
+	default:
+		return nil, fmt.Errorf("unsupported image format %q", format)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	thumbnail, err := encodeJPEG(Thumbnail(img, thumbSize), 80)
+	if err != nil {
+		return nil, err
+	}
+
+	bounds := img.Bounds()
+	return &Result{
+		Data:      cleaned,
+		Width:     bounds.Dx(),
+		Height:    bounds.Dy(),
+		Thumbnail: thumbnail,
+	}, nil
+}
+
+// Thumbnail scales img to fit within size x size, flattening transparency onto white.
+// Images that already fit are not upscaled.
+func Thumbnail(img image.Image, size int) image.Image {
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	if width > size || height > size {
+		if width >= height {
+			height = max(1, height*size/width)
+			width = size
+		} else {
+			width = max(1, width*size/height)
+			height = size
+		}
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.Draw(dst, dst.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+	xdraw.CatmullRom.Scale(dst, dst.Bounds(), img, bounds, draw.Over, nil)
+	return dst
+}
+
+func encodeJPEG(img image.Image, quality int) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: quality}); err != nil {
+		return nil, fmt.Errorf("failed to encode JPEG: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// orient applies an EXIF orientation (2-8) so the image displays upright
+func orient(img image.Image, orientation int) image.Image {
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+
+	dw, dh := w, h
+	if orientation >= 5 {
+		dw, dh = h, w
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, dw, dh))
+
+	for y := 0; y < dh; y++ {
+		for x := 0; x < dw; x++ {
+			var sx, sy int
+			switch orientation {
+			case 2: // mirrored horizontally
+				sx, sy = w-1-x, y
+			case 3: // rotated 180
+				sx, sy = w-1-x, h-1-y
+			case 4: // mirrored vertically
+				sx, sy = x, h-1-y
+			case 5: // transposed
+				sx, sy = y, x
+			case 6: // rotated 90 clockwise
+				sx, sy = y, h-1-x
+			case 7: // transversed
+				sx, sy = w-1-y, h-1-x
+			case 8: // rotated 90 counter-clockwise
+				sx, sy = w-1-y, x
+			default:
+				sx, sy = x, y
+			}
+			dst.Set(x, y, img.At(b.Min.X+sx, b.Min.Y+sy))
+		}
+	}
+	return dst
+}
@@ -0,0 +1,312 @@
+package imaging
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+)
+
+var errMalformed = errors.New("malformed image data")
+
+// stripJPEG drops APPn and comment segments that can carry EXIF, XMP, IPTC or free text,
+// keeping JFIF, ICC color profiles and the Adobe color transform marker. Entropy-coded
+// data is copied verbatim, so the image is not re-encoded.
+func stripJPEG(data []byte) ([]byte, error) {
+	if len(data) < 4 || data[0] != 0xFF || data[1] != 0xD8 {
+		return nil, errMalformed
+	}
+
+	out := bytes.NewBuffer(make([]byte, 0, len(data)))
+	out.Write(data[:2])
+
+	i := 2
+	for i < len(data) {
+		if data[i] != 0xFF {
+			return nil, errMalformed
+		}
+		// Skip fill bytes
+		for i < len(data) && data[i] == 0xFF {
+			i++
+		}
+		if i >= len(data) {
+			return nil, errMalformed
+		}
+		marker := data[i]
+		i++
+
+		// Standalone markers have no length
+		if marker == 0x01 || (marker >= 0xD0 && marker <= 0xD7) {
+			out.Write([]byte{0xFF, marker})
+			continue
+		}
+		if marker == 0xD9 {
+			out.Write([]byte{0xFF, marker})
+			return out.Bytes(), nil
+		}
+
+		if i+2 > len(data) {
+			return nil, errMalformed
+		}
+		length := int(binary.BigEndian.Uint16(data[i:]))
+		if length < 2 || i+length > len(data) {
+			return nil, errMalformed
+		}
+		segment := data[i+2 : i+length]
+
+		// Start of scan: the rest of the file is image data
+		if marker == 0xDA {
+			out.Write(data[i-2:])
+			return out.Bytes(), nil
+		}
+
+		if keepJPEGSegment(marker, segment) {
+			out.Write(data[i-2 : i+length])
+		}
+		i += length
+	}
+
+	return out.Bytes(), nil
+}
+
+func keepJPEGSegment(marker byte, segment []byte) bool {
+	switch {
+	case marker == 0xE0: // APP0 (JFIF)
+		return true
+	case marker == 0xE2: // APP2, only ICC profiles
+		return bytes.HasPrefix(segment, []byte("ICC_PROFILE\x00"))
+	case marker == 0xEE: // APP14 (Adobe color transform)
+		return bytes.HasPrefix(segment, []byte("Adobe"))
+	case marker >= 0xE1 && marker <= 0xEF: // other APPn (EXIF, XMP, IPTC, ...)
+		return false
+	case marker == 0xFE: // comment
+		return false
+	}
+	return true
+}
+
+// jpegOrientation returns the EXIF orientation tag (1-8) of a JPEG, or 0 if absent
+func jpegOrientation(data []byte) int {
+	i := 2
+	for i+4 <= len(data) && data[i] == 0xFF {
+		marker := data[i+1]
+		if marker == 0xDA || marker == 0xD9 {
+			return 0
+		}
+		length := int(binary.BigEndian.Uint16(data[i+2:]))
+		if length < 2 || i+2+length > len(data) {
+			return 0
+		}
+		segment := data[i+4 : i+2+length]
+		if marker == 0xE1 && bytes.HasPrefix(segment, []byte("Exif\x00\x00")) {
+			return exifOrientation(segment[6:])
+		}
+		i += 2 + length
+	}
+	return 0
+}
+
+// exifOrientation reads the orientation tag from IFD0 of a TIFF-structured EXIF block
+func exifOrientation(tiff []byte) int {
+	if len(tiff) < 8 {
+		return 0
+	}
+
+	var order binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return 0
+	}
+
+	offset := int(order.Uint32(tiff[4:]))
+	if offset < 8 || offset+2 > len(tiff) {
+		return 0
+	}
+	entries := int(order.Uint16(tiff[offset:]))
+	for n := 0; n < entries; n++ {
+		entry := offset + 2 + n*12
+		if entry+12 > len(tiff) {
+			return 0
+		}
+		if order.Uint16(tiff[entry:]) == 0x0112 {
+			orientation := int(order.Uint16(tiff[entry+8:]))
+			if orientation < 1 || orientation > 8 {
+				return 0
+			}
+			return orientation
+		}
+	}
+	return 0
+}
+
+// pngMetadataChunks are ancillary chunks that can hold EXIF data, text or timestamps
+var pngMetadataChunks = map[string]bool{
+	"eXIf": true,
+	"tEXt": true,
+	"zTXt": true,
+	"iTXt": true,
+	"tIME": true,
+}
+
+// stripPNG removes metadata chunks from a PNG without touching image data
+func stripPNG(data []byte) ([]byte, error) {
+	const signature = "\x89PNG\r\n\x1a\n"
+	if !bytes.HasPrefix(data, []byte(signature)) {
+		return nil, errMalformed
+	}
+
+	out := bytes.NewBuffer(make([]byte, 0, len(data)))
+	out.WriteString(signature)
+
+	i := len(signature)
+	for i+8 <= len(data) {
+		length := int(binary.BigEndian.Uint32(data[i:]))
+		chunkType := string(data[i+4 : i+8])
+		end := i + 12 + length
+		if length < 0 || end > len(data) {
+			return nil, errMalformed
+		}
+		if !pngMetadataChunks[chunkType] {
+			out.Write(data[i:end])
+		}
+		i = end
+		if chunkType == "IEND" {
+			break
+		}
+	}
+
+	return out.Bytes(), nil
+}
+
+// stripWebP removes EXIF and XMP chunks from a WebP container and clears their VP8X flags
+func stripWebP(data []byte) ([]byte, error) {
+	if len(data) < 12 || string(data[:4]) != "RIFF" || string(data[8:12]) != "WEBP" {
+		return nil, errMalformed
+	}
+
+	out := bytes.NewBuffer(make([]byte, 0, len(data)))
+	out.Write(data[:12])
+
+	i := 12
+	for i+8 <= len(data) {
+		fourCC := string(data[i : i+4])
+		size := int(binary.LittleEndian.Uint32(data[i+4:]))
+		end := i + 8 + size + size%2
+		if size < 0 || end > len(data) {
+			return nil, errMalformed
+		}
+
+		switch fourCC {
+		case "EXIF", "XMP ":
+		case "VP8X":
+			chunk := append([]byte(nil), data[i:end]...)
+			if size > 0 {
+				chunk[8] &^= 0x08 | 0x04 // EXIF and XMP present flags
+			}
+			out.Write(chunk)
+		default:
+			out.Write(data[i:end])
+		}
+		i = end
+	}
+
+	result := out.Bytes()
+	binary.LittleEndian.PutUint32(result[4:], uint32(len(result)-8))
+	return result, nil
+}
+
+// gifKeptApplications are the GIF application extensions kept when stripping metadata;
+// they control animation looping
+var gifKeptApplications = map[string]bool{
+	"NETSCAPE2.0": true,
+	"ANIMEXTS1.0": true,
+}
+
+// stripGIF removes comment extensions and application extensions other than animation
+// control (which is where XMP lives) from a GIF, copying image data verbatim
+func stripGIF(data []byte) ([]byte, error) {
+	if len(data) < 13 || (string(data[:6]) != "GIF87a" && string(data[:6]) != "GIF89a") {
+		return nil, errMalformed
+	}
+
+	// Header, logical screen descriptor and global color table
+	i := 13
+	if flags := data[10]; flags&0x80 != 0 {
+		i += 3 << ((flags & 0x07) + 1)
+	}
+	if i > len(data) {
+		return nil, errMalformed
+	}
+
+	out := bytes.NewBuffer(make([]byte, 0, len(data)))
+	out.Write(data[:i])
+
+	for i < len(data) {
+		start := i
+		switch data[i] {
+		case 0x3B: // trailer
+			out.WriteByte(0x3B)
+			return out.Bytes(), nil
+
+		case 0x2C: // image descriptor, local color table, LZW code size and image data
+			if i+11 > len(data) {
+				return nil, errMalformed
+			}
+			if flags := data[i+9]; flags&0x80 != 0 {
+				i += 3 << ((flags & 0x07) + 1)
+			}
+			end, err := skipGIFSubBlocks(data, i+11)
+			if err != nil {
+				return nil, err
+			}
+			out.Write(data[start:end])
+			i = end
+
+		case 0x21: // extension
+			if i+2 > len(data) {
+				return nil, errMalformed
+			}
+			end, err := skipGIFSubBlocks(data, i+2)
+			if err != nil {
+				return nil, err
+			}
+			keep := true
+			switch data[i+1] {
+			case 0xFE: // comment
+				keep = false
+			case 0xFF: // application
+				keep = i+14 <= end && data[i+2] == 11 && gifKeptApplications[string(data[i+3:i+14])]
+			}
+			if keep {
+				out.Write(data[start:end])
+			}
+			i = end
+
+		default:
+			return nil, errMalformed
+		}
+	}
+
+	// Tolerate a missing trailer, as decoders do
+	out.WriteByte(0x3B)
+	return out.Bytes(), nil
+}
+
+// skipGIFSubBlocks returns the offset just past the data sub-blocks starting at i,
+// including the zero-length terminator
+func skipGIFSubBlocks(data []byte, i int) (int, error) {
+	for {
+		if i >= len(data) {
+			return 0, errMalformed
+		}
+		size := int(data[i])
+		i++
+		if size == 0 {
+			return i, nil
+		}
+		i += size
+	}
+}
@@ -1,7 +1,8 @@
 package jwt
 
 import (
-	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 
 	"github.com/golang-jwt/jwt/v5"
@@ -16,14 +17,24 @@ type Claims struct {
 	jwt.RegisteredClaims
 }
 
-// ValidateAccessToken validates a JWT access token using RSA-256 public key
-func ValidateAccessToken(tokenString string, publicKey *rsa.PublicKey) (*Claims, error) {
+// ValidateAccessToken validates a JWT access token against the RSA public key named by
+// its kid header, or against every key in the set if it has none
+func ValidateAccessToken(tokenString string, keys *KeySet) (*Claims, error) {
 	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, func(token *jwt.Token) (interface{}, error) {
 		// Verify signing method is RS256
 		if _, ok := token.Method.(*jwt.SigningMethodRSA); !ok {
 			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
 		}
-		return publicKey, nil
+
+		if kid, ok := token.Header["kid"].(string); ok && kid != "" {
+			return keys.Key(kid)
+		}
+
+		set := jwt.VerificationKeySet{}
+		for _, key := range keys.All() {
+			set.Keys = append(set.Keys, key)
+		}
+		return set, nil
 	})
 
 	if err != nil {
@@ -36,3 +47,13 @@ func ValidateAccessToken(tokenString string, publicKey *rsa.PublicKey) (*Claims,
 
 	return nil, fmt.Errorf("invalid token")
 }
+
+// TokenID identifies a token for revocation: its jti claim, or a hash of the token
+// if the issuer doesn't set one
+func (c *Claims) TokenID(tokenString string) string {
+	if c.ID != "" {
+		return c.ID
+	}
+	sum := sha256.Sum256([]byte(tokenString))
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
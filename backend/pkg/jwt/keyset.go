@@ -0,0 +1,328 @@
+package jwt
+
+import (
+	"bytes"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math/big"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// maxKeySetSize bounds how much of a key endpoint's response is read
+const maxKeySetSize = 1 << 20
+
+// ErrUnknownKey is returned when a token's kid isn't in the key set, even after a refetch
+var ErrUnknownKey = errors.New("unknown signing key")
+
+// KeySetConfig configures where a KeySet loads its verification keys from
+type KeySetConfig struct {
+	// URL serves either a JWKS document or a single PEM public key
+	URL string
+
+	// StaticKeyFile is a PEM public key or JWKS document used when neither the URL nor
+	// the cache file is available at startup
+	StaticKeyFile string
+
+	// CacheFile stores the last key set fetched from URL, so a restart while the
+	// auth-service is down can use it
+	CacheFile string
+
+	// RefreshInterval is how often keys are refetched in the background
+	RefreshInterval time.Duration
+
+	// MinRefetchInterval limits how often a token with an unknown kid triggers a refetch
+	MinRefetchInterval time.Duration
+
+	HTTPClient *http.Client
+}
+
+// KeySet holds the RSA public keys access tokens can be signed with, keyed by kid.
+// Keys from a PEM source have an empty kid.
+type KeySet struct {
+	cfg KeySetConfig
+
+	mu          sync.RWMutex
+	keys        map[string]*rsa.PublicKey
+	lastFetched time.Time
+
+	// fetchMu serializes fetches so a burst of unknown kids causes one request
+	fetchMu     sync.Mutex
+	lastAttempt time.Time
+}
+
+// NewKeySet loads the initial keys from the URL, falling back to the cache file and then
+// the static key file. It only fails if none of them has a usable key.
+func NewKeySet(cfg KeySetConfig) (*KeySet, error) {
+	if cfg.HTTPClient == nil {
+		cfg.HTTPClient = &http.Client{Timeout: 10 * time.Second}
+	}
+	if cfg.RefreshInterval <= 0 {
+		cfg.RefreshInterval = 10 * time.Minute
+	}
+	if cfg.MinRefetchInterval <= 0 {
+		cfg.MinRefetchInterval = 30 * time.Second
+	}
+
+	ks := &KeySet{cfg: cfg, keys: map[string]*rsa.PublicKey{}}
+
+	err := ks.Refresh()
+	if err == nil {
+		return ks, nil
+	}
+	log.Printf("[AUTH] Failed to fetch JWT keys from %s: %v", cfg.URL, err)
+
+	for _, file := range []string{cfg.CacheFile, cfg.StaticKeyFile} {
+		if file == "" {
+			continue
+		}
+		data, readErr := os.ReadFile(file)
+		if readErr != nil {
+			if !os.IsNotExist(readErr) {
+				log.Printf("[AUTH] Failed to read JWT keys from %s: %v", file, readErr)
+			}
+			continue
+		}
+		keys, parseErr := ParseKeys(data)
+		if parseErr != nil {
+			log.Printf("[AUTH] Failed to parse JWT keys from %s: %v", file, parseErr)
+			continue
+		}
+		ks.keys = keys
+		log.Printf("[AUTH] Using %d JWT key(s) from %s until the auth-service is reachable", len(keys), file)
+		return ks, nil
+	}
+
+	return nil, fmt.Errorf("no JWT keys available: %w", err)
+}
+
+// StartRefresh refetches keys in the background every RefreshInterval. Failed refreshes
+// keep the last known good keys.
+func (ks *KeySet) StartRefresh() {
+	go func() {
+		ticker := time.NewTicker(ks.cfg.RefreshInterval)
+		defer ticker.Stop()
+		for range ticker.C {
+			if err := ks.Refresh(); err != nil {
+				log.Printf("[AUTH] Failed to refresh JWT keys, keeping %d cached key(s): %v", ks.Len(), err)
+			}
+		}
+	}()
+}
+
+// Refresh fetches the keys from the URL and replaces the current set
+func (ks *KeySet) Refresh() error {
+	ks.fetchMu.Lock()
+	defer ks.fetchMu.Unlock()
+	return ks.fetch()
+}
+
+// fetch must be called with fetchMu held
+func (ks *KeySet) fetch() error {
+	ks.lastAttempt = time.Now()
+
+	if ks.cfg.URL == "" {
+		return errors.New("no key URL configured")
+	}
+
+	resp, err := ks.cfg.HTTPClient.Get(ks.cfg.URL)
+	if err != nil {
+		return fmt.Errorf("failed to fetch keys: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to fetch keys: status %d", resp.StatusCode)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxKeySetSize))
+	if err != nil {
+		return fmt.Errorf("failed to read keys: %w", err)
+	}
+
+	keys, err := ParseKeys(data)
+	if err != nil {
+		return err
+	}
+
+	ks.mu.Lock()
+	ks.keys = keys
+	ks.lastFetched = time.Now()
+	ks.mu.Unlock()
+
+	if ks.cfg.CacheFile != "" {
+		if err := writeFileAtomic(ks.cfg.CacheFile, data); err != nil {
+			log.Printf("[AUTH] Failed to cache JWT keys: %v", err)
+		}
+	}
+
+	return nil
+}
+
+// Len returns the number of keys in the set
+func (ks *KeySet) Len() int {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+	return len(ks.keys)
+}
+
+// LastFetched returns when keys were last fetched successfully (zero if they came from a file)
+func (ks *KeySet) LastFetched() time.Time {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+	return ks.lastFetched
+}
+
+// Key returns the key for a kid. An unknown kid usually means the auth-service rotated
+// its keys, so the set is refetched (at most once per MinRefetchInterval) before giving up.
+// A PEM key has no kid and is used for any token.
+func (ks *KeySet) Key(kid string) (*rsa.PublicKey, error) {
+	if key := ks.lookup(kid); key != nil {
+		return key, nil
+	}
+
+	ks.fetchMu.Lock()
+	// Another request may have refetched while we waited
+	if key := ks.lookup(kid); key != nil {
+		ks.fetchMu.Unlock()
+		return key, nil
+	}
+	if time.Since(ks.lastAttempt) >= ks.cfg.MinRefetchInterval {
+		if err := ks.fetch(); err != nil {
+			log.Printf("[AUTH] Failed to refetch JWT keys for kid %q: %v", kid, err)
+		}
+	}
+	ks.fetchMu.Unlock()
+
+	if key := ks.lookup(kid); key != nil {
+		return key, nil
+	}
+	if key := ks.lookup(""); key != nil {
+		return key, nil
+	}
+	return nil, ErrUnknownKey
+}
+
+// All returns every key in the set, for tokens without a kid
+func (ks *KeySet) All() []*rsa.PublicKey {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+
+	keys := make([]*rsa.PublicKey, 0, len(ks.keys))
+	for _, key := range ks.keys {
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+func (ks *KeySet) lookup(kid string) *rsa.PublicKey {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+	return ks.keys[kid]
+}
+
+// jwk is the subset of an RFC 7517 JSON Web Key needed for RSA signature verification
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+}
+
+// ParseKeys parses a JWKS document or a PEM public key (PKCS#1 or PKIX) into keys by kid
+func ParseKeys(data []byte) (map[string]*rsa.PublicKey, error) {
+	trimmed := bytes.TrimSpace(data)
+	if len(trimmed) > 0 && trimmed[0] == '{' {
+		return parseJWKS(trimmed)
+	}
+
+	block, _ := pem.Decode(trimmed)
+	if block == nil {
+		return nil, fmt.Errorf("failed to decode PEM block")
+	}
+
+	key, err := parsePEMKey(block)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]*rsa.PublicKey{"": key}, nil
+}
+
+func parsePEMKey(block *pem.Block) (*rsa.PublicKey, error) {
+	if key, err := x509.ParsePKCS1PublicKey(block.Bytes); err == nil {
+		return key, nil
+	}
+
+	parsed, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse public key: %w", err)
+	}
+	key, ok := parsed.(*rsa.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("public key is not an RSA key")
+	}
+	return key, nil
+}
+
+func parseJWKS(data []byte) (map[string]*rsa.PublicKey, error) {
+	var doc struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("failed to parse JWKS: %w", err)
+	}
+
+	keys := map[string]*rsa.PublicKey{}
+	for _, k := range doc.Keys {
+		// Skip keys we can't verify with rather than rejecting the whole set
+		if k.Kty != "RSA" || (k.Use != "" && k.Use != "sig") {
+			continue
+		}
+		n, errN := base64.RawURLEncoding.DecodeString(k.N)
+		e, errE := base64.RawURLEncoding.DecodeString(k.E)
+		if errN != nil || errE != nil || len(n) == 0 || len(e) == 0 || len(e) > 4 {
+			log.Printf("[AUTH] Skipping malformed JWK %q", k.Kid)
+			continue
+		}
+		keys[k.Kid] = &rsa.PublicKey{
+			N: new(big.Int).SetBytes(n),
+			E: int(new(big.Int).SetBytes(e).Int64()),
+		}
+	}
+
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("JWKS has no usable RSA signing keys")
+	}
+	return keys, nil
+}
+
+func writeFileAtomic(path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".jwks-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,22 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+)
+
+// ErrNotFound is returned when an object doesn't exist in the backend
+var ErrNotFound = errors.New("storage: object not found")
+
+// Storage is a blob store addressed by slash-separated keys
+type Storage interface {
+	// Put stores data under key, replacing any existing object
+	Put(ctx context.Context, key string, data []byte, contentType string) error
+
+	// Get opens the object stored under key, returning ErrNotFound if it doesn't exist
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+
+	// Delete removes the object stored under key; deleting a missing object is not an error
+	Delete(ctx context.Context, key string) error
+}
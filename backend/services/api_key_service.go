@@ -0,0 +1,81 @@
+package services
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base32"
+	"encoding/base64"
+	"encoding/hex"
+	"strings"
+)
+
+const (
+	// apiKeyPrefixLength is how many leading characters of a key are stored in the clear
+	apiKeyPrefixLength = 12
+
+	// apiKeyTag marks keys issued by this service, e.g. fbk_abcd2345_<secret>
+	apiKeyTag = "fbk_"
+
+	// personalAccessTokenTag marks personal access tokens, e.g. fbp_abcd2345_<secret>
+	personalAccessTokenTag = "fbp_"
+)
+
+// GeneratedAPIKey is a new API key. Key is only available at generation time;
+// only Prefix and Hash are stored.
+type GeneratedAPIKey struct {
+	Key    string
+	Prefix string
+	Hash   string
+}
+
+// GenerateAPIKey creates a random API key with a lookup prefix
+func GenerateAPIKey() (*GeneratedAPIKey, error) {
+	return generateKey(apiKeyTag)
+}
+
+// GeneratePersonalAccessToken creates a random personal access token with a lookup prefix.
+// Tokens have the same format as API keys with a different tag, and are hashed the same way.
+func GeneratePersonalAccessToken() (*GeneratedAPIKey, error) {
+	return generateKey(personalAccessTokenTag)
+}
+
+// IsPersonalAccessToken reports whether a bearer token is a personal access token rather than a JWT
+func IsPersonalAccessToken(token string) bool {
+	return strings.HasPrefix(token, personalAccessTokenTag)
+}
+
+func generateKey(tag string) (*GeneratedAPIKey, error) {
+	id := make([]byte, 5)
+	if _, err := rand.Read(id); err != nil {
+		return nil, err
+	}
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, err
+	}
+
+	key := tag +
+		strings.ToLower(base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(id)) + "_" +
+		base64.RawURLEncoding.EncodeToString(secret)
+
+	return &GeneratedAPIKey{
+		Key:    key,
+		Prefix: APIKeyPrefix(key),
+		Hash:   HashAPIKey(key),
+	}, nil
+}
+
+// APIKeyPrefix returns the visible part of a key used to look it up
+func APIKeyPrefix(key string) string {
+	if len(key) <= apiKeyPrefixLength {
+		return key
+	}
+	return key[:apiKeyPrefixLength]
+}
+
+// HashAPIKey returns the hex SHA-256 hash a key is stored as. Keys carry 256 bits of
+// randomness, so a fast hash is enough.
+func HashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,278 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"log"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/frallan97/feedback-service/backend/database"
+	"github.com/frallan97/feedback-service/backend/pkg/imaging"
+	"github.com/google/uuid"
+)
+
+// Attachment processing states
+const (
+	ProcessingStatusPending    = "pending"
+	ProcessingStatusProcessing = "processing"
+	ProcessingStatusDone       = "done"
+	ProcessingStatusFailed     = "failed"
+	ProcessingStatusSkipped    = "skipped"
+)
+
+const (
+	// attachmentProcessingLease is how long a claimed attachment is reserved for one worker
+	attachmentProcessingLease = 5 * time.Minute
+
+	// maxProcessedFileSize bounds how much of a stored file the processor reads
+	maxProcessedFileSize = 64 << 20
+)
+
+// AttachmentProcessorConfig configures the background image processing workers
+type AttachmentProcessorConfig struct {
+	Workers       int
+	PollInterval  time.Duration
+	MaxAttempts   int
+	ThumbnailSize int
+}
+
+// attachmentJob is an image attachment claimed from the database for processing
+type attachmentJob struct {
+	id       uuid.UUID
+	key      string
+	fileType string
+	attempt  int
+}
+
+type attachmentProcessor struct {
+	cfg   AttachmentProcessorConfig
+	queue chan *attachmentJob
+	wake  chan struct{}
+}
+
+var processor *attachmentProcessor
+
+// StartAttachmentProcessor starts the background workers that strip metadata from
+// uploaded images, record their dimensions and generate thumbnails
+func StartAttachmentProcessor(cfg AttachmentProcessorConfig) {
+	if cfg.Workers < 1 {
+		cfg.Workers = 1
+	}
+	if cfg.MaxAttempts < 1 {
+		cfg.MaxAttempts = 1
+	}
+	if cfg.PollInterval <= 0 {
+		cfg.PollInterval = 5 * time.Second
+	}
+	if cfg.ThumbnailSize <= 0 {
+		cfg.ThumbnailSize = 320
+	}
+
+	processor = &attachmentProcessor{
+		cfg:   cfg,
+		queue: make(chan *attachmentJob),
+		wake:  make(chan struct{}, 1),
+	}
+
+	for i := 0; i < cfg.Workers; i++ {
+		go processor.work()
+	}
+	go processor.poll()
+
+	log.Printf("Attachment processor started with %d workers", cfg.Workers)
+}
+
+// AttachmentProcessingStatus returns the initial processing status for an uploaded file type
+func AttachmentProcessingStatus(fileType string) string {
+	if strings.HasPrefix(fileType, "image/") {
+		return ProcessingStatusPending
+	}
+	return ProcessingStatusSkipped
+}
+
+// AttachmentDownloadable reports whether an attachment in a processing status can be
+// downloaded. Images can't be until their metadata has been stripped.
+func AttachmentDownloadable(status string) bool {
+	return status == ProcessingStatusDone || status == ProcessingStatusSkipped
+}
+
+// ThumbnailKey returns the storage key of the thumbnail for an attachment's storage key
+func ThumbnailKey(key string) string {
+	return strings.TrimSuffix(key, path.Ext(key)) + "_thumb.jpg"
+}
+
+// WakeAttachmentProcessor prompts the processor to pick up new uploads without waiting for the next tick
+func WakeAttachmentProcessor() {
+	if processor == nil {
+		return
+	}
+	select {
+	case processor.wake <- struct{}{}:
+	default:
+	}
+}
+
+// poll claims pending attachments from the database and hands them to the workers
+func (p *attachmentProcessor) poll() {
+	ticker := time.NewTicker(p.cfg.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+		case <-p.wake:
+		}
+
+		for {
+			jobs, err := p.claim(p.cfg.Workers)
+			if err != nil {
+				log.Printf("[ATTACHMENTS] Failed to claim attachments for processing: %v", err)
+				break
+			}
+			for _, job := range jobs {
+				p.queue <- job
+			}
+			if len(jobs) < p.cfg.Workers {
+				break
+			}
+		}
+	}
+}
+
+// claim leases up to limit attachments awaiting processing, including ones whose
+// previous worker died before finishing
+func (p *attachmentProcessor) claim(limit int) ([]*attachmentJob, error) {
+	rows, err := database.DB.Query(`
+		UPDATE feedback_attachments
+		SET processing_status = 'processing',
+			processing_attempts = processing_attempts + 1,
+			processing_locked_until = NOW() + make_interval(secs => $2)
+		WHERE id IN (
+			SELECT id FROM feedback_attachments
+			WHERE processing_status IN ('pending', 'processing')
+			  AND (processing_locked_until IS NULL OR processing_locked_until <= NOW())
+			ORDER BY created_at
+			LIMIT $1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING id, file_url, COALESCE(file_type, ''), processing_attempts
+	`, limit, attachmentProcessingLease.Seconds())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	jobs := []*attachmentJob{}
+	for rows.Next() {
+		var job attachmentJob
+		if err := rows.Scan(&job.id, &job.key, &job.fileType, &job.attempt); err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, &job)
+	}
+
+	return jobs, rows.Err()
+}
+
+func (p *attachmentProcessor) work() {
+	for job := range p.queue {
+		p.process(job)
+	}
+}
+
+// process strips metadata from one image, stores the cleaned file and its thumbnail,
+// and records the outcome
+func (p *attachmentProcessor) process(job *attachmentJob) {
+	ctx, cancel := context.WithTimeout(context.Background(), attachmentProcessingLease)
+	defer cancel()
+
+	// Leases that expired because a worker died still count as attempts
+	if job.attempt > p.cfg.MaxAttempts {
+		p.fail(job, errors.New("too many processing attempts"), false)
+		return
+	}
+
+	data, err := readAttachment(ctx, job.key)
+	if err != nil {
+		p.fail(job, err, true)
+		return
+	}
+
+	result, err := imaging.Process(data, p.cfg.ThumbnailSize)
+	if err != nil {
+		// Undecodable or oversized images won't get better on retry
+		p.fail(job, err, false)
+		return
+	}
+
+	if !bytes.Equal(result.Data, data) {
+		if err := SaveAttachmentFile(ctx, job.key, result.Data, job.fileType); err != nil {
+			p.fail(job, err, true)
+			return
+		}
+	}
+
+	thumbnailKey := ThumbnailKey(job.key)
+	if err := SaveAttachmentFile(ctx, thumbnailKey, result.Thumbnail, "image/jpeg"); err != nil {
+		p.fail(job, err, true)
+		return
+	}
+
+	res, err := database.DB.ExecContext(ctx, `
+		UPDATE feedback_attachments
+		SET processing_status = 'done', processing_error = NULL, processing_locked_until = NULL,
+			processed_at = NOW(), width = $2, height = $3, thumbnail_key = $4, file_size = $5
+		WHERE id = $1
+	`, job.id, result.Width, result.Height, thumbnailKey, len(result.Data))
+	if err != nil {
+		log.Printf("[ATTACHMENTS] Failed to record processing of %s: %v", job.id, err)
+		return
+	}
+
+	// The attachment was deleted while we were working; don't leave files behind
+	if n, _ := res.RowsAffected(); n == 0 {
+		DeleteAttachmentFiles(ctx, []string{job.key, thumbnailKey})
+	}
+}
+
+// fail records a processing error, scheduling another attempt if the error is
+// retryable and attempts remain
+func (p *attachmentProcessor) fail(job *attachmentJob, procErr error, retryable bool) {
+	log.Printf("[ATTACHMENTS] Processing attempt %d for %s failed: %v", job.attempt, job.id, procErr)
+
+	status := ProcessingStatusFailed
+	if retryable && job.attempt < p.cfg.MaxAttempts {
+		status = ProcessingStatusPending
+	}
+	retryDelay := time.Duration(job.attempt) * time.Minute
+
+	if _, err := database.DB.Exec(`
+		UPDATE feedback_attachments
+		SET processing_status = $2, processing_error = $3,
+			processing_locked_until = NOW() + make_interval(secs => $4)
+		WHERE id = $1
+	`, job.id, status, procErr.Error(), retryDelay.Seconds()); err != nil {
+		log.Printf("[ATTACHMENTS] Failed to record processing failure for %s: %v", job.id, err)
+	}
+}
+
+func readAttachment(ctx context.Context, key string) ([]byte, error) {
+	file, err := OpenAttachmentFile(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(io.LimitReader(file, maxProcessedFileSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > maxProcessedFileSize {
+		return nil, errors.New("file too large to process")
+	}
+	return data, nil
+}
@@ -0,0 +1,154 @@
+package services
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/frallan97/feedback-service/backend/database"
+	"github.com/frallan97/feedback-service/backend/pkg/storage"
+	"github.com/google/uuid"
+)
+
+// AttachmentStorageConfig configures where attachments are stored and how download links are signed
+type AttachmentStorageConfig struct {
+	Store     storage.Storage
+	URLSecret []byte
+	URLTTL    time.Duration
+	PublicURL string
+}
+
+var attachments AttachmentStorageConfig
+
+// InitAttachmentStorage sets the backend attachment files are stored in
+func InitAttachmentStorage(cfg AttachmentStorageConfig) {
+	if cfg.URLTTL <= 0 {
+		cfg.URLTTL = 5 * time.Minute
+	}
+	cfg.PublicURL = strings.TrimRight(cfg.PublicURL, "/")
+	attachments = cfg
+}
+
+// SaveAttachmentFile writes an attachment's contents under the given storage key
+func SaveAttachmentFile(ctx context.Context, key string, data []byte, contentType string) error {
+	if attachments.Store == nil {
+		return fmt.Errorf("attachment storage not initialized")
+	}
+	return attachments.Store.Put(ctx, key, data, contentType)
+}
+
+// OpenAttachmentFile opens an attachment for reading by storage key
+func OpenAttachmentFile(ctx context.Context, key string) (io.ReadCloser, error) {
+	if attachments.Store == nil {
+		return nil, fmt.Errorf("attachment storage not initialized")
+	}
+	return attachments.Store.Get(ctx, key)
+}
+
+// DeleteAttachmentFiles removes files whose database rows are already gone, logging failures
+func DeleteAttachmentFiles(ctx context.Context, keys []string) {
+	if attachments.Store == nil {
+		return
+	}
+	for _, key := range keys {
+		if err := attachments.Store.Delete(ctx, key); err != nil {
+			log.Printf("[ATTACHMENTS] Failed to delete %s: %v", key, err)
+		}
+	}
+}
+
+// AttachmentKeysForFeedback returns the storage keys of every attachment (and thumbnail) on a feedback item
+func AttachmentKeysForFeedback(ctx context.Context, feedbackID interface{}) ([]string, error) {
+	return queryAttachmentKeys(ctx, "SELECT file_url, thumbnail_key FROM feedback_attachments WHERE feedback_id = $1", feedbackID)
+}
+
+// AttachmentKeysForApplication returns the storage keys of every attachment (and thumbnail) in an application
+func AttachmentKeysForApplication(ctx context.Context, appID interface{}) ([]string, error) {
+	return queryAttachmentKeys(ctx, `
+		SELECT a.file_url, a.thumbnail_key
+		FROM feedback_attachments a
+		JOIN feedback f ON f.id = a.feedback_id
+		WHERE f.application_id = $1
+	`, appID)
+}
+
+func queryAttachmentKeys(ctx context.Context, query string, arg interface{}) ([]string, error) {
+	rows, err := database.DB.QueryContext(ctx, query, arg)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	keys := []string{}
+	for rows.Next() {
+		var key string
+		var thumbnailKey sql.NullString
+		if err := rows.Scan(&key, &thumbnailKey); err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+		if thumbnailKey.Valid {
+			keys = append(keys, thumbnailKey.String)
+		}
+	}
+	return keys, rows.Err()
+}
+
+// AttachmentVariantThumbnail selects an attachment's generated thumbnail instead of the original file
+const AttachmentVariantThumbnail = "thumbnail"
+
+// SignedAttachmentURL returns a short-lived download link for an attachment
+func SignedAttachmentURL(attachmentID uuid.UUID) string {
+	return signedAttachmentURL(attachmentID, "")
+}
+
+// SignedAttachmentThumbnailURL returns a short-lived link to an attachment's thumbnail
+func SignedAttachmentThumbnailURL(attachmentID uuid.UUID) string {
+	return signedAttachmentURL(attachmentID, AttachmentVariantThumbnail)
+}
+
+func signedAttachmentURL(attachmentID uuid.UUID, variant string) string {
+	expires := time.Now().Add(attachments.URLTTL).Unix()
+	query := url.Values{}
+	if variant != "" {
+		query.Set("variant", variant)
+	}
+	query.Set("expires", strconv.FormatInt(expires, 10))
+	query.Set("signature", signAttachment(attachmentID, variant, expires))
+	return attachments.PublicURL + "/api/v1/attachments/" + attachmentID.String() + "?" + query.Encode()
+}
+
+// VerifyAttachmentSignature checks a download link's expiry and signature. The variant
+// is part of the signature, so a link to a thumbnail can't be used to fetch the original.
+func VerifyAttachmentSignature(attachmentID uuid.UUID, variant, expiresParam, signature string) bool {
+	expires, err := strconv.ParseInt(expiresParam, 10, 64)
+	if err != nil || time.Now().Unix() > expires {
+		return false
+	}
+	expected := signAttachment(attachmentID, variant, expires)
+	return hmac.Equal([]byte(expected), []byte(signature))
+}
+
+// AttachmentURLTTL is how long signed download links stay valid
+func AttachmentURLTTL() time.Duration {
+	return attachments.URLTTL
+}
+
+func signAttachment(attachmentID uuid.UUID, variant string, expires int64) string {
+	mac := hmac.New(sha256.New, attachments.URLSecret)
+	mac.Write([]byte(attachmentID.String()))
+	mac.Write([]byte("\n"))
+	mac.Write([]byte(variant))
+	mac.Write([]byte("\n"))
+	mac.Write([]byte(strconv.FormatInt(expires, 10)))
+	return hex.EncodeToString(mac.Sum(nil))
+}
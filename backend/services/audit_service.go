@@ -0,0 +1,134 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/frallan97/feedback-service/backend/database"
+	"github.com/frallan97/feedback-service/backend/models"
+	"github.com/google/uuid"
+)
+
+// auditRedactedColumns are left out of audit snapshots so the log never holds secrets
+const auditRedactedColumns = `'{secret,key_hash,token_hash,identity_secret,signing_secret}'::text[]`
+
+// auditEventColumns selects a models.AuditEvent, in the order ListAuditEvents scans them
+const auditEventColumns = "id, actor_id, actor_email, actor_token_id, action, target_type, target_id, application_id, before, after, ip, request_id, created_at"
+
+// AuditFilter narrows ListAuditEvents. Empty fields don't filter.
+type AuditFilter struct {
+	ActorID       *uuid.UUID
+	Action        string
+	TargetType    string
+	TargetID      string
+	ApplicationID *uuid.UUID
+	Since         *time.Time
+	Until         *time.Time
+	Limit         int
+	Offset        int
+}
+
+// RecordAuditEvent appends an event to the audit log. The change it describes has already
+// happened, so a failure is logged rather than returned.
+func RecordAuditEvent(ctx context.Context, event models.AuditEvent) {
+	_, err := database.DB.ExecContext(ctx, `
+        INSERT INTO audit_events (actor_id, actor_email, actor_token_id, action, target_type, target_id,
+                                  application_id, before, after, ip, request_id)
+        VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+    `, event.ActorID, event.ActorEmail, event.ActorTokenID, event.Action, event.TargetType, event.TargetID,
+		event.ApplicationID, nullableJSON(event.Before), nullableJSON(event.After), event.IP, event.RequestID)
+	if err != nil {
+		log.Printf("[AUDIT] Failed to record %s on %s %s: %v", event.Action, event.TargetType, event.TargetID, err)
+	}
+}
+
+// SnapshotRow returns the row of table matching where (with args) as JSON, minus secret
+// columns, for an audit event's before or after state. It returns nil if there's no such row.
+func SnapshotRow(ctx context.Context, table, where string, args ...interface{}) json.RawMessage {
+	var snapshot []byte
+	err := database.DB.QueryRowContext(ctx,
+		"SELECT to_jsonb(t) - "+auditRedactedColumns+" FROM "+table+" t WHERE "+where, args...,
+	).Scan(&snapshot)
+	if err != nil {
+		return nil
+	}
+	return snapshot
+}
+
+// ListAuditEvents returns the events matching filter, newest first, and how many match in total
+func ListAuditEvents(ctx context.Context, filter AuditFilter) ([]models.AuditEvent, int, error) {
+	where := " WHERE 1=1"
+	args := []interface{}{}
+	argPos := 1
+
+	addFilter := func(condition string, value interface{}) {
+		where += " AND " + condition + " $" + strconv.Itoa(argPos)
+		args = append(args, value)
+		argPos++
+	}
+	if filter.ActorID != nil {
+		addFilter("actor_id =", *filter.ActorID)
+	}
+	if filter.Action != "" {
+		addFilter("action =", filter.Action)
+	}
+	if filter.TargetType != "" {
+		addFilter("target_type =", filter.TargetType)
+	}
+	if filter.TargetID != "" {
+		addFilter("target_id =", filter.TargetID)
+	}
+	if filter.ApplicationID != nil {
+		addFilter("application_id =", *filter.ApplicationID)
+	}
+	if filter.Since != nil {
+		addFilter("created_at >=", *filter.Since)
+	}
+	if filter.Until != nil {
+		addFilter("created_at <", *filter.Until)
+	}
+
+	var total int
+	if err := database.DB.QueryRowContext(ctx, "SELECT COUNT(*) FROM audit_events"+where, args...).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count audit events: %w", err)
+	}
+
+	query := "SELECT " + auditEventColumns + " FROM audit_events" + where +
+		" ORDER BY created_at DESC, id DESC LIMIT $" + strconv.Itoa(argPos) + " OFFSET $" + strconv.Itoa(argPos+1)
+	args = append(args, filter.Limit, filter.Offset)
+
+	rows, err := database.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to fetch audit events: %w", err)
+	}
+	defer rows.Close()
+
+	events := []models.AuditEvent{}
+	for rows.Next() {
+		var event models.AuditEvent
+		var before, after []byte
+		if err := rows.Scan(
+			&event.ID, &event.ActorID, &event.ActorEmail, &event.ActorTokenID, &event.Action,
+			&event.TargetType, &event.TargetID, &event.ApplicationID, &before, &after,
+			&event.IP, &event.RequestID, &event.CreatedAt,
+		); err != nil {
+			return nil, 0, fmt.Errorf("failed to scan audit event: %w", err)
+		}
+		event.Before = before
+		event.After = after
+		events = append(events, event)
+	}
+	return events, total, rows.Err()
+}
+
+// nullableJSON stores an empty snapshot as SQL NULL rather than invalid JSON
+func nullableJSON(data json.RawMessage) interface{} {
+	if len(data) == 0 {
+		return nil
+	}
+	return string(data)
+}
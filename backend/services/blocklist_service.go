@@ -0,0 +1,223 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/frallan97/feedback-service/backend/database"
+	"github.com/frallan97/feedback-service/backend/models"
+	"github.com/google/uuid"
+)
+
+const (
+	// maxBlocklistValueLength bounds rule values, including phrase patterns
+	maxBlocklistValueLength = 500
+
+	// discardedSubmissionRetention is how long the status endpoint answers for a
+	// discarded submission
+	discardedSubmissionRetention = 30 * 24 * time.Hour
+
+	// discardedPurgeInterval limits how often expired discarded submissions are deleted
+	discardedPurgeInterval = time.Hour
+)
+
+var (
+	discardedPurgeMu   sync.Mutex
+	lastDiscardedPurge time.Time
+)
+
+// ErrInvalidBlocklistValue is returned for a rule value that doesn't fit its type
+var ErrInvalidBlocklistValue = errors.New("invalid blocklist value")
+
+// BlocklistSubmission is the part of a submission blocklist rules are matched against.
+// IP is empty when the client's IP isn't known or doesn't apply.
+type BlocklistSubmission struct {
+	ContactEmail string
+	IP           string
+	Text         string
+}
+
+// NormalizeBlocklistValue validates a rule value for its type and returns it in the form
+// it's matched in: emails and domains in lowercase, IPs and CIDR ranges in canonical form
+func NormalizeBlocklistValue(ruleType, value string) (string, error) {
+	value = strings.TrimSpace(value)
+	if value == "" || len(value) > maxBlocklistValueLength {
+		return "", fmt.Errorf("%w: must be 1 to %d characters", ErrInvalidBlocklistValue, maxBlocklistValueLength)
+	}
+
+	switch ruleType {
+	case models.BlocklistTypeEmail:
+		local, domain, found := strings.Cut(strings.ToLower(value), "@")
+		if !found || local == "" || domain == "" || strings.Contains(domain, "@") {
+			return "", fmt.Errorf("%w: not an email address", ErrInvalidBlocklistValue)
+		}
+		return local + "@" + domain, nil
+
+	case models.BlocklistTypeEmailDomain:
+		domain := strings.TrimPrefix(strings.ToLower(value), "@")
+		if domain == "" || strings.ContainsAny(domain, "@ \t") {
+			return "", fmt.Errorf("%w: not a domain", ErrInvalidBlocklistValue)
+		}
+		return domain, nil
+
+	case models.BlocklistTypeIP:
+		if strings.Contains(value, "/") {
+			_, network, err := net.ParseCIDR(value)
+			if err != nil {
+				return "", fmt.Errorf("%w: not a CIDR range", ErrInvalidBlocklistValue)
+			}
+			return network.String(), nil
+		}
+		ip := net.ParseIP(value)
+		if ip == nil {
+			return "", fmt.Errorf("%w: not an IP address", ErrInvalidBlocklistValue)
+		}
+		return ip.String(), nil
+
+	case models.BlocklistTypePhrase:
+		if _, err := regexp.Compile(value); err != nil {
+			return "", fmt.Errorf("%w: %v", ErrInvalidBlocklistValue, err)
+		}
+		return value, nil
+	}
+
+	return "", fmt.Errorf("%w: unknown rule type %q", ErrInvalidBlocklistValue, ruleType)
+}
+
+// compileBlocklistPhrase compiles a phrase rule; phrases match case-insensitively
+func compileBlocklistPhrase(pattern string) (*regexp.Regexp, error) {
+	return regexp.Compile("(?i)" + pattern)
+}
+
+// MatchBlocklist returns the first of an application's blocklist rules, oldest first, that
+// matches a submission, and counts the hit. It returns nil if no rule matches.
+func MatchBlocklist(ctx context.Context, appID uuid.UUID, sub BlocklistSubmission) (*models.BlocklistRule, error) {
+	rows, err := database.DB.QueryContext(ctx, `
+		SELECT id, application_id, type, value, action
+		FROM blocklist_rules
+		WHERE application_id = $1
+		ORDER BY created_at, id
+	`, appID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch blocklist rules: %w", err)
+	}
+	defer rows.Close()
+
+	var match *models.BlocklistRule
+	for rows.Next() {
+		var rule models.BlocklistRule
+		if err := rows.Scan(&rule.ID, &rule.ApplicationID, &rule.Type, &rule.Value, &rule.Action); err != nil {
+			return nil, fmt.Errorf("failed to fetch blocklist rules: %w", err)
+		}
+		if blocklistRuleMatches(&rule, sub) {
+			match = &rule
+			break
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to fetch blocklist rules: %w", err)
+	}
+	rows.Close()
+
+	if match != nil {
+		_, err := database.DB.ExecContext(ctx,
+			"UPDATE blocklist_rules SET hit_count = hit_count + 1, last_hit_at = NOW() WHERE id = $1",
+			match.ID,
+		)
+		if err != nil {
+			log.Printf("[BLOCKLIST] Failed to count hit on rule %s: %v", match.ID, err)
+		}
+	}
+	return match, nil
+}
+
+func blocklistRuleMatches(rule *models.BlocklistRule, sub BlocklistSubmission) bool {
+	email := strings.ToLower(strings.TrimSpace(sub.ContactEmail))
+
+	switch rule.Type {
+	case models.BlocklistTypeEmail:
+		return email != "" && email == rule.Value
+
+	case models.BlocklistTypeEmailDomain:
+		at := strings.LastIndex(email, "@")
+		if at < 0 {
+			return false
+		}
+		domain := email[at+1:]
+		return domain == rule.Value || strings.HasSuffix(domain, "."+rule.Value)
+
+	case models.BlocklistTypeIP:
+		ip := net.ParseIP(sub.IP)
+		if ip == nil {
+			return false
+		}
+		if _, network, err := net.ParseCIDR(rule.Value); err == nil {
+			return network.Contains(ip)
+		}
+		return ip.Equal(net.ParseIP(rule.Value))
+
+	case models.BlocklistTypePhrase:
+		re, err := compileBlocklistPhrase(rule.Value)
+		if err != nil {
+			log.Printf("[BLOCKLIST] Skipping rule %s with invalid pattern: %v", rule.ID, err)
+			return false
+		}
+		return re.MatchString(sub.Text)
+	}
+	return false
+}
+
+// RecordDiscardedSubmission keeps a tombstone for a submission a discard rule dropped and
+// returns the ID to answer with, so the submission's status can be looked up like stored
+// feedback's
+func RecordDiscardedSubmission(ctx context.Context, tx *sql.Tx, appID uuid.UUID) (uuid.UUID, error) {
+	purgeDiscardedSubmissions(ctx)
+
+	var id uuid.UUID
+	err := tx.QueryRowContext(ctx,
+		"INSERT INTO discarded_submissions (application_id) VALUES ($1) RETURNING id",
+		appID,
+	).Scan(&id)
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("failed to record discarded submission: %w", err)
+	}
+	return id, nil
+}
+
+// GetDiscardedSubmission returns when an application's discarded submission was made, or
+// sql.ErrNoRows if id isn't one
+func GetDiscardedSubmission(ctx context.Context, appID uuid.UUID, id string) (time.Time, error) {
+	var createdAt time.Time
+	err := database.DB.QueryRowContext(ctx,
+		"SELECT created_at FROM discarded_submissions WHERE id = $1 AND application_id = $2",
+		id, appID,
+	).Scan(&createdAt)
+	return createdAt, err
+}
+
+// purgeDiscardedSubmissions deletes tombstones older than discardedSubmissionRetention
+func purgeDiscardedSubmissions(ctx context.Context) {
+	discardedPurgeMu.Lock()
+	if time.Since(lastDiscardedPurge) < discardedPurgeInterval {
+		discardedPurgeMu.Unlock()
+		return
+	}
+	lastDiscardedPurge = time.Now()
+	discardedPurgeMu.Unlock()
+
+	_, err := database.DB.ExecContext(ctx,
+		"DELETE FROM discarded_submissions WHERE created_at < NOW() - make_interval(secs => $1)",
+		discardedSubmissionRetention.Seconds(),
+	)
+	if err != nil {
+		log.Printf("[BLOCKLIST] Failed to purge discarded submissions: %v", err)
+	}
+}
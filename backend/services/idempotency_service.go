@@ -0,0 +1,117 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/frallan97/feedback-service/backend/database"
+	"github.com/google/uuid"
+)
+
+const (
+	// IdempotencyKeyTTL is how long a response is replayed for retries with the same key
+	IdempotencyKeyTTL = 24 * time.Hour
+
+	// idempotencyLockTimeout is how long a request can hold a key without completing before
+	// a retry may take over, e.g. after the instance handling it crashed
+	idempotencyLockTimeout = time.Minute
+
+	// idempotencyPurgeInterval limits how often expired keys are deleted
+	idempotencyPurgeInterval = time.Minute
+)
+
+var (
+	idempotencyPurgeMu   sync.Mutex
+	lastIdempotencyPurge time.Time
+)
+
+// IdempotentRequest is the request that claimed an idempotency key and, once it
+// completed, its response. StatusCode is 0 while the request is still being processed.
+type IdempotentRequest struct {
+	RequestHash string
+	StatusCode  int
+	Response    []byte
+}
+
+// ClaimIdempotencyKey claims an application's idempotency key for a request. If the
+// request may proceed, it returns the claim's ID, which completes or releases it;
+// otherwise it returns the request that holds the key.
+func ClaimIdempotencyKey(ctx context.Context, appID uuid.UUID, key, requestHash string) (uuid.UUID, *IdempotentRequest, error) {
+	purgeIdempotencyKeys(ctx)
+
+	// Expired keys, and keys held by requests that never completed, can be claimed again
+	claimID := uuid.New()
+	var claimed bool
+	err := database.DB.QueryRowContext(ctx, `
+		INSERT INTO idempotency_keys (application_id, key, claim_id, request_hash, expires_at)
+		VALUES ($1, $2, $3, $4, NOW() + make_interval(secs => $5))
+		ON CONFLICT (application_id, key) DO UPDATE
+		SET claim_id = EXCLUDED.claim_id, request_hash = EXCLUDED.request_hash, status_code = NULL,
+			response = NULL, created_at = NOW(), expires_at = EXCLUDED.expires_at
+		WHERE idempotency_keys.expires_at < NOW()
+		   OR (idempotency_keys.status_code IS NULL AND idempotency_keys.created_at < NOW() - make_interval(secs => $6))
+		RETURNING TRUE
+	`, appID, key, claimID, requestHash, IdempotencyKeyTTL.Seconds(), idempotencyLockTimeout.Seconds()).Scan(&claimed)
+	if err == nil {
+		return claimID, nil, nil
+	}
+	if err != sql.ErrNoRows {
+		return uuid.Nil, nil, fmt.Errorf("failed to claim idempotency key: %w", err)
+	}
+
+	var held IdempotentRequest
+	var statusCode sql.NullInt64
+	err = database.DB.QueryRowContext(ctx,
+		"SELECT request_hash, status_code, response FROM idempotency_keys WHERE application_id = $1 AND key = $2",
+		appID, key,
+	).Scan(&held.RequestHash, &statusCode, &held.Response)
+	if err != nil {
+		return uuid.Nil, nil, fmt.Errorf("failed to fetch idempotency key: %w", err)
+	}
+	held.StatusCode = int(statusCode.Int64)
+	return uuid.Nil, &held, nil
+}
+
+// CompleteIdempotencyKey stores the response to the request that claimed a key. It does
+// nothing if a retry has since taken the claim over.
+func CompleteIdempotencyKey(ctx context.Context, appID uuid.UUID, key string, claimID uuid.UUID, statusCode int, response []byte) error {
+	_, err := database.DB.ExecContext(ctx,
+		"UPDATE idempotency_keys SET status_code = $1, response = $2 WHERE application_id = $3 AND key = $4 AND claim_id = $5",
+		statusCode, response, appID, key, claimID,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to store idempotent response: %w", err)
+	}
+	return nil
+}
+
+// ReleaseIdempotencyKey frees a key whose request failed, so the client can retry with
+// it. It does nothing if a retry has since taken the claim over.
+func ReleaseIdempotencyKey(ctx context.Context, appID uuid.UUID, key string, claimID uuid.UUID) {
+	_, err := database.DB.ExecContext(ctx,
+		"DELETE FROM idempotency_keys WHERE application_id = $1 AND key = $2 AND claim_id = $3 AND status_code IS NULL",
+		appID, key, claimID,
+	)
+	if err != nil {
+		log.Printf("[IDEMPOTENCY] Failed to release key for application %s: %v", appID, err)
+	}
+}
+
+// purgeIdempotencyKeys deletes keys whose responses are no longer replayed
+func purgeIdempotencyKeys(ctx context.Context) {
+	idempotencyPurgeMu.Lock()
+	if time.Since(lastIdempotencyPurge) < idempotencyPurgeInterval {
+		idempotencyPurgeMu.Unlock()
+		return
+	}
+	lastIdempotencyPurge = time.Now()
+	idempotencyPurgeMu.Unlock()
+
+	if _, err := database.DB.ExecContext(ctx, "DELETE FROM idempotency_keys WHERE expires_at < NOW()"); err != nil {
+		log.Printf("[IDEMPOTENCY] Failed to purge expired keys: %v", err)
+	}
+}
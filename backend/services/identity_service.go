@@ -0,0 +1,138 @@
+package services
+
+import (
+	"context"
+	"crypto/rsa"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/frallan97/feedback-service/backend/database"
+	"github.com/frallan97/feedback-service/backend/models"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+const (
+	// UserTokenHeader carries the end-user identity token on public API requests
+	UserTokenHeader = "X-User-Token"
+
+	// MaxIdentityTokenLifetime is how far in the future an identity token may expire
+	MaxIdentityTokenLifetime = time.Hour
+
+	identityTokenLeeway = 30 * time.Second
+	maxExternalIDLength = 255
+)
+
+var (
+	// ErrNoIdentityKey is returned when an application hasn't configured a key for the token's algorithm
+	ErrNoIdentityKey = errors.New("application has no identity key for this token")
+
+	// ErrInvalidIdentityToken is returned for tokens that fail verification
+	ErrInvalidIdentityToken = errors.New("invalid identity token")
+)
+
+// IdentityClaims are the claims of an end-user identity token. The subject is the
+// application's ID for the user.
+type IdentityClaims struct {
+	Email string `json:"email"`
+	Name  string `json:"name"`
+	jwt.RegisteredClaims
+}
+
+// VerifyIdentityToken verifies an end-user identity token signed with the application's
+// secret (HS256) or the private key matching its public key (RS256/ES256). Tokens must
+// have a subject and expire within MaxIdentityTokenLifetime.
+func VerifyIdentityToken(tokenString string, identity models.ApplicationIdentity) (*IdentityClaims, error) {
+	claims := &IdentityClaims{}
+	_, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		switch token.Method.(type) {
+		case *jwt.SigningMethodHMAC:
+			if identity.Secret == "" {
+				return nil, ErrNoIdentityKey
+			}
+			return []byte(identity.Secret), nil
+		case *jwt.SigningMethodRSA, *jwt.SigningMethodECDSA:
+			if identity.PublicKey == "" {
+				return nil, ErrNoIdentityKey
+			}
+			key, err := ParseIdentityPublicKey(identity.PublicKey)
+			if err != nil {
+				return nil, err
+			}
+			if _, isRSA := token.Method.(*jwt.SigningMethodRSA); isRSA != isRSAKey(key) {
+				return nil, fmt.Errorf("token algorithm %v doesn't match the public key", token.Header["alg"])
+			}
+			return key, nil
+		default:
+			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		}
+	},
+		jwt.WithValidMethods([]string{"HS256", "RS256", "ES256"}),
+		jwt.WithExpirationRequired(),
+		jwt.WithLeeway(identityTokenLeeway),
+	)
+	if errors.Is(err, ErrNoIdentityKey) {
+		return nil, ErrNoIdentityKey
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidIdentityToken, err)
+	}
+
+	if claims.Subject == "" || len(claims.Subject) > maxExternalIDLength {
+		return nil, fmt.Errorf("%w: sub must be 1-%d characters", ErrInvalidIdentityToken, maxExternalIDLength)
+	}
+	// Short-lived tokens limit the damage of one leaking from a client
+	if time.Until(claims.ExpiresAt.Time) > MaxIdentityTokenLifetime+identityTokenLeeway {
+		return nil, fmt.Errorf("%w: exp is more than %s in the future", ErrInvalidIdentityToken, MaxIdentityTokenLifetime)
+	}
+
+	return claims, nil
+}
+
+// ParseIdentityPublicKey parses a PEM RSA or ECDSA public key
+func ParseIdentityPublicKey(pemKey string) (interface{}, error) {
+	if key, err := jwt.ParseRSAPublicKeyFromPEM([]byte(pemKey)); err == nil {
+		return key, nil
+	}
+	if key, err := jwt.ParseECPublicKeyFromPEM([]byte(pemKey)); err == nil {
+		return key, nil
+	}
+	return nil, errors.New("public key must be a PEM encoded RSA or ECDSA public key")
+}
+
+func isRSAKey(key interface{}) bool {
+	_, ok := key.(*rsa.PublicKey)
+	return ok
+}
+
+// GetApplicationIdentity returns the keys an application signs identity tokens with
+func GetApplicationIdentity(ctx context.Context, appID uuid.UUID) (models.ApplicationIdentity, error) {
+	var identity models.ApplicationIdentity
+	err := database.DB.QueryRowContext(ctx,
+		"SELECT COALESCE(identity_secret, ''), COALESCE(identity_public_key, '') FROM applications WHERE id = $1",
+		appID,
+	).Scan(&identity.Secret, &identity.PublicKey)
+	if err != nil {
+		return identity, fmt.Errorf("failed to fetch application identity keys: %w", err)
+	}
+	return identity, nil
+}
+
+// UpsertEndUser records a verified end user, updating their email and name if the token has them
+func UpsertEndUser(ctx context.Context, appID uuid.UUID, claims *IdentityClaims) (uuid.UUID, error) {
+	var endUserID uuid.UUID
+	err := database.DB.QueryRowContext(ctx, `
+		INSERT INTO end_users (application_id, external_id, email, name)
+		VALUES ($1, $2, NULLIF($3, ''), NULLIF($4, ''))
+		ON CONFLICT (application_id, external_id) DO UPDATE SET
+			email = COALESCE(EXCLUDED.email, end_users.email),
+			name = COALESCE(EXCLUDED.name, end_users.name),
+			last_seen_at = NOW()
+		RETURNING id
+	`, appID, claims.Subject, claims.Email, claims.Name).Scan(&endUserID)
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("failed to record end user: %w", err)
+	}
+	return endUserID, nil
+}
@@ -0,0 +1,69 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+
+	"github.com/frallan97/feedback-service/backend/database"
+	"github.com/google/uuid"
+)
+
+// GetApplicationRole returns a user's role in an application, or "" if they aren't a member
+func GetApplicationRole(ctx context.Context, appID, userID uuid.UUID) (string, error) {
+	var role string
+	err := database.DB.QueryRowContext(ctx,
+		"SELECT role FROM application_members WHERE application_id = $1 AND user_id = $2",
+		appID, userID,
+	).Scan(&role)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to fetch membership: %w", err)
+	}
+	return role, nil
+}
+
+// GetFeedbackApplicationID returns the application a feedback item belongs to.
+// It returns sql.ErrNoRows if the feedback doesn't exist.
+func GetFeedbackApplicationID(ctx context.Context, feedbackID uuid.UUID) (uuid.UUID, error) {
+	var appID uuid.UUID
+	err := database.DB.QueryRowContext(ctx,
+		"SELECT application_id FROM feedback WHERE id = $1",
+		feedbackID,
+	).Scan(&appID)
+	return appID, err
+}
+
+// acceptInvitations turns pending invitations for a newly synced user's email into memberships
+func acceptInvitations(ctx context.Context, userID uuid.UUID, email string) {
+	tx, err := database.DB.BeginTx(ctx, nil)
+	if err != nil {
+		log.Printf("[MEMBERS] Failed to accept invitations for %s: %v", userID, err)
+		return
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO application_members (application_id, user_id, role, added_by)
+		SELECT application_id, $1::uuid, role, invited_by
+		FROM application_invitations
+		WHERE LOWER(email) = LOWER($2)
+		ON CONFLICT (application_id, user_id) DO NOTHING
+	`, userID, email)
+	if err != nil {
+		log.Printf("[MEMBERS] Failed to accept invitations for %s: %v", userID, err)
+		return
+	}
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM application_invitations WHERE LOWER(email) = LOWER($1)", email); err != nil {
+		log.Printf("[MEMBERS] Failed to clear invitations for %s: %v", userID, err)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		log.Printf("[MEMBERS] Failed to accept invitations for %s: %v", userID, err)
+	}
+}
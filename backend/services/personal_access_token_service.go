@@ -0,0 +1,165 @@
+package services
+
+import (
+	"context"
+	"crypto/subtle"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/frallan97/feedback-service/backend/database"
+	"github.com/frallan97/feedback-service/backend/models"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+var (
+	// ErrInvalidPersonalAccessToken is returned for tokens that don't exist
+	ErrInvalidPersonalAccessToken = errors.New("invalid personal access token")
+
+	// ErrPersonalAccessTokenRevoked is returned for revoked tokens
+	ErrPersonalAccessTokenRevoked = errors.New("personal access token has been revoked")
+
+	// ErrPersonalAccessTokenExpired is returned for expired tokens
+	ErrPersonalAccessTokenExpired = errors.New("personal access token has expired")
+)
+
+// personalAccessTokenColumns are the personal_access_tokens columns scanned by scanPersonalAccessToken
+const personalAccessTokenColumns = "id, user_id, name, token_prefix, scopes, expires_at, last_used_at, revoked_at, created_at"
+
+// tokenLastUsedResolution limits how often a token's last_used_at is written
+const tokenLastUsedResolution = time.Minute
+
+func scanPersonalAccessToken(row interface{ Scan(...interface{}) error }) (*models.PersonalAccessToken, error) {
+	var t models.PersonalAccessToken
+	err := row.Scan(
+		&t.ID, &t.UserID, &t.Name, &t.Prefix, pq.Array(&t.Scopes),
+		&t.ExpiresAt, &t.LastUsedAt, &t.RevokedAt, &t.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	t.SetStatus(time.Now())
+	return &t, nil
+}
+
+// CreatePersonalAccessToken generates and stores a token for a user. The returned token
+// includes the full token, which is never available again.
+func CreatePersonalAccessToken(ctx context.Context, userID uuid.UUID, name string, scopes []string, expiresAt time.Time) (*models.PersonalAccessToken, error) {
+	generated, err := GeneratePersonalAccessToken()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate token: %w", err)
+	}
+
+	t, err := scanPersonalAccessToken(database.DB.QueryRowContext(ctx, `
+		INSERT INTO personal_access_tokens (user_id, name, token_prefix, token_hash, scopes, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING `+personalAccessTokenColumns,
+		userID, name, generated.Prefix, generated.Hash, pq.Array(scopes), expiresAt,
+	))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create token: %w", err)
+	}
+	t.Token = generated.Key
+	return t, nil
+}
+
+// ListPersonalAccessTokens returns a user's tokens, including revoked and expired ones
+func ListPersonalAccessTokens(ctx context.Context, userID uuid.UUID) ([]*models.PersonalAccessToken, error) {
+	rows, err := database.DB.QueryContext(ctx, `
+		SELECT `+personalAccessTokenColumns+`
+		FROM personal_access_tokens
+		WHERE user_id = $1
+		ORDER BY created_at DESC
+	`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch tokens: %w", err)
+	}
+	defer rows.Close()
+
+	tokens := []*models.PersonalAccessToken{}
+	for rows.Next() {
+		t, err := scanPersonalAccessToken(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan token: %w", err)
+		}
+		tokens = append(tokens, t)
+	}
+	return tokens, rows.Err()
+}
+
+// RevokePersonalAccessToken revokes one of a user's tokens. It returns sql.ErrNoRows if
+// the user has no such live token.
+func RevokePersonalAccessToken(ctx context.Context, userID, tokenID uuid.UUID) error {
+	result, err := database.DB.ExecContext(ctx,
+		"UPDATE personal_access_tokens SET revoked_at = NOW() WHERE id = $1 AND user_id = $2 AND revoked_at IS NULL",
+		tokenID, userID,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to revoke token: %w", err)
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// AuthenticatePersonalAccessToken looks a presented token up by its prefix, compares
+// hashes and checks that it is still live
+func AuthenticatePersonalAccessToken(ctx context.Context, token string) (*models.PersonalAccessToken, error) {
+	rows, err := database.DB.QueryContext(ctx, `
+		SELECT token_hash, `+personalAccessTokenColumns+`
+		FROM personal_access_tokens
+		WHERE token_prefix = $1
+	`, APIKeyPrefix(token))
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up token: %w", err)
+	}
+	defer rows.Close()
+
+	hash := []byte(HashAPIKey(token))
+	var match *models.PersonalAccessToken
+	for rows.Next() {
+		var storedHash string
+		var t models.PersonalAccessToken
+		if err := rows.Scan(
+			&storedHash, &t.ID, &t.UserID, &t.Name, &t.Prefix, pq.Array(&t.Scopes),
+			&t.ExpiresAt, &t.LastUsedAt, &t.RevokedAt, &t.CreatedAt,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan token: %w", err)
+		}
+		if subtle.ConstantTimeCompare(hash, []byte(storedHash)) == 1 {
+			t.SetStatus(time.Now())
+			match = &t
+			break
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to look up token: %w", err)
+	}
+
+	switch {
+	case match == nil:
+		return nil, ErrInvalidPersonalAccessToken
+	case match.Status == models.APIKeyStatusRevoked:
+		return nil, ErrPersonalAccessTokenRevoked
+	case match.Status == models.APIKeyStatusExpired:
+		return nil, ErrPersonalAccessTokenExpired
+	}
+
+	touchPersonalAccessToken(ctx, match.ID)
+	return match, nil
+}
+
+// touchPersonalAccessToken records that a token was used, at most once per tokenLastUsedResolution
+func touchPersonalAccessToken(ctx context.Context, tokenID uuid.UUID) {
+	_, err := database.DB.ExecContext(ctx, `
+		UPDATE personal_access_tokens SET last_used_at = NOW()
+		WHERE id = $1 AND (last_used_at IS NULL OR last_used_at < NOW() - make_interval(secs => $2))
+	`, tokenID, tokenLastUsedResolution.Seconds())
+	if err != nil {
+		log.Printf("[AUTH] Failed to record use of personal access token %s: %v", tokenID, err)
+	}
+}
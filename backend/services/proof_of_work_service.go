@@ -0,0 +1,238 @@
+package services
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"math/bits"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/frallan97/feedback-service/backend/database"
+	"github.com/frallan97/feedback-service/backend/models"
+	"github.com/google/uuid"
+)
+
+// ChallengeAlgorithm is the hash proof-of-work solutions are checked with
+const ChallengeAlgorithm = "sha256"
+
+const (
+	// maxChallengeDifficulty bounds how far abuse can raise the difficulty; each bit
+	// doubles the expected work
+	maxChallengeDifficulty = 26
+
+	// maxSolutionLength bounds the solutions the server hashes
+	maxSolutionLength = 64
+
+	// challengePurgeInterval limits how often expired spent challenges are deleted
+	challengePurgeInterval = time.Minute
+)
+
+var (
+	ErrInvalidChallenge = errors.New("invalid challenge")
+	ErrChallengeExpired = errors.New("challenge has expired")
+	ErrChallengeSpent   = errors.New("challenge has already been used")
+	ErrInvalidSolution  = errors.New("solution does not meet the challenge difficulty")
+)
+
+// ProofOfWorkConfig configures how challenges are signed and how long they can be solved for
+type ProofOfWorkConfig struct {
+	Secret []byte
+	TTL    time.Duration
+}
+
+var (
+	proofOfWork ProofOfWorkConfig
+
+	challengePurgeMu   sync.Mutex
+	lastChallengePurge time.Time
+)
+
+// InitProofOfWork sets the secret and lifetime of proof-of-work challenges
+func InitProofOfWork(cfg ProofOfWorkConfig) {
+	proofOfWork = cfg
+}
+
+// ProofOfWorkSettings are an application's proof-of-work settings. Difficulty is the
+// number of leading zero bits solutions need before abuse raises it.
+type ProofOfWorkSettings struct {
+	Enabled    bool
+	Difficulty int
+}
+
+// GetProofOfWorkSettings returns an application's proof-of-work settings
+func GetProofOfWorkSettings(ctx context.Context, appID uuid.UUID) (ProofOfWorkSettings, error) {
+	var settings ProofOfWorkSettings
+	err := database.DB.QueryRowContext(ctx,
+		"SELECT pow_enabled, pow_difficulty FROM applications WHERE id = $1",
+		appID,
+	).Scan(&settings.Enabled, &settings.Difficulty)
+	if err != nil {
+		return settings, fmt.Errorf("failed to fetch proof-of-work settings: %w", err)
+	}
+	return settings, nil
+}
+
+// ChallengeDifficulty returns the difficulty of a new challenge: the application's base
+// difficulty, raised while the rate limiter sees abuse from the client's IP or against the
+// application as a whole
+func ChallengeDifficulty(ctx context.Context, appID uuid.UUID, base int, clientIP string) int {
+	limits, err := GetApplicationRateLimits(ctx, appID)
+	if err != nil {
+		log.Printf("[RATELIMIT] %v", err)
+		return base
+	}
+
+	ipBucket := IPRateLimitBucket(appID, clientIP)
+	rejectedBucket := RejectedRateLimitBucket(appID)
+	counts, err := CurrentRateLimitCounts(ctx, []string{ipBucket, rejectedBucket})
+	if err != nil {
+		log.Printf("[RATELIMIT] %v", err)
+		return base
+	}
+
+	difficulty := base
+	if limits.PerIP > 0 && clientIP != "" {
+		switch used := counts[ipBucket]; {
+		case used > limits.PerIP:
+			difficulty += 4
+		case used*2 > limits.PerIP:
+			difficulty += 2
+		}
+	}
+	if counts[rejectedBucket] > 0 {
+		difficulty += 2
+	}
+
+	if difficulty > maxChallengeDifficulty {
+		difficulty = maxChallengeDifficulty
+	}
+	return difficulty
+}
+
+// IssueChallenge returns a new challenge for an application. Nothing is stored: the
+// challenge carries its ID, expiry and difficulty, signed with the server's secret.
+func IssueChallenge(appID uuid.UUID, difficulty int) (*models.ProofOfWorkChallenge, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return nil, fmt.Errorf("failed to generate challenge: %w", err)
+	}
+
+	id := hex.EncodeToString(b)
+	expiresAt := time.Now().Add(proofOfWork.TTL).Truncate(time.Second)
+	payload := id + "." + strconv.FormatInt(expiresAt.Unix(), 10) + "." + strconv.Itoa(difficulty)
+
+	return &models.ProofOfWorkChallenge{
+		Challenge:  payload + "." + signChallenge(appID, payload),
+		Algorithm:  ChallengeAlgorithm,
+		Difficulty: difficulty,
+		ExpiresAt:  expiresAt,
+	}, nil
+}
+
+// signChallenge returns the hex HMAC-SHA256 of "<application ID>.<payload>", so a
+// challenge is only valid for the application it was issued to
+func signChallenge(appID uuid.UUID, payload string) string {
+	mac := hmac.New(sha256.New, proofOfWork.Secret)
+	mac.Write([]byte(appID.String() + "." + payload))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// SolvedChallenge is a challenge whose proof of work has been verified, to be spent by
+// the submission it was solved for
+type SolvedChallenge struct {
+	ID        string
+	ExpiresAt time.Time
+}
+
+// VerifyProofOfWork checks that solution solves an application's challenge, i.e. that
+// SHA-256("<challenge>:<solution>") starts with the challenge's difficulty in zero bits.
+// The challenge isn't spent until SpendChallenge stores the submission with it.
+func VerifyProofOfWork(appID uuid.UUID, challenge, solution string) (*SolvedChallenge, error) {
+	parts := strings.Split(challenge, ".")
+	if len(parts) != 4 {
+		return nil, ErrInvalidChallenge
+	}
+	id, payload := parts[0], strings.Join(parts[:3], ".")
+	if !hmac.Equal([]byte(parts[3]), []byte(signChallenge(appID, payload))) {
+		return nil, ErrInvalidChallenge
+	}
+
+	expires, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil {
+		return nil, ErrInvalidChallenge
+	}
+	difficulty, err := strconv.Atoi(parts[2])
+	if err != nil {
+		return nil, ErrInvalidChallenge
+	}
+	expiresAt := time.Unix(expires, 0)
+	if !time.Now().Before(expiresAt) {
+		return nil, ErrChallengeExpired
+	}
+
+	if solution == "" || len(solution) > maxSolutionLength {
+		return nil, ErrInvalidSolution
+	}
+	hash := sha256.Sum256([]byte(challenge + ":" + solution))
+	if leadingZeroBits(hash[:]) < difficulty {
+		return nil, ErrInvalidSolution
+	}
+
+	return &SolvedChallenge{ID: id, ExpiresAt: expiresAt}, nil
+}
+
+func leadingZeroBits(hash []byte) int {
+	n := 0
+	for _, b := range hash {
+		if b != 0 {
+			return n + bits.LeadingZeros8(b)
+		}
+		n += 8
+	}
+	return n
+}
+
+// SpendChallenge records a solved challenge as used until it expires, in the transaction
+// that stores the submission. It returns ErrChallengeSpent if it was already used.
+func SpendChallenge(ctx context.Context, tx *sql.Tx, appID uuid.UUID, solved *SolvedChallenge) error {
+	purgeSpentChallenges(ctx)
+
+	// The expiry is computed by the database, whose clock purges it
+	result, err := tx.ExecContext(ctx, `
+		INSERT INTO spent_challenges (application_id, challenge_id, expires_at)
+		VALUES ($1, $2, NOW() + make_interval(secs => $3))
+		ON CONFLICT (application_id, challenge_id) DO NOTHING
+	`, appID, solved.ID, time.Until(solved.ExpiresAt).Seconds())
+	if err != nil {
+		return fmt.Errorf("failed to record spent challenge: %w", err)
+	}
+
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return ErrChallengeSpent
+	}
+	return nil
+}
+
+// purgeSpentChallenges deletes spent challenges that would be rejected as expired anyway
+func purgeSpentChallenges(ctx context.Context) {
+	challengePurgeMu.Lock()
+	if time.Since(lastChallengePurge) < challengePurgeInterval {
+		challengePurgeMu.Unlock()
+		return
+	}
+	lastChallengePurge = time.Now()
+	challengePurgeMu.Unlock()
+
+	if _, err := database.DB.ExecContext(ctx, "DELETE FROM spent_challenges WHERE expires_at < NOW()"); err != nil {
+		log.Printf("[AUTH] Failed to purge spent challenges: %v", err)
+	}
+}
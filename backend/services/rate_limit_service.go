@@ -0,0 +1,168 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/frallan97/feedback-service/backend/database"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// RateLimitWindow is how long public API requests are counted for. Counters use fixed
+// windows aligned to the minute in Postgres, so every instance shares them.
+const RateLimitWindow = time.Minute
+
+// rateLimitPurgeInterval limits how often finished windows are deleted
+const rateLimitPurgeInterval = time.Minute
+
+var (
+	rateLimitPurgeMu   sync.Mutex
+	lastRateLimitPurge time.Time
+)
+
+// RateLimits are an application's public API limits, in requests per RateLimitWindow.
+// Zero means unlimited.
+type RateLimits struct {
+	PerKey int
+	PerIP  int
+}
+
+// RateLimitCount is a bucket's request count in the current window
+type RateLimitCount struct {
+	Bucket string
+	Limit  int
+	Count  int
+	Reset  time.Duration // Until the window ends
+}
+
+// Remaining returns how many more requests the bucket allows in this window
+func (c RateLimitCount) Remaining() int {
+	if c.Count >= c.Limit {
+		return 0
+	}
+	return c.Limit - c.Count
+}
+
+// Exceeded reports whether the request that was counted went over the limit
+func (c RateLimitCount) Exceeded() bool {
+	return c.Count > c.Limit
+}
+
+// IPRateLimitBucket names the counter of a client IP's requests to an application
+func IPRateLimitBucket(appID uuid.UUID, ip string) string {
+	return "ip:" + appID.String() + ":" + ip
+}
+
+// RejectedRateLimitBucket names the counter of an application's requests rejected for
+// exceeding a limit
+func RejectedRateLimitBucket(appID uuid.UUID) string {
+	return "rejected:" + appID.String()
+}
+
+// GetApplicationRateLimits returns an application's public API rate limits
+func GetApplicationRateLimits(ctx context.Context, appID uuid.UUID) (RateLimits, error) {
+	var limits RateLimits
+	err := database.DB.QueryRowContext(ctx,
+		"SELECT rate_limit_per_key, rate_limit_per_ip FROM applications WHERE id = $1",
+		appID,
+	).Scan(&limits.PerKey, &limits.PerIP)
+	if err != nil {
+		return limits, fmt.Errorf("failed to fetch rate limits: %w", err)
+	}
+	return limits, nil
+}
+
+// CountRateLimitedRequest counts a request against each bucket, given with its limit, and
+// returns the buckets' counts in the current window
+func CountRateLimitedRequest(ctx context.Context, limits map[string]int) ([]RateLimitCount, error) {
+	purgeRateLimitCounters(ctx)
+
+	// Lock rows in a consistent order so concurrent requests can't deadlock
+	buckets := make([]string, 0, len(limits))
+	for bucket := range limits {
+		buckets = append(buckets, bucket)
+	}
+	sort.Strings(buckets)
+
+	rows, err := database.DB.QueryContext(ctx, `
+		INSERT INTO rate_limit_counters (bucket, window_start, count)
+		SELECT bucket, date_trunc('minute', NOW()), 1 FROM unnest($1::text[]) AS bucket
+		ON CONFLICT (bucket, window_start) DO UPDATE SET count = rate_limit_counters.count + 1
+		RETURNING bucket, count, EXTRACT(EPOCH FROM window_start + INTERVAL '1 minute' - NOW())
+	`, pq.Array(buckets))
+	if err != nil {
+		return nil, fmt.Errorf("failed to count request: %w", err)
+	}
+	defer rows.Close()
+
+	counts := []RateLimitCount{}
+	for rows.Next() {
+		var c RateLimitCount
+		var resetSeconds float64
+		if err := rows.Scan(&c.Bucket, &c.Count, &resetSeconds); err != nil {
+			return nil, fmt.Errorf("failed to count request: %w", err)
+		}
+		c.Limit = limits[c.Bucket]
+		c.Reset = time.Duration(math.Ceil(resetSeconds)) * time.Second
+		counts = append(counts, c)
+	}
+	return counts, rows.Err()
+}
+
+// RecordRateLimitRejection counts a request rejected for exceeding one of an application's
+// limits, a sign the application is being abused
+func RecordRateLimitRejection(ctx context.Context, appID uuid.UUID) {
+	_, err := database.DB.ExecContext(ctx, `
+		INSERT INTO rate_limit_counters (bucket, window_start, count)
+		VALUES ($1, date_trunc('minute', NOW()), 1)
+		ON CONFLICT (bucket, window_start) DO UPDATE SET count = rate_limit_counters.count + 1
+	`, RejectedRateLimitBucket(appID))
+	if err != nil {
+		log.Printf("[RATELIMIT] Failed to record rejected request for application %s: %v", appID, err)
+	}
+}
+
+// CurrentRateLimitCounts returns the buckets' counts in the current window, without
+// counting a request. Buckets without requests are left out.
+func CurrentRateLimitCounts(ctx context.Context, buckets []string) (map[string]int, error) {
+	rows, err := database.DB.QueryContext(ctx, `
+		SELECT bucket, count FROM rate_limit_counters
+		WHERE bucket = ANY($1) AND window_start = date_trunc('minute', NOW())
+	`, pq.Array(buckets))
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch rate limit counts: %w", err)
+	}
+	defer rows.Close()
+
+	counts := map[string]int{}
+	for rows.Next() {
+		var bucket string
+		var count int
+		if err := rows.Scan(&bucket, &count); err != nil {
+			return nil, fmt.Errorf("failed to fetch rate limit counts: %w", err)
+		}
+		counts[bucket] = count
+	}
+	return counts, rows.Err()
+}
+
+// purgeRateLimitCounters deletes the counters of finished windows
+func purgeRateLimitCounters(ctx context.Context) {
+	rateLimitPurgeMu.Lock()
+	if time.Since(lastRateLimitPurge) < rateLimitPurgeInterval {
+		rateLimitPurgeMu.Unlock()
+		return
+	}
+	lastRateLimitPurge = time.Now()
+	rateLimitPurgeMu.Unlock()
+
+	if _, err := database.DB.ExecContext(ctx, "DELETE FROM rate_limit_counters WHERE window_start < date_trunc('minute', NOW())"); err != nil {
+		log.Printf("[RATELIMIT] Failed to purge rate limit counters: %v", err)
+	}
+}
@@ -0,0 +1,298 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/frallan97/feedback-service/backend/database"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// Reasons a submission's spam score was raised
+const (
+	SpamReasonHoneypot        = "honeypot"
+	SpamReasonLinks           = "links"
+	SpamReasonDuplicate       = "duplicate"
+	SpamReasonRepetitive      = "repetitive"
+	SpamReasonDisposableEmail = "disposable_email"
+	SpamReasonClassifier      = "classifier"
+)
+
+// Labels moderation decisions train the classifier with
+const (
+	SpamLabelSpam = "spam"
+	SpamLabelHam  = "ham"
+)
+
+const (
+	// minSpamTrainingDocs is how many spam and ham documents the classifier needs before it's used
+	minSpamTrainingDocs = 10
+
+	// maxSpamTokens bounds how many distinct tokens of a submission are scored or trained
+	maxSpamTokens = 200
+
+	// minDuplicateLength keeps short submissions like "Great app!" from counting as duplicates
+	minDuplicateLength = 20
+)
+
+var linkPattern = regexp.MustCompile(`(?i)\b(?:https?://|www\.)\S+`)
+
+// disposableEmailDomains are throwaway email providers; their subdomains match too
+var disposableEmailDomains = map[string]bool{
+	"10minutemail.com": true, "burnermail.io": true, "discard.email": true, "dispostable.com": true,
+	"emailondeck.com": true, "fakeinbox.com": true, "getnada.com": true, "grr.la": true,
+	"guerrillamail.com": true, "guerrillamail.net": true, "mailcatch.com": true, "maildrop.cc": true,
+	"mailinator.com": true, "mailnesia.com": true, "mintemail.com": true, "moakt.com": true,
+	"mohmal.com": true, "nada.email": true, "sharklasers.com": true, "spamgourmet.com": true,
+	"temp-mail.io": true, "temp-mail.org": true, "tempmail.com": true, "tempmail.net": true,
+	"tempr.email": true, "throwawaymail.com": true, "trashmail.com": true, "yopmail.com": true,
+}
+
+// SpamSettings are an application's spam filter settings. Submissions scoring at least
+// Threshold are held for moderation.
+type SpamSettings struct {
+	Enabled   bool
+	Threshold float64
+}
+
+// SpamSubmission is the part of a submission the spam filter looks at. Honeypot is a form
+// field hidden from people, so only bots fill it in.
+type SpamSubmission struct {
+	Title        string
+	Content      string
+	ContactEmail string
+	Honeypot     string
+}
+
+// SpamScore rates a submission from 0 (legitimate) to 1 (spam)
+type SpamScore struct {
+	Score   float64
+	Reasons []string
+}
+
+func (s *SpamScore) add(points float64, reason string) {
+	s.Score += points
+	if reason != "" {
+		s.Reasons = append(s.Reasons, reason)
+	}
+}
+
+// GetSpamSettings returns an application's spam filter settings
+func GetSpamSettings(ctx context.Context, appID uuid.UUID) (SpamSettings, error) {
+	var settings SpamSettings
+	err := database.DB.QueryRowContext(ctx,
+		"SELECT spam_filter_enabled, spam_threshold FROM applications WHERE id = $1",
+		appID,
+	).Scan(&settings.Enabled, &settings.Threshold)
+	if err != nil {
+		return settings, fmt.Errorf("failed to fetch spam settings: %w", err)
+	}
+	return settings, nil
+}
+
+// ScoreSpam scores a submission to an application using heuristics, recent submissions and
+// the classifier trained by moderators
+func ScoreSpam(ctx context.Context, appID uuid.UUID, s SpamSubmission) (SpamScore, error) {
+	score := scoreSpamHeuristics(s)
+
+	if len(strings.TrimSpace(s.Content)) >= minDuplicateLength {
+		var duplicates int
+		err := database.DB.QueryRowContext(ctx, `
+			SELECT COUNT(*) FROM feedback
+			WHERE application_id = $1 AND content = $2 AND created_at > NOW() - INTERVAL '24 hours'
+		`, appID, s.Content).Scan(&duplicates)
+		if err != nil {
+			return score, fmt.Errorf("failed to look for duplicate submissions: %w", err)
+		}
+		if duplicates > 0 {
+			score.add(0.4, SpamReasonDuplicate)
+		}
+	}
+
+	probability, trained, err := classifySpam(ctx, appID, spamTokens(s.Title+" "+s.Content))
+	if err != nil {
+		return score, err
+	}
+	if trained {
+		// Legitimate-looking text lowers the score as much as spammy text raises it
+		reason := ""
+		if probability >= 0.9 {
+			reason = SpamReasonClassifier
+		}
+		score.add((probability-0.5)*1.2, reason)
+	}
+
+	score.Score = math.Max(0, math.Min(1, score.Score))
+	return score, nil
+}
+
+// scoreSpamHeuristics scores what can be judged from the submission alone
+func scoreSpamHeuristics(s SpamSubmission) SpamScore {
+	var score SpamScore
+
+	if strings.TrimSpace(s.Honeypot) != "" {
+		score.add(1, SpamReasonHoneypot)
+	}
+
+	text := s.Title + " " + s.Content
+	words := strings.Fields(text)
+
+	// Spam exists to place links: many links, or text that is mostly links
+	if links := len(linkPattern.FindAllString(text, -1)); links > 0 && len(words) > 0 {
+		points := 0.15*float64(links-1) + float64(links)/float64(len(words))
+		if points >= 0.1 {
+			score.add(math.Min(points, 0.5), SpamReasonLinks)
+		}
+	}
+
+	if len(words) >= 8 {
+		distinct := map[string]bool{}
+		for _, word := range words {
+			distinct[strings.ToLower(word)] = true
+		}
+		if float64(len(distinct))/float64(len(words)) < 0.3 {
+			score.add(0.3, SpamReasonRepetitive)
+		}
+	}
+
+	if isDisposableEmail(s.ContactEmail) {
+		score.add(0.3, SpamReasonDisposableEmail)
+	}
+
+	return score
+}
+
+func isDisposableEmail(email string) bool {
+	at := strings.LastIndex(email, "@")
+	if at < 0 {
+		return false
+	}
+	domain := strings.ToLower(strings.TrimSpace(email[at+1:]))
+	for domain != "" {
+		if disposableEmailDomains[domain] {
+			return true
+		}
+		_, parent, found := strings.Cut(domain, ".")
+		if !found {
+			break
+		}
+		domain = parent
+	}
+	return false
+}
+
+// spamTokens returns the distinct lowercase words of text, sorted
+func spamTokens(text string) []string {
+	seen := map[string]bool{}
+	tokens := []string{}
+	fields := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	for _, field := range fields {
+		if len(field) < 2 || len(field) > 64 || seen[field] {
+			continue
+		}
+		seen[field] = true
+		tokens = append(tokens, field)
+		if len(tokens) == maxSpamTokens {
+			break
+		}
+	}
+	sort.Strings(tokens)
+	return tokens
+}
+
+// classifySpam returns the naive Bayes probability that tokens are spam by the application's
+// model, and false if the model hasn't been trained enough to judge
+func classifySpam(ctx context.Context, appID uuid.UUID, tokens []string) (float64, bool, error) {
+	var spamDocs, hamDocs int
+	err := database.DB.QueryRowContext(ctx,
+		"SELECT spam_docs, ham_docs FROM spam_model WHERE application_id = $1",
+		appID,
+	).Scan(&spamDocs, &hamDocs)
+	if err != nil && err != sql.ErrNoRows {
+		return 0, false, fmt.Errorf("failed to load spam model: %w", err)
+	}
+	if spamDocs < minSpamTrainingDocs || hamDocs < minSpamTrainingDocs || len(tokens) == 0 {
+		return 0.5, false, nil
+	}
+
+	rows, err := database.DB.QueryContext(ctx,
+		"SELECT spam_count, ham_count FROM spam_tokens WHERE application_id = $1 AND token = ANY($2)",
+		appID, pq.Array(tokens),
+	)
+	if err != nil {
+		return 0, false, fmt.Errorf("failed to load spam tokens: %w", err)
+	}
+	defer rows.Close()
+
+	// Sum the log likelihood ratios of known tokens, with Laplace smoothing and equal priors
+	var logOdds float64
+	for rows.Next() {
+		var spamCount, hamCount int
+		if err := rows.Scan(&spamCount, &hamCount); err != nil {
+			return 0, false, fmt.Errorf("failed to load spam tokens: %w", err)
+		}
+		if spamCount+hamCount < 2 {
+			continue
+		}
+		pSpam := float64(spamCount+1) / float64(spamDocs+2)
+		pHam := float64(hamCount+1) / float64(hamDocs+2)
+		logOdds += math.Log(pSpam / pHam)
+	}
+	if err := rows.Err(); err != nil {
+		return 0, false, fmt.Errorf("failed to load spam tokens: %w", err)
+	}
+
+	return 1 / (1 + math.Exp(-logOdds)), true, nil
+}
+
+// TrainSpamClassifier trains an application's classifier with a moderator's decision on
+// text. previous is how the same text was trained before ("" if never), which is undone first.
+func TrainSpamClassifier(ctx context.Context, tx *sql.Tx, appID uuid.UUID, text, label, previous string) error {
+	if label == previous {
+		return nil
+	}
+
+	tokens := spamTokens(text)
+	if previous != "" {
+		if err := adjustSpamModel(ctx, tx, appID, tokens, previous, -1); err != nil {
+			return err
+		}
+	}
+	return adjustSpamModel(ctx, tx, appID, tokens, label, 1)
+}
+
+// adjustSpamModel adds delta to the label's counts for tokens and for the document total
+// in an application's model
+func adjustSpamModel(ctx context.Context, tx *sql.Tx, appID uuid.UUID, tokens []string, label string, delta int) error {
+	tokenColumn, docsColumn := "ham_count", "ham_docs"
+	if label == SpamLabelSpam {
+		tokenColumn, docsColumn = "spam_count", "spam_docs"
+	}
+
+	// Tokens are sorted, so concurrent training locks rows in the same order
+	_, err := tx.ExecContext(ctx, `
+		INSERT INTO spam_tokens (application_id, token, `+tokenColumn+`)
+		SELECT $1, token, GREATEST($3, 0) FROM unnest($2::text[]) AS token
+		ON CONFLICT (application_id, token) DO UPDATE SET `+tokenColumn+` = GREATEST(spam_tokens.`+tokenColumn+` + $3, 0)
+	`, appID, pq.Array(tokens), delta)
+	if err != nil {
+		return fmt.Errorf("failed to train spam tokens: %w", err)
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO spam_model (application_id, `+docsColumn+`) VALUES ($1, GREATEST($2, 0))
+		ON CONFLICT (application_id) DO UPDATE SET `+docsColumn+` = GREATEST(spam_model.`+docsColumn+` + $2, 0)
+	`, appID, delta); err != nil {
+		return fmt.Errorf("failed to train spam model: %w", err)
+	}
+	return nil
+}
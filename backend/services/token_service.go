@@ -0,0 +1,44 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/frallan97/feedback-service/backend/database"
+	"github.com/google/uuid"
+)
+
+// RevokeToken adds an access token to the revocation list until it expires. The expiry
+// is stored relative to the database clock, which the purge below compares it with.
+func RevokeToken(ctx context.Context, tokenID string, userID uuid.UUID, expiresAt time.Time) error {
+	_, err := database.DB.ExecContext(ctx, `
+		INSERT INTO revoked_tokens (token_id, user_id, expires_at)
+		VALUES ($1, $2, NOW() + make_interval(secs => $3))
+		ON CONFLICT (token_id) DO NOTHING
+	`, tokenID, userID, time.Until(expiresAt).Seconds())
+	if err != nil {
+		return fmt.Errorf("failed to revoke token: %w", err)
+	}
+
+	// Expired tokens are rejected anyway, so their entries can go
+	if _, err := database.DB.ExecContext(ctx, "DELETE FROM revoked_tokens WHERE expires_at < NOW()"); err != nil {
+		log.Printf("[AUTH] Failed to purge expired revoked tokens: %v", err)
+	}
+
+	return nil
+}
+
+// IsTokenRevoked reports whether an access token has been revoked
+func IsTokenRevoked(ctx context.Context, tokenID string) (bool, error) {
+	var revoked bool
+	err := database.DB.QueryRowContext(ctx,
+		"SELECT EXISTS(SELECT 1 FROM revoked_tokens WHERE token_id = $1)",
+		tokenID,
+	).Scan(&revoked)
+	if err != nil {
+		return false, fmt.Errorf("failed to check token revocation: %w", err)
+	}
+	return revoked, nil
+}
@@ -3,15 +3,44 @@ package services
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"log"
+	"strconv"
+	"strings"
 
 	"github.com/frallan97/feedback-service/backend/database"
 	"github.com/frallan97/feedback-service/backend/models"
 	"github.com/google/uuid"
 )
 
+var (
+	// ErrUserNotFound is returned when a user doesn't exist
+	ErrUserNotFound = errors.New("user not found")
+
+	// ErrLastAdmin is returned when a change would leave no active admin
+	ErrLastAdmin = errors.New("cannot remove the last active admin")
+)
+
+// userColumns selects a models.User, in the order scanUser expects
+const userColumns = "id, email, name, google_id, avatar_url, is_active, role, created_at, updated_at, last_seen_at"
+
+// bootstrapAdminEmail is promoted to admin when they sign in while there are no admins
+var bootstrapAdminEmail string
+
+// UserFilter narrows ListUsers. Empty fields don't filter.
+type UserFilter struct {
+	Search   string // Matches email or name
+	Role     string
+	IsActive *bool
+	Limit    int
+	Offset   int
+}
+
 // CreateOrUpdateUser syncs user from JWT claims to local database
-// This is called after JWT validation to ensure user exists locally
+// This is called after JWT validation to ensure user exists locally.
+// The first time a user is seen, their pending application invitations are accepted.
 func CreateOrUpdateUser(ctx context.Context, userID uuid.UUID, email, name string) (*models.User, error) {
 	query := `
         INSERT INTO users (id, email, name, updated_at)
@@ -19,27 +48,43 @@ func CreateOrUpdateUser(ctx context.Context, userID uuid.UUID, email, name strin
         ON CONFLICT (id) DO UPDATE
         SET email = EXCLUDED.email,
             name = EXCLUDED.name,
-            updated_at = NOW()
-        RETURNING id, email, name, google_id, avatar_url, is_active, is_admin, created_at, updated_at
+            updated_at = NOW(),
+            last_seen_at = NOW()
+        RETURNING ` + userColumns + `, (xmax = 0) AS inserted
     `
 
 	var user models.User
+	var inserted bool
 	err := database.DB.QueryRowContext(ctx, query, userID, email, name).Scan(
 		&user.ID, &user.Email, &user.Name, &user.GoogleID, &user.AvatarURL,
-		&user.IsActive, &user.IsAdmin, &user.CreatedAt, &user.UpdatedAt,
+		&user.IsActive, &user.Role, &user.CreatedAt, &user.UpdatedAt, &user.LastSeenAt, &inserted,
 	)
 
 	if err != nil {
 		return nil, fmt.Errorf("failed to create/update user: %w", err)
 	}
 
+	if inserted {
+		acceptInvitations(ctx, user.ID, user.Email)
+	}
+
+	if user.Role != models.RoleAdmin && bootstrapAdminEmail != "" && strings.EqualFold(user.Email, bootstrapAdminEmail) {
+		promoted, err := promoteFirstAdmin(ctx, user.ID)
+		if err != nil {
+			log.Printf("[AUTH] Failed to bootstrap admin %s: %v", user.Email, err)
+		} else if promoted {
+			log.Printf("[AUTH] Promoted %s to admin (bootstrap)", user.Email)
+			user.Role = models.RoleAdmin
+		}
+	}
+
 	return &user, nil
 }
 
 // GetUserByID fetches a user by ID
 func GetUserByID(ctx context.Context, userID uuid.UUID) (*models.User, error) {
 	query := `
-        SELECT id, email, name, google_id, avatar_url, is_active, is_admin, created_at, updated_at
+        SELECT ` + userColumns + `
         FROM users
         WHERE id = $1
     `
@@ -47,11 +92,11 @@ func GetUserByID(ctx context.Context, userID uuid.UUID) (*models.User, error) {
 	var user models.User
 	err := database.DB.QueryRowContext(ctx, query, userID).Scan(
 		&user.ID, &user.Email, &user.Name, &user.GoogleID, &user.AvatarURL,
-		&user.IsActive, &user.IsAdmin, &user.CreatedAt, &user.UpdatedAt,
+		&user.IsActive, &user.Role, &user.CreatedAt, &user.UpdatedAt, &user.LastSeenAt,
 	)
 
 	if err == sql.ErrNoRows {
-		return nil, fmt.Errorf("user not found")
+		return nil, ErrUserNotFound
 	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to fetch user: %w", err)
@@ -60,11 +105,201 @@ func GetUserByID(ctx context.Context, userID uuid.UUID) (*models.User, error) {
 	return &user, nil
 }
 
-// DeactivateUser marks a user as inactive
+// ListUsers returns the users matching filter, most recently active first, and how many match in total
+func ListUsers(ctx context.Context, filter UserFilter) ([]models.User, int, error) {
+	where := " WHERE 1=1"
+	args := []interface{}{}
+	argPos := 1
+
+	if filter.Search != "" {
+		where += " AND (email ILIKE '%' || $" + strconv.Itoa(argPos) + " || '%' OR name ILIKE '%' || $" + strconv.Itoa(argPos) + " || '%')"
+		args = append(args, filter.Search)
+		argPos++
+	}
+	if filter.Role != "" {
+		where += " AND role = $" + strconv.Itoa(argPos)
+		args = append(args, filter.Role)
+		argPos++
+	}
+	if filter.IsActive != nil {
+		where += " AND is_active = $" + strconv.Itoa(argPos)
+		args = append(args, *filter.IsActive)
+		argPos++
+	}
+
+	var total int
+	if err := database.DB.QueryRowContext(ctx, "SELECT COUNT(*) FROM users"+where, args...).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count users: %w", err)
+	}
+
+	query := "SELECT " + userColumns + " FROM users" + where +
+		" ORDER BY last_seen_at DESC NULLS LAST, created_at DESC LIMIT $" + strconv.Itoa(argPos) + " OFFSET $" + strconv.Itoa(argPos+1)
+	args = append(args, filter.Limit, filter.Offset)
+
+	rows, err := database.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to fetch users: %w", err)
+	}
+	defer rows.Close()
+
+	users := []models.User{}
+	for rows.Next() {
+		var user models.User
+		if err := rows.Scan(
+			&user.ID, &user.Email, &user.Name, &user.GoogleID, &user.AvatarURL,
+			&user.IsActive, &user.Role, &user.CreatedAt, &user.UpdatedAt, &user.LastSeenAt,
+		); err != nil {
+			return nil, 0, fmt.Errorf("failed to scan user: %w", err)
+		}
+		users = append(users, user)
+	}
+	return users, total, rows.Err()
+}
+
+// SetUserRole changes a user's global role. Demoting the last active admin fails with ErrLastAdmin.
+func SetUserRole(ctx context.Context, userID uuid.UUID, role string) error {
+	return updateUser(ctx, userID, "role = $2", role, role != models.RoleAdmin)
+}
+
+// DeactivateUser marks a user as inactive. Deactivating the last active admin fails with ErrLastAdmin.
 func DeactivateUser(ctx context.Context, userID uuid.UUID) error {
-	query := `UPDATE users SET is_active = false, updated_at = NOW() WHERE id = $1`
-	_, err := database.DB.ExecContext(ctx, query, userID)
-	return err
+	return updateUser(ctx, userID, "is_active = $2", false, true)
+}
+
+// ReactivateUser marks a deactivated user as active again
+func ReactivateUser(ctx context.Context, userID uuid.UUID) error {
+	return updateUser(ctx, userID, "is_active = $2", true, false)
+}
+
+// updateUser applies set (with value as $2) to a user. If removesAdmin, the update is refused
+// when the user is the only active admin. The active admins are locked while checking, so
+// concurrent demotions of different admins can't both succeed.
+func updateUser(ctx context.Context, userID uuid.UUID, set string, value interface{}, removesAdmin bool) error {
+	tx, err := database.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to update user: %w", err)
+	}
+	defer tx.Rollback()
+
+	if removesAdmin {
+		rows, err := tx.QueryContext(ctx, "SELECT id FROM users WHERE role = 'admin' AND is_active FOR UPDATE")
+		if err != nil {
+			return fmt.Errorf("failed to lock admins: %w", err)
+		}
+		admins := []uuid.UUID{}
+		for rows.Next() {
+			var id uuid.UUID
+			if err := rows.Scan(&id); err != nil {
+				rows.Close()
+				return fmt.Errorf("failed to lock admins: %w", err)
+			}
+			admins = append(admins, id)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return fmt.Errorf("failed to lock admins: %w", err)
+		}
+
+		if len(admins) == 1 && admins[0] == userID {
+			return ErrLastAdmin
+		}
+	}
+
+	result, err := tx.ExecContext(ctx, "UPDATE users SET "+set+", updated_at = NOW() WHERE id = $1", userID, value)
+	if err != nil {
+		return fmt.Errorf("failed to update user: %w", err)
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return ErrUserNotFound
+	}
+
+	return tx.Commit()
+}
+
+// SetBootstrapAdminEmail makes the user with this email an admin when they sign in, as long
+// as there are no admins yet. This lets a fresh install get its first admin.
+func SetBootstrapAdminEmail(email string) {
+	bootstrapAdminEmail = strings.TrimSpace(email)
+}
+
+// BootstrapAdmin promotes an existing user with the bootstrap email if there are no admins.
+// Users who haven't signed in yet are promoted on their first sign-in instead.
+func BootstrapAdmin(ctx context.Context) error {
+	if bootstrapAdminEmail == "" {
+		return nil
+	}
+
+	var userID uuid.UUID
+	err := database.DB.QueryRowContext(ctx,
+		"SELECT id FROM users WHERE LOWER(email) = LOWER($1)",
+		bootstrapAdminEmail,
+	).Scan(&userID)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to look up bootstrap admin: %w", err)
+	}
+
+	promoted, err := promoteFirstAdmin(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if promoted {
+		log.Printf("[AUTH] Promoted %s to admin (bootstrap)", bootstrapAdminEmail)
+	}
+	return nil
+}
+
+// PromoteAdmin makes the user with an email an admin, regardless of existing admins.
+// It is used by the promote-admin command to recover access.
+func PromoteAdmin(ctx context.Context, email string) error {
+	before := SnapshotRow(ctx, "users", "LOWER(email) = LOWER($1)", email)
+
+	var userID uuid.UUID
+	err := database.DB.QueryRowContext(ctx,
+		"UPDATE users SET role = 'admin', is_active = true, updated_at = NOW() WHERE LOWER(email) = LOWER($1) RETURNING id",
+		email,
+	).Scan(&userID)
+	if err == sql.ErrNoRows {
+		return ErrUserNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("failed to promote user: %w", err)
+	}
+
+	recordSystemUserChange(ctx, "user.promote_admin", userID, before)
+	return nil
+}
+
+// promoteFirstAdmin makes a user an admin if no active admin exists
+func promoteFirstAdmin(ctx context.Context, userID uuid.UUID) (bool, error) {
+	before := SnapshotRow(ctx, "users", "id = $1", userID)
+
+	result, err := database.DB.ExecContext(ctx, `
+        UPDATE users SET role = 'admin', updated_at = NOW()
+        WHERE id = $1 AND NOT EXISTS (SELECT 1 FROM users WHERE role = 'admin' AND is_active)
+    `, userID)
+	if err != nil {
+		return false, fmt.Errorf("failed to promote user: %w", err)
+	}
+	rows, _ := result.RowsAffected()
+	if rows > 0 {
+		recordSystemUserChange(ctx, "user.bootstrap_admin", userID, before)
+	}
+	return rows > 0, nil
+}
+
+// recordSystemUserChange audits a change to a user made by the service itself rather than
+// through the API, so the event has no actor
+func recordSystemUserChange(ctx context.Context, action string, userID uuid.UUID, before json.RawMessage) {
+	RecordAuditEvent(ctx, models.AuditEvent{
+		Action:     action,
+		TargetType: "user",
+		TargetID:   userID.String(),
+		Before:     before,
+		After:      SnapshotRow(ctx, "users", "id = $1", userID),
+	})
 }
 
 // CheckUserActive returns whether a user is active
@@ -73,7 +308,7 @@ func CheckUserActive(ctx context.Context, userID uuid.UUID) (bool, error) {
 	query := `SELECT is_active FROM users WHERE id = $1`
 	err := database.DB.QueryRowContext(ctx, query, userID).Scan(&isActive)
 	if err == sql.ErrNoRows {
-		return false, fmt.Errorf("user not found")
+		return false, ErrUserNotFound
 	}
 	return isActive, err
 }
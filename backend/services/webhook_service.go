@@ -0,0 +1,505 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"math/rand"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/frallan97/feedback-service/backend/database"
+	"github.com/frallan97/feedback-service/backend/models"
+	"github.com/google/uuid"
+)
+
+// Webhook event types
+const (
+	EventFeedbackCreated       = "feedback.created"
+	EventFeedbackUpdated       = "feedback.updated"
+	EventFeedbackStatusChanged = "feedback.status_changed"
+	EventFeedbackDeleted       = "feedback.deleted"
+	EventCommentCreated        = "comment.created"
+
+	// EventWildcard subscribes an endpoint to every event type
+	EventWildcard = "*"
+)
+
+// WebhookEventTypes lists every event type an endpoint can subscribe to
+var WebhookEventTypes = []string{
+	EventFeedbackCreated,
+	EventFeedbackUpdated,
+	EventFeedbackStatusChanged,
+	EventFeedbackDeleted,
+	EventCommentCreated,
+}
+
+// Webhook delivery statuses
+const (
+	DeliveryStatusPending   = "pending"
+	DeliveryStatusSucceeded = "succeeded"
+	DeliveryStatusDead      = "dead"
+)
+
+// Headers sent with every webhook delivery
+const (
+	WebhookEventHeader     = "X-Feedback-Event"
+	WebhookDeliveryHeader  = "X-Feedback-Delivery"
+	WebhookTimestampHeader = "X-Feedback-Timestamp"
+	WebhookSignatureHeader = "X-Feedback-Signature"
+)
+
+const (
+	// maxWebhookRetryDelay caps the exponential backoff between attempts
+	maxWebhookRetryDelay = 10 * time.Minute
+
+	// maxStoredResponseBody limits how much of a receiver's response is kept in the attempt log
+	maxStoredResponseBody = 4 * 1024
+)
+
+// ErrDeliveryNotFound is returned when a delivery doesn't exist for the application
+var ErrDeliveryNotFound = fmt.Errorf("delivery not found")
+
+// ErrWebhookAddressBlocked is returned when a webhook URL resolves to an address inside
+// the service's network
+var ErrWebhookAddressBlocked = fmt.Errorf("webhook address is not publicly routable")
+
+// WebhookDispatcherConfig configures the webhook delivery workers
+type WebhookDispatcherConfig struct {
+	Workers      int
+	Timeout      time.Duration
+	MaxAttempts  int
+	RetryDelay   time.Duration
+	PollInterval time.Duration
+	Retention    time.Duration
+}
+
+// webhookJob is a delivery claimed from the database for one attempt
+type webhookJob struct {
+	deliveryID uuid.UUID
+	eventID    uuid.UUID
+	eventType  string
+	url        string
+	secret     string
+	payload    []byte
+	attempt    int
+}
+
+type webhookDispatcher struct {
+	client *http.Client
+	cfg    WebhookDispatcherConfig
+	queue  chan *webhookJob
+	wake   chan struct{}
+}
+
+var dispatcher *webhookDispatcher
+
+// StartWebhookDispatcher starts the background workers that deliver webhook events.
+// Deliveries are persisted, so events queued while the dispatcher is down are sent once it starts.
+// Deliveries for disabled endpoints stay pending until the endpoint is re-enabled.
+func StartWebhookDispatcher(cfg WebhookDispatcherConfig) {
+	if cfg.Workers < 1 {
+		cfg.Workers = 1
+	}
+	if cfg.MaxAttempts < 1 {
+		cfg.MaxAttempts = 1
+	}
+	if cfg.PollInterval <= 0 {
+		cfg.PollInterval = 2 * time.Second
+	}
+
+	dispatcher = &webhookDispatcher{
+		client: newWebhookClient(cfg.Timeout),
+		cfg:    cfg,
+		queue:  make(chan *webhookJob),
+		wake:   make(chan struct{}, 1),
+	}
+
+	for i := 0; i < cfg.Workers; i++ {
+		go dispatcher.work()
+	}
+	go dispatcher.poll()
+
+	log.Printf("Webhook dispatcher started with %d workers", cfg.Workers)
+}
+
+// newWebhookClient returns the client deliveries are sent with. Receivers are chosen by
+// application owners, so it only connects to public addresses - checked on the resolved
+// address as it's dialed, so DNS rebinding can't get around it - goes through no proxy,
+// and doesn't follow redirects.
+func newWebhookClient(timeout time.Duration) *http.Client {
+	dialer := &net.Dialer{
+		Timeout: 10 * time.Second,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if ip := net.ParseIP(host); ip == nil || IsInternalIP(ip) {
+				return fmt.Errorf("%w: %s", ErrWebhookAddressBlocked, host)
+			}
+			return nil
+		},
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+
+	return &http.Client{
+		Timeout:   timeout,
+		Transport: transport,
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+// IsInternalIP reports whether ip is a loopback, private, link-local, multicast or
+// unspecified address, which webhooks aren't delivered to
+func IsInternalIP(ip net.IP) bool {
+	return ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() ||
+		ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast() || ip.IsMulticast() ||
+		ip.IsUnspecified()
+}
+
+// IsInternalWebhookHost reports whether a webhook URL's host is obviously internal: an
+// internal IP address or localhost. Other names are checked when they're resolved.
+func IsInternalWebhookHost(host string) bool {
+	host = strings.TrimSuffix(strings.ToLower(host), ".")
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return true
+	}
+	if ip := net.ParseIP(host); ip != nil {
+		return IsInternalIP(ip)
+	}
+	return false
+}
+
+// EmitWebhookEvent queues an event for delivery to every enabled endpoint of the
+// application subscribed to eventType. It is a no-op if no endpoint is subscribed.
+func EmitWebhookEvent(ctx context.Context, appID uuid.UUID, eventType string, data interface{}) {
+	event := models.WebhookEvent{
+		ID:            uuid.New(),
+		Type:          eventType,
+		ApplicationID: appID,
+		CreatedAt:     time.Now().UTC(),
+		Data:          data,
+	}
+
+	payload, err := json.Marshal(event)
+	if err != nil {
+		log.Printf("[WEBHOOK] Failed to encode %s event: %v", eventType, err)
+		return
+	}
+
+	result, err := database.DB.ExecContext(ctx, `
+		INSERT INTO webhook_deliveries (application_id, endpoint_id, event_id, event_type, url, payload)
+		SELECT e.application_id, e.id, $2::uuid, $3::text, e.url, $4::jsonb
+		FROM webhook_endpoints e
+		JOIN applications a ON a.id = e.application_id
+		WHERE e.application_id = $1 AND e.enabled AND a.is_active
+		  AND ($3::text = ANY(e.event_types) OR '*' = ANY(e.event_types))
+	`, appID, event.ID, eventType, payload)
+	if err != nil {
+		log.Printf("[WEBHOOK] Failed to queue %s event %s: %v", eventType, event.ID, err)
+		return
+	}
+
+	if n, _ := result.RowsAffected(); n > 0 {
+		wakeDispatcher()
+	}
+}
+
+// RedeliverWebhook queues a fresh delivery of an earlier delivery's event to its
+// endpoint's current URL and returns the new delivery ID
+func RedeliverWebhook(ctx context.Context, appID, deliveryID uuid.UUID) (uuid.UUID, error) {
+	var newID uuid.UUID
+	err := database.DB.QueryRowContext(ctx, `
+		INSERT INTO webhook_deliveries (application_id, endpoint_id, event_id, event_type, url, payload, redelivery_of)
+		SELECT d.application_id, e.id, d.event_id, d.event_type, e.url, d.payload, d.id
+		FROM webhook_deliveries d
+		JOIN webhook_endpoints e ON e.id = d.endpoint_id
+		WHERE d.id = $1 AND d.application_id = $2
+		RETURNING id
+	`, deliveryID, appID).Scan(&newID)
+	if err == sql.ErrNoRows {
+		return uuid.Nil, ErrDeliveryNotFound
+	}
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("failed to queue redelivery: %w", err)
+	}
+
+	wakeDispatcher()
+	return newID, nil
+}
+
+// DeleteWebhookEndpoint removes an application's webhook endpoint, dead-lettering its
+// pending deliveries in the same transaction so none are left without an endpoint. It
+// returns false if the endpoint doesn't exist.
+func DeleteWebhookEndpoint(ctx context.Context, appID, endpointID uuid.UUID) (bool, error) {
+	tx, err := database.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE webhook_deliveries
+		SET status = 'dead', last_error = 'Endpoint deleted', completed_at = NOW()
+		WHERE endpoint_id = $1 AND application_id = $2 AND status = 'pending'
+	`, endpointID, appID); err != nil {
+		return false, fmt.Errorf("failed to abandon deliveries: %w", err)
+	}
+
+	result, err := tx.ExecContext(ctx,
+		"DELETE FROM webhook_endpoints WHERE id = $1 AND application_id = $2",
+		endpointID, appID,
+	)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete webhook endpoint: %w", err)
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return false, nil
+	}
+
+	return true, tx.Commit()
+}
+
+// IsValidWebhookEventType reports whether an endpoint can subscribe to eventType
+func IsValidWebhookEventType(eventType string) bool {
+	if eventType == EventWildcard {
+		return true
+	}
+	for _, t := range WebhookEventTypes {
+		if t == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// SignWebhookPayload returns the hex HMAC-SHA256 of "<timestamp>.<payload>" keyed by secret
+func SignWebhookPayload(secret string, timestamp int64, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(payload)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// wakeDispatcher prompts the poller to look for due deliveries without waiting for the next tick
+func wakeDispatcher() {
+	if dispatcher == nil {
+		return
+	}
+	select {
+	case dispatcher.wake <- struct{}{}:
+	default:
+	}
+}
+
+// poll claims due deliveries from the database and hands them to the workers
+func (d *webhookDispatcher) poll() {
+	ticker := time.NewTicker(d.cfg.PollInterval)
+	defer ticker.Stop()
+
+	cleanup := time.NewTicker(time.Hour)
+	defer cleanup.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+		case <-d.wake:
+		case <-cleanup.C:
+			d.purgeOldDeliveries()
+			continue
+		}
+
+		for {
+			jobs, err := d.claim(d.cfg.Workers)
+			if err != nil {
+				log.Printf("[WEBHOOK] Failed to claim deliveries: %v", err)
+				break
+			}
+			for _, job := range jobs {
+				d.queue <- job
+			}
+			if len(jobs) < d.cfg.Workers {
+				break
+			}
+		}
+	}
+}
+
+// claim leases up to limit due deliveries so no other worker or replica picks them up
+func (d *webhookDispatcher) claim(limit int) ([]*webhookJob, error) {
+	lease := d.cfg.Timeout + time.Minute
+
+	rows, err := database.DB.Query(`
+		UPDATE webhook_deliveries d
+		SET next_attempt_at = NOW() + make_interval(secs => $2)
+		FROM webhook_endpoints e
+		WHERE e.id = d.endpoint_id
+		  AND d.id IN (
+			SELECT pd.id FROM webhook_deliveries pd
+			JOIN webhook_endpoints pe ON pe.id = pd.endpoint_id
+			WHERE pd.status = 'pending' AND pd.next_attempt_at <= NOW() AND pe.enabled
+			ORDER BY pd.next_attempt_at
+			LIMIT $1
+			FOR UPDATE OF pd SKIP LOCKED
+		  )
+		RETURNING d.id, d.event_id, d.event_type, d.url, e.secret, d.payload::text, d.attempts
+	`, limit, lease.Seconds())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	jobs := []*webhookJob{}
+	for rows.Next() {
+		var job webhookJob
+		var payload string
+		if err := rows.Scan(&job.deliveryID, &job.eventID, &job.eventType, &job.url, &job.secret, &payload, &job.attempt); err != nil {
+			return nil, err
+		}
+		job.payload = []byte(payload)
+		job.attempt++
+		jobs = append(jobs, &job)
+	}
+
+	return jobs, rows.Err()
+}
+
+func (d *webhookDispatcher) work() {
+	for job := range d.queue {
+		d.process(job)
+	}
+}
+
+// process performs one attempt for a job and records the outcome
+func (d *webhookDispatcher) process(job *webhookJob) {
+	start := time.Now()
+	status, body, err := d.deliver(job)
+	latency := time.Since(start)
+
+	var statusArg, errorArg, bodyArg interface{}
+	if status != 0 {
+		statusArg = status
+		bodyArg = body
+	}
+	if err != nil {
+		errorArg = err.Error()
+	}
+
+	if _, dbErr := database.DB.Exec(`
+		INSERT INTO webhook_delivery_attempts (delivery_id, attempt, response_status, response_body, latency_ms, error)
+		VALUES ($1, $2, $3, $4, $5, $6)
+	`, job.deliveryID, job.attempt, statusArg, bodyArg, latency.Milliseconds(), errorArg); dbErr != nil {
+		log.Printf("[WEBHOOK] Failed to record attempt for delivery %s: %v", job.deliveryID, dbErr)
+	}
+
+	newStatus := DeliveryStatusSucceeded
+	var nextAttempt, completedAt interface{}
+	switch {
+	case err == nil:
+		completedAt = time.Now()
+	case job.attempt >= d.cfg.MaxAttempts:
+		newStatus = DeliveryStatusDead
+		completedAt = time.Now()
+		log.Printf("[WEBHOOK] Delivery %s (%s) dead-lettered after %d attempts: %v",
+			job.deliveryID, job.eventType, job.attempt, err)
+	default:
+		newStatus = DeliveryStatusPending
+		delay := d.retryDelay(job.attempt)
+		nextAttempt = time.Now().Add(delay)
+		log.Printf("[WEBHOOK] Delivery %s (%s) failed (attempt %d/%d), retrying in %s: %v",
+			job.deliveryID, job.eventType, job.attempt, d.cfg.MaxAttempts, delay, err)
+	}
+
+	// A delivery dead-lettered while this attempt was in flight, because its endpoint was
+	// deleted, stays dead
+	if _, dbErr := database.DB.Exec(`
+		UPDATE webhook_deliveries
+		SET status = $1, attempts = $2, next_attempt_at = $3,
+		    last_response_status = $4, last_latency_ms = $5, last_error = $6, completed_at = $7
+		WHERE id = $8 AND status = 'pending'
+	`, newStatus, job.attempt, nextAttempt, statusArg, latency.Milliseconds(), errorArg, completedAt, job.deliveryID); dbErr != nil {
+		log.Printf("[WEBHOOK] Failed to update delivery %s: %v", job.deliveryID, dbErr)
+	}
+}
+
+// deliver performs a single signed POST of the job's payload, returning the response status and body
+func (d *webhookDispatcher) deliver(job *webhookJob) (int, string, error) {
+	req, err := http.NewRequest(http.MethodPost, job.url, bytes.NewReader(job.payload))
+	if err != nil {
+		return 0, "", fmt.Errorf("failed to build request: %w", err)
+	}
+
+	timestamp := time.Now().Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "feedback-service-webhooks/1.0")
+	req.Header.Set(WebhookEventHeader, job.eventType)
+	req.Header.Set(WebhookDeliveryHeader, job.eventID.String())
+	req.Header.Set(WebhookTimestampHeader, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(WebhookSignatureHeader, "sha256="+SignWebhookPayload(job.secret, timestamp, job.payload))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, "", fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, maxStoredResponseBody))
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, string(body), fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+
+	return resp.StatusCode, string(body), nil
+}
+
+// retryDelay returns the exponential backoff (with jitter) before the next attempt
+func (d *webhookDispatcher) retryDelay(attempt int) time.Duration {
+	delay := d.cfg.RetryDelay
+	for i := 1; i < attempt && delay < maxWebhookRetryDelay; i++ {
+		delay *= 2
+	}
+	if delay <= 0 || delay > maxWebhookRetryDelay {
+		delay = maxWebhookRetryDelay
+	}
+	jitter := time.Duration(rand.Int63n(int64(delay)/5 + 1))
+	return delay + jitter
+}
+
+// purgeOldDeliveries removes completed deliveries older than the retention period
+func (d *webhookDispatcher) purgeOldDeliveries() {
+	if d.cfg.Retention <= 0 {
+		return
+	}
+
+	result, err := database.DB.Exec(`
+		DELETE FROM webhook_deliveries
+		WHERE status = 'succeeded' AND created_at < NOW() - make_interval(secs => $1)
+	`, d.cfg.Retention.Seconds())
+	if err != nil {
+		log.Printf("[WEBHOOK] Failed to purge old deliveries: %v", err)
+		return
+	}
+
+	if n, _ := result.RowsAffected(); n > 0 {
+		log.Printf("[WEBHOOK] Purged %d old deliveries", n)
+	}
+}
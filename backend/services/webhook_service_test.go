@@ -0,0 +1,46 @@
+package services
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestWebhookClientRefusesInternalAddresses(t *testing.T) {
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("internal"))
+	}))
+	defer receiver.Close()
+
+	_, err := newWebhookClient(time.Second).Post(receiver.URL, "application/json", nil)
+	if !errors.Is(err, ErrWebhookAddressBlocked) {
+		t.Fatalf("err = %v, want ErrWebhookAddressBlocked", err)
+	}
+}
+
+func TestIsInternalWebhookHost(t *testing.T) {
+	tests := map[string]bool{
+		"localhost":       true,
+		"api.localhost":   true,
+		"127.0.0.1":       true,
+		"10.1.2.3":        true,
+		"192.168.0.10":    true,
+		"169.254.169.254": true,
+		"0.0.0.0":         true,
+		"::1":             true,
+		"fd00::1":         true,
+		"fe80::1":         true,
+		"224.0.0.1":       true,
+		"93.184.216.34":   false,
+		"2606:4700::1111": false,
+		"hooks.example":   false,
+	}
+
+	for host, want := range tests {
+		if got := IsInternalWebhookHost(host); got != want {
+			t.Errorf("IsInternalWebhookHost(%q) = %v, want %v", host, got, want)
+		}
+	}
+}
@@ -130,11 +130,13 @@ export function AuthProvider({ children }: { children: React.ReactNode }) {
   // Logout
   const logout = useCallback(async () => {
     try {
-      // Call backend logout endpoint to revoke refresh token
+      // Call backend logout endpoint to revoke the access and refresh tokens
       const API_BASE = import.meta.env.VITE_API_URL || 'http://127.0.0.1:8080/api/v1';
+      const token = sessionStorage.getItem(STORAGE_KEY);
       await fetch(`${API_BASE}/auth/logout`, {
         method: 'POST',
         credentials: 'include', // Include cookies (refresh token)
+        headers: token ? { Authorization: `Bearer ${token}` } : undefined,
       });
     } catch (error) {
       console.error('Logout API call failed:', error);
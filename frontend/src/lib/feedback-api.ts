@@ -15,10 +15,23 @@ interface Feedback {
   app_version: string;
   metadata?: Record<string, any>;
   contact_email: string;
+  reporter?: EndUser; // Verified with an end-user identity token
   created_at: string;
   updated_at: string;
   reviewed_at?: string;
   resolved_at?: string;
+  thumbnail_url?: string;
+}
+
+interface EndUser {
+  id: string;
+  application_id: string;
+  external_id: string;
+  email?: string;
+  name?: string;
+  first_seen_at: string;
+  last_seen_at: string;
+  feedback_count?: number;
 }
 
 interface Application {
@@ -26,9 +39,9 @@ interface Application {
   name: string;
   slug: string;
   description: string;
-  api_key?: string;
+  api_key_prefix: string;
+  api_key?: string; // Only returned when a key is created or regenerated
   is_active: boolean;
-  webhook_url?: string;
   allowed_origins: string[];
   created_at: string;
   updated_at: string;
@@ -157,7 +170,6 @@ export const applicationApi = {
     name: string;
     slug: string;
     description: string;
-    webhook_url?: string;
     allowed_origins?: string[];
   }) =>
     apiFetch<Application>('/applications', {
@@ -179,9 +191,11 @@ export const applicationApi = {
     }),
 
   // Regenerate API key
-  regenerateApiKey: (id: string) =>
-    apiFetch<{ api_key: string; message: string }>(`/applications/${id}/regenerate-key`, {
+  // gracePeriod (e.g. '24h') keeps the old key working while clients switch over
+  regenerateApiKey: (id: string, gracePeriod?: string) =>
+    apiFetch<{ api_key: string; api_key_prefix: string; message: string }>(`/applications/${id}/regenerate-key`, {
       method: 'POST',
+      body: JSON.stringify(gracePeriod ? { grace_period: gracePeriod } : {}),
     }),
 
   // Get categories for application
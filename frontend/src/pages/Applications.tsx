@@ -14,7 +14,7 @@ export default function Applications() {
   const [loading, setLoading] = useState(true);
   const [showCreate, setShowCreate] = useState(false);
   const [selectedApp, setSelectedApp] = useState<Application | null>(null);
-  const [formData, setFormData] = useState({ name: '', slug: '', description: '' });
+  const [formData, setFormData] = useState({ name: '', slug: '', description: '', allowed_origins: '' });
 
   useEffect(() => {
     loadApplications();
@@ -34,9 +34,15 @@ export default function Applications() {
   const handleCreate = async (e: React.FormEvent) => {
     e.preventDefault();
     try {
-      const newApp = await applicationApi.createApplication(formData);
+      const newApp = await applicationApi.createApplication({
+        ...formData,
+        allowed_origins: formData.allowed_origins
+          .split(',')
+          .map((origin) => origin.trim())
+          .filter(Boolean),
+      });
       toast.success('Application created successfully!');
-      setFormData({ name: '', slug: '', description: '' });
+      setFormData({ name: '', slug: '', description: '', allowed_origins: '' });
       setShowCreate(false);
       setSelectedApp(newApp); // Show the new app with API key
       loadApplications();
@@ -55,12 +61,13 @@ export default function Applications() {
   };
 
   const handleRegenerateKey = async (id: string) => {
-    if (!confirm('Are you sure? This will invalidate the current API key.')) return;
+    if (!confirm('Regenerate the API key? The current key keeps working for 24 hours so clients can switch over.')) return;
     try {
-      const result = await applicationApi.regenerateApiKey(id);
+      const result = await applicationApi.regenerateApiKey(id, '24h');
       toast.success('API key regenerated');
-      if (selectedApp?.id === id) {
-        setSelectedApp({ ...selectedApp, api_key: result.api_key });
+      const app = selectedApp?.id === id ? selectedApp : applications.find((a) => a.id === id);
+      if (app) {
+        setSelectedApp({ ...app, api_key: result.api_key, api_key_prefix: result.api_key_prefix });
       }
     } catch (error) {
       toast.error('Failed to regenerate API key');
@@ -112,6 +119,17 @@ export default function Applications() {
                     onChange={(e) => setFormData({ ...formData, description: e.target.value })}
                   />
                 </div>
+                <div>
+                  <Label>Allowed Origins</Label>
+                  <Input
+                    value={formData.allowed_origins}
+                    onChange={(e) => setFormData({ ...formData, allowed_origins: e.target.value })}
+                    placeholder="https://example.com, https://*.example.com"
+                  />
+                  <p className="text-xs text-muted-foreground mt-1">
+                    Sites allowed to submit feedback from a browser. Leave empty to only allow server requests.
+                  </p>
+                </div>
                 <div className="flex gap-2">
                   <Button type="submit">Create</Button>
                   <Button type="button" variant="outline" onClick={() => setShowCreate(false)}>
@@ -129,20 +147,33 @@ export default function Applications() {
               <CardTitle>API Key for {selectedApp.name}</CardTitle>
             </CardHeader>
             <CardContent className="space-y-4">
-              <div>
-                <Label>API Key (keep this secret!)</Label>
-                <div className="flex gap-2 mt-2">
-                  <Input value={selectedApp.api_key} readOnly className="font-mono" />
-                  <Button
-                    onClick={() => {
-                      navigator.clipboard.writeText(selectedApp.api_key!);
-                      toast.success('API key copied!');
-                    }}
-                  >
-                    Copy
-                  </Button>
+              {selectedApp.api_key ? (
+                <div>
+                  <Label>API Key (keep this secret!)</Label>
+                  <div className="flex gap-2 mt-2">
+                    <Input value={selectedApp.api_key} readOnly className="font-mono" />
+                    <Button
+                      onClick={() => {
+                        navigator.clipboard.writeText(selectedApp.api_key!);
+                        toast.success('API key copied!');
+                      }}
+                    >
+                      Copy
+                    </Button>
+                  </div>
+                  <p className="text-xs text-muted-foreground mt-2">
+                    Copy it now - the full key is only shown once.
+                  </p>
                 </div>
-              </div>
+              ) : (
+                <div>
+                  <Label>API Key</Label>
+                  <Input value={`${selectedApp.api_key_prefix}…`} readOnly className="font-mono mt-2" />
+                  <p className="text-xs text-muted-foreground mt-2">
+                    Only the start of the key is stored. Regenerate it if you've lost the full key.
+                  </p>
+                </div>
+              )}
               <div className="flex gap-2">
                 <Button
                   variant="destructive"
@@ -178,6 +209,7 @@ export default function Applications() {
                 <p className="text-sm text-muted-foreground">{app.description}</p>
                 <div className="text-xs text-muted-foreground">
                   <div>Slug: {app.slug}</div>
+                  <div>Origins: {app.allowed_origins?.length ? app.allowed_origins.join(', ') : 'none'}</div>
                   <div>Created: {new Date(app.created_at).toLocaleDateString()}</div>
                 </div>
                 <Button size="sm" onClick={() => handleViewApiKey(app.id)}>
@@ -95,6 +95,19 @@ export default function FeedbackDetail() {
               </div>
             )}
 
+            {feedback.reporter && (
+              <div>
+                <h3 className="font-semibold mb-2">Reporter (verified)</h3>
+                <p className="text-sm">
+                  {feedback.reporter.name || feedback.reporter.email || feedback.reporter.external_id}
+                </p>
+                <p className="text-xs text-muted-foreground">
+                  ID {feedback.reporter.external_id}
+                  {feedback.reporter.email && ` · ${feedback.reporter.email}`}
+                </p>
+              </div>
+            )}
+
             {feedback.contact_email && (
               <div>
                 <h3 className="font-semibold mb-2">Contact</h3>
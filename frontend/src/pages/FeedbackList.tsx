@@ -58,7 +58,17 @@ export default function FeedbackList() {
                 </CardTitle>
               </CardHeader>
               <CardContent>
-                <p className="text-sm text-muted-foreground line-clamp-2">{item.content}</p>
+                <div className="flex gap-4">
+                  {item.thumbnail_url && (
+                    <img
+                      src={item.thumbnail_url}
+                      alt="Screenshot preview"
+                      loading="lazy"
+                      className="w-24 h-16 object-cover rounded border flex-shrink-0"
+                    />
+                  )}
+                  <p className="text-sm text-muted-foreground line-clamp-2">{item.content}</p>
+                </div>
                 <div className="flex justify-between items-center mt-4 text-xs text-muted-foreground">
                   <span>{new Date(item.created_at).toLocaleDateString()}</span>
                   {item.contact_email && <span>{item.contact_email}</span>}